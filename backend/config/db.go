@@ -1,6 +1,7 @@
 package config
 
 import (
+	"backend/models"
 	"backend/services"
 	"backend/utils"
 )
@@ -10,6 +11,9 @@ func ConnectDB() {
 
 	services.ConnectMongo()
 	services.ConnectRedis()
+	if err := models.EnsureIndexes(); err != nil {
+		utils.Logger.Printf("Failed to create MongoDB indexes: %v", err)
+	}
 	go services.HandleBroadcasts()
 	utils.Logger.Println("Database and Services Connected")
 }
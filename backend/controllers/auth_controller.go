@@ -121,6 +121,17 @@ func GoogleSignIn(w http.ResponseWriter, r *http.Request) {
 
 	UserId := User.ID
 
+	// Firebase has checked the email, and the phone of phone sign-ins, so reveals can trust them
+	emailVerified, _ := token.Claims["email_verified"].(bool)
+	phoneNumber, _ := token.Claims["phone_number"].(string)
+	var phone string
+	if numbers := utils.FindPhoneNumbers(phoneNumber); len(numbers) > 0 {
+		phone = numbers[0]
+	}
+	if err := models.MarkUserVerified(UserId, emailVerified, phone); err != nil {
+		utils.Logger.Printf("Failed to record verification of user %s: %v", UserId.Hex(), err)
+	}
+
 	appToken, err := utils.GenerateJWT(UserId.Hex(), "tenant")
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
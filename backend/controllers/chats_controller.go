@@ -51,12 +51,17 @@ func SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if chatMessage.PropertyID != "" {
+		recordPropertySignal(senderID, models.SignalChat, chatMessage.PropertyID)
+	}
+
 	// Broadcast message using WebSocket
 	messageToBroadcast := services.Message{
-		Sender:    senderID,
-		Receiver:  chatMessage.ReceiverID,
-		Message:   chatMessage.Message,
-		Timestamp: time.Now().Format(time.RFC3339), // Format timestamp for client
+		Sender:     senderID,
+		Receiver:   chatMessage.ReceiverID,
+		Message:    chatMessage.Message,
+		Timestamp:  time.Now().Format(time.RFC3339), // Format timestamp for client
+		PropertyID: chatMessage.PropertyID,
 	}
 	services.Broadcast <- messageToBroadcast
 
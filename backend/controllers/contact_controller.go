@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/utils"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// revealRequiresVerification is set by CONTACT_REVEAL_REQUIRE_VERIFIED: only users with a
+// verified phone or email may reveal contact details
+func revealRequiresVerification() bool {
+	required, _ := strconv.ParseBool(os.Getenv("CONTACT_REVEAL_REQUIRE_VERIFIED"))
+	return required
+}
+
+// RevealContact gives the caller the owner's contact details and logs it as a lead for the
+// owner. Each user may reveal ContactRevealLimit new listings a day; listings they revealed
+// before do not count again.
+func RevealContact(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil || !property.IsPublic() {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	owner, err := models.FindUserByID(property.OwnerID)
+	if err != nil {
+		utils.Logger.Printf("Failed to load owner of property %s: %v", propertyID, err)
+	}
+	if property.OwnerID == userID {
+		utils.WriteSuccessResponse(w, property.ContactDetails(owner), http.StatusOK)
+		return
+	}
+
+	user, err := models.FindUserByID(userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if revealRequiresVerification() && !user.IsVerified() {
+		utils.WriteErrorResponse(w, "Verify your phone or email to see contact details", http.StatusForbidden)
+		return
+	}
+
+	revealed, err := models.HasRevealedContact(propertyID, userID)
+	if err != nil {
+		utils.Logger.Printf("Failed to check contact reveals of user %s: %v", userID, err)
+		utils.WriteErrorResponse(w, "Failed to reveal contact", http.StatusInternalServerError)
+		return
+	}
+	if !revealed {
+		count, err := models.CountRecentContactReveals(userID)
+		if err != nil {
+			utils.Logger.Printf("Failed to count contact reveals of user %s: %v", userID, err)
+			utils.WriteErrorResponse(w, "Failed to reveal contact", http.StatusInternalServerError)
+			return
+		}
+		if count >= models.ContactRevealLimit {
+			utils.Logger.Printf("User %s hit the contact reveal limit", userID)
+			w.Header().Set("Retry-After", strconv.Itoa(int(models.ContactRevealWindow.Seconds())))
+			utils.WriteErrorResponse(w, "You have revealed too many contacts today, try again tomorrow", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	if _, err := models.RecordContactReveal(property, user); err != nil {
+		utils.Logger.Printf("Failed to record contact reveal of property %s by user %s: %v", propertyID, userID, err)
+		utils.WriteErrorResponse(w, "Failed to reveal contact", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, property.ContactDetails(owner), http.StatusOK)
+}
+
+// GetLeads lists the users who revealed the contact of the caller's listings, most recent first.
+// ?limit= caps the results (50 by default, at most 200).
+func GetLeads(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	limit := int64(models.DefaultLeadPage)
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.ParseInt(limitParam, 10, 64)
+		if err != nil || parsed <= 0 || parsed > models.MaxLeadPage {
+			utils.WriteErrorResponse(w, "limit must be between 1 and "+strconv.Itoa(models.MaxLeadPage), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	leads, err := models.GetOwnerLeads(userID, limit)
+	if err != nil {
+		utils.Logger.Printf("Failed to load leads of user %s: %v", userID, err)
+		utils.WriteErrorResponse(w, "Failed to fetch leads", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, leads, http.StatusOK)
+}
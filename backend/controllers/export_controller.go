@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"backend/jobs"
+	"backend/models"
+	"backend/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportProperties streams the caller's listings as CSV, XLSX or NDJSON. It takes the search
+// filters as a JSON body (POST) or as query parameters (GET), plus:
+//   - format: csv (default), xlsx or ndjson
+//   - fields: columns to include, comma separated in the query or an array in the body
+//   - allOwners / ownerId: admins only, export every owner's listings or another owner's
+func ExportProperties(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	role, _ := r.Context().Value("userRole").(string)
+
+	filters := map[string]interface{}{}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&filters); err != nil {
+			utils.WriteErrorResponse(w, "Invalid filter parameters", http.StatusBadRequest)
+			return
+		}
+	} else {
+		filters = filtersFromQuery(r.URL.Query())
+	}
+
+	format, _ := filters["format"].(string)
+	if format == "" {
+		format = "csv"
+	}
+	contentType := jobs.ExportContentType(format)
+	if contentType == "" {
+		utils.WriteErrorResponse(w, "Unsupported export format, expected csv, xlsx or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	fields := exportFieldsFromFilters(filters)
+	if err := jobs.ValidateExportFields(fields); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ownerID := userID
+	allOwners, _ := filters["allOwners"].(bool)
+	requestedOwner, _ := filters["ownerId"].(string)
+	if allOwners || (requestedOwner != "" && requestedOwner != userID) {
+		if role != "admin" {
+			utils.WriteErrorResponse(w, "Only admins can export other owners' listings", http.StatusForbidden)
+			return
+		}
+		ownerID = requestedOwner // empty when exporting every owner
+	}
+
+	filter, err := models.PropertyExportFilter(filters, ownerID)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cursor, err := models.StreamProperties(r.Context(), filter, jobs.ExportProjection(fields))
+	if err != nil {
+		utils.Logger.Printf("Error starting property export: %v", err)
+		utils.WriteErrorResponse(w, "Failed to export properties", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	fileName := fmt.Sprintf("properties_%s.%s", time.Now().Format("20060102150405"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.WriteHeader(http.StatusOK)
+
+	// the status is already sent, so a failure here can only cut the download short
+	count, err := jobs.WriteExport(r.Context(), w, format, cursor, fields)
+	if err != nil {
+		utils.Logger.Printf("Property export for %s stopped after %d rows: %v", userID, count, err)
+		return
+	}
+	utils.Logger.Printf("Exported %d properties as %s for %s", count, format, userID)
+}
+
+// filtersFromQuery reads search filters from a query string, typing numbers and booleans the
+// way the JSON search body would
+func filtersFromQuery(query url.Values) map[string]interface{} {
+	filters := make(map[string]interface{})
+	for key, values := range query {
+		value := values[0]
+		switch key {
+		case "fields", "format", "ownerId", "status", "near", "bbox", "offices":
+			filters[key] = value
+			continue
+		}
+		if b, err := strconv.ParseBool(value); err == nil {
+			filters[key] = b
+		} else if n, err := strconv.ParseFloat(value, 64); err == nil {
+			filters[key] = n
+		} else {
+			filters[key] = value
+		}
+	}
+	return filters
+}
+
+func exportFieldsFromFilters(filters map[string]interface{}) []string {
+	var fields []string
+	switch v := filters["fields"].(type) {
+	case string:
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+	case []interface{}:
+		for _, field := range v {
+			if name, ok := field.(string); ok && name != "" {
+				fields = append(fields, name)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return jobs.DefaultExportFields
+	}
+	return fields
+}
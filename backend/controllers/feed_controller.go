@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/utils"
+	"net/http"
+	"strconv"
+)
+
+// recordSignal stores a feed signal in the background; signals are best effort
+func recordSignal(signal *models.UserSignal) {
+	if signal == nil || signal.UserID == "" {
+		return
+	}
+	go func() {
+		if err := models.RecordUserSignal(signal); err != nil {
+			utils.Logger.Printf("Failed to record %s signal of user %s: %v", signal.Kind, signal.UserID, err)
+		}
+	}()
+}
+
+// recordPropertySignal records a view, favorite or chat about a listing known only by ID
+func recordPropertySignal(userID string, kind string, propertyID string) {
+	if userID == "" {
+		return
+	}
+	go func() {
+		property, err := models.FindPropertyByID(propertyID)
+		if err != nil || !property.IsPublic() {
+			return
+		}
+		if err := models.RecordUserSignal(models.NewPropertySignal(userID, kind, property)); err != nil {
+			utils.Logger.Printf("Failed to record %s signal of user %s: %v", kind, userID, err)
+		}
+	}()
+}
+
+// GetFeed returns the caller's home feed, ranked from their searches, views, favorites and
+// chats. New users get the most viewed listings until they have done enough to rank by.
+// ?limit= caps the results (8 by default, at most 50).
+func GetFeed(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	limit := models.DefaultFeedSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 || parsed > models.MaxFeedSize {
+			utils.WriteErrorResponse(w, "limit must be between 1 and "+strconv.Itoa(models.MaxFeedSize), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	properties, personalized, err := models.GetPersonalizedFeed(userID, limit)
+	if err != nil {
+		utils.Logger.Printf("Failed to build feed of user %s: %v", userID, err)
+		utils.WriteErrorResponse(w, "Failed to fetch feed", http.StatusInternalServerError)
+		return
+	}
+	models.HideContacts(properties)
+	utils.WriteSuccessResponse(w, map[string]interface{}{
+		"personalized": personalized,
+		"properties":   properties,
+	}, http.StatusOK)
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Geocode resolves a free-text place such as "HSR Layout Sector 2" to coordinates
+func Geocode(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		utils.WriteErrorResponse(w, "q parameter is missing", http.StatusBadRequest)
+		return
+	}
+
+	result, err := services.DefaultGeocoder.Geocode(r.Context(), query)
+	writeGeocodeResult(w, result, err)
+}
+
+// ReverseGeocode names the locality at ?lat=&lng=
+func ReverseGeocode(w http.ResponseWriter, r *http.Request) {
+	latitude, errLat := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	longitude, errLng := strconv.ParseFloat(r.URL.Query().Get("lng"), 64)
+	if errLat != nil || errLng != nil || !models.ValidCoordinates(latitude, longitude) {
+		utils.WriteErrorResponse(w, "lat and lng must be valid coordinates", http.StatusBadRequest)
+		return
+	}
+
+	result, err := services.DefaultGeocoder.ReverseGeocode(r.Context(), latitude, longitude)
+	writeGeocodeResult(w, result, err)
+}
+
+func writeGeocodeResult(w http.ResponseWriter, result *services.GeoResult, err error) {
+	if errors.Is(err, services.ErrNoGeocodeMatch) {
+		utils.WriteErrorResponse(w, "No matching place found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrGeocoderBusy) {
+		utils.WriteErrorResponse(w, "Too many place lookups, try again shortly", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		utils.Logger.Printf("Geocoding failed: %v", err)
+		utils.WriteErrorResponse(w, "Failed to look up place", http.StatusBadGateway)
+		return
+	}
+	utils.WriteSuccessResponse(w, result, http.StatusOK)
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"backend/jobs"
+	"backend/models"
+	"backend/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// ImportProperties accepts a CSV, XLSX or NDJSON file of listings and imports it in the background
+// as drafts. The response carries the job ID to poll for the per-row report.
+func ImportProperties(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	if models.IsUserSuspended(userID) {
+		utils.WriteErrorResponse(w, suspendedMessage, http.StatusForbidden)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, jobs.MaxImportFileBytes+1<<20)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		utils.WriteErrorResponse(w, "Request too large (max 20MB)", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteErrorResponse(w, "Missing import file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = jobs.ImportFormatFromFileName(fileHeader.Filename)
+	}
+
+	var mapping map[string]string
+	if mappingJSON := r.FormValue("mapping"); mappingJSON != "" {
+		if err := json.Unmarshal([]byte(mappingJSON), &mapping); err != nil {
+			utils.WriteErrorResponse(w, "Invalid column mapping JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to read import file", http.StatusBadRequest)
+		return
+	}
+
+	job, err := jobs.CreateImport(userID, format, data, mapping)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	go startImport(job.ID.Hex())
+
+	utils.WriteSuccessResponse(w, map[string]interface{}{
+		"jobId":     job.ID.Hex(),
+		"status":    job.Status,
+		"totalRows": job.TotalRows,
+	}, http.StatusAccepted)
+}
+
+// GetImportJob returns the import's progress and per-row report
+func GetImportJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := importJobForRequest(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteSuccessResponse(w, job, http.StatusOK)
+}
+
+// ResumeImportJob restarts a failed or interrupted import from its last processed row
+func ResumeImportJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := importJobForRequest(w, r)
+	if !ok {
+		return
+	}
+	if job.Status == models.ImportCompleted {
+		utils.WriteErrorResponse(w, "Import has already completed", http.StatusConflict)
+		return
+	}
+
+	go startImport(job.ID.Hex())
+
+	utils.WriteSuccessResponse(w, map[string]interface{}{
+		"jobId":         job.ID.Hex(),
+		"processedRows": job.ProcessedRows,
+		"message":       "Import resumed",
+	}, http.StatusAccepted)
+}
+
+func startImport(jobID string) {
+	if err := jobs.RunImportJob(jobID); err != nil {
+		utils.Logger.Printf("Import job %s stopped: %v", jobID, err)
+	}
+}
+
+// importJobForRequest loads the job from the URL and checks the caller owns it
+func importJobForRequest(w http.ResponseWriter, r *http.Request) (*models.ImportJob, bool) {
+	userID := r.Context().Value("userID").(string)
+	role, _ := r.Context().Value("userRole").(string)
+
+	job, err := models.GetImportJobByID(mux.Vars(r)["jobId"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Import job not found", http.StatusNotFound)
+		return nil, false
+	}
+	if job.OwnerID != userID && role != "admin" {
+		utils.WriteErrorResponse(w, "Unauthorized to access this import", http.StatusForbidden)
+		return nil, false
+	}
+	return job, true
+}
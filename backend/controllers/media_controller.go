@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"backend/utils"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+)
+
+// ServeMedia serves a stored file for the local and memory storage drivers
+func ServeMedia(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+
+	// raw uploads may still carry EXIF, import sources hold owners' contact details
+	if utils.IsPrivateKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := utils.Store.Get(r.Context(), key)
+	if errors.Is(err, utils.ErrBlobNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		utils.Logger.Printf("Failed to read media %s: %v", key, err)
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	io.Copy(w, body)
+}
+
+// ReceiveMedia accepts a presigned PUT for the local and memory storage drivers
+func ReceiveMedia(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	contentType := r.Header.Get("Content-Type")
+
+	if err := utils.VerifyMediaUpload(key, r.URL.Query(), contentType, r.ContentLength); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, r.ContentLength))
+	if err != nil || int64(len(data)) != r.ContentLength {
+		utils.WriteErrorResponse(w, "Upload does not match the signed size", http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.Store.Put(r.Context(), key, data, contentType); err != nil {
+		utils.Logger.Printf("Failed to store media %s: %v", key, err)
+		utils.WriteErrorResponse(w, "Failed to store file", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"backend/jobs"
+	"backend/models"
+	"backend/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultModerationPage = 50
+	maxModerationPage     = 200
+)
+
+// heldProperty shows a moderator the risk assessment that public responses leave out
+type heldProperty struct {
+	*models.Property
+	Risk *models.RiskAssessment `json:"risk"`
+}
+
+// GetHeldProperties lists the listings held for moderation, riskiest first, with the rules each
+// one tripped
+func GetHeldProperties(w http.ResponseWriter, r *http.Request) {
+	limit := int64(defaultModerationPage)
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 1 || parsed > maxModerationPage {
+			utils.WriteErrorResponse(w, "limit must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	properties, err := models.GetHeldProperties(limit)
+	if err != nil {
+		utils.Logger.Printf("Failed to load held properties: %v", err)
+		utils.WriteErrorResponse(w, "Failed to load held properties", http.StatusInternalServerError)
+		return
+	}
+	held := make([]heldProperty, len(properties))
+	for i, property := range properties {
+		held[i] = heldProperty{Property: property, Risk: property.Risk}
+	}
+	utils.WriteSuccessResponse(w, held, http.StatusOK)
+}
+
+// ApproveProperty publishes a held listing
+func ApproveProperty(w http.ResponseWriter, r *http.Request) {
+	moderateProperty(w, r, true)
+}
+
+// RejectProperty keeps a held listing off the site for good
+func RejectProperty(w http.ResponseWriter, r *http.Request) {
+	moderateProperty(w, r, false)
+}
+
+func moderateProperty(w http.ResponseWriter, r *http.Request, approve bool) {
+	adminID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	moderated, err := models.ModerateHeldProperty(propertyID, approve, adminID)
+	if err != nil {
+		utils.Logger.Printf("Failed to moderate property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to moderate property", http.StatusInternalServerError)
+		return
+	}
+	if !moderated {
+		utils.WriteErrorResponse(w, "Property is not awaiting moderation", http.StatusConflict)
+		return
+	}
+
+	event, status := jobs.PropertyRejectedEvent, models.StatusRejected
+	if approve {
+		event, status = jobs.PropertyApprovedEvent, models.StatusPublished
+	}
+	utils.Logger.Printf("Admin %s moderated property %s: %s", adminID, propertyID, status)
+	go jobs.NotifyModeration(propertyID, property.OwnerID, event)
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Property " + status, "status": status}, http.StatusOK)
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/utils"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// SubscribePriceAlert asks to be told when a listing's rent drops
+func SubscribePriceAlert(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	if property.OwnerID == userID {
+		utils.WriteErrorResponse(w, "You cannot subscribe to your own listing", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.SubscribePriceAlert(userID, propertyID); err != nil {
+		utils.Logger.Printf("Failed to subscribe user %s to price alerts of property %s: %v", userID, propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to subscribe to price alerts", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Subscribed to price drops"}, http.StatusOK)
+}
+
+// UnsubscribePriceAlert stops a listing's price drop notifications
+func UnsubscribePriceAlert(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	removed, err := models.UnsubscribePriceAlert(userID, propertyID)
+	if err != nil {
+		utils.Logger.Printf("Failed to unsubscribe user %s from price alerts of property %s: %v", userID, propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to unsubscribe from price alerts", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		utils.WriteErrorResponse(w, "Not subscribed to this property", http.StatusNotFound)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Unsubscribed from price drops"}, http.StatusOK)
+}
+
+// GetPriceAlerts lists the listings the caller gets price drop notifications for
+func GetPriceAlerts(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	alerts, err := models.GetUserPriceAlerts(userID)
+	if err != nil {
+		utils.Logger.Printf("Failed to load price alerts of user %s: %v", userID, err)
+		utils.WriteErrorResponse(w, "Failed to load price alerts", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, alerts, http.StatusOK)
+}
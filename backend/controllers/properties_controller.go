@@ -6,15 +6,17 @@ import (
 	"backend/services"
 	"backend/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // GetProperties retrieves all properties
@@ -25,6 +27,7 @@ func GetProperties(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, "Failed to fetch properties", http.StatusInternalServerError)
 		return
 	}
+	models.HideContacts(properties)
 	utils.WriteSuccessResponse(w, properties, http.StatusOK)
 }
 
@@ -37,24 +40,78 @@ func GetTopProperties(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, "Failed to fetch top properties", http.StatusInternalServerError)
 		return
 	}
+	models.HideContacts(properties)
 	utils.WriteSuccessResponse(w, properties, http.StatusOK)
 }
 
-// GetPropertyByID retrieves a property by its ID
+// GetPropertyByID retrieves a property by its ID. Listings that are not public are only shown
+// to their owner and admins.
 func GetPropertyByID(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	propertyID := params["id"]
 
-	property, err := models.GetPropertyByID(propertyID)
+	property, err := models.FindPropertyByID(propertyID)
 	if err != nil {
 		utils.Logger.Printf("Property not found: %v, error: %v", propertyID, err) // Correct: Printf is a standard method
 		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
 		return
 	}
 
+	userID, _ := r.Context().Value("userID").(string)
+	role, _ := r.Context().Value("userRole").(string)
+	if !property.IsPublic() {
+		if userID == "" || (property.OwnerID != userID && role != "admin") {
+			utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		// the feed's view signal is recorded once, by the client's POST /view
+		go services.IncrementPropertyView(propertyID) // async call to avoid blocking
+	}
+	// owners see their own contact details, everyone else reveals them through RevealContact
+	if property.OwnerID != userID && role != "admin" {
+		property.HideContacts()
+	}
+
+	property.PriceHistory, err = models.GetPriceHistory(propertyID)
+	if err != nil {
+		utils.Logger.Printf("Failed to load price history of property %s: %v", propertyID, err)
+	}
+
 	utils.WriteSuccessResponse(w, property, http.StatusOK)
 }
 
+// GetSimilarProperties recommends other listings like this one, best first. ?limit= caps the
+// results (6 by default, at most 20).
+func GetSimilarProperties(w http.ResponseWriter, r *http.Request) {
+	propertyID := mux.Vars(r)["id"]
+
+	limit := models.DefaultSimilarProperties
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 || parsed > models.MaxSimilarProperties {
+			utils.WriteErrorResponse(w, "limit must be between 1 and "+strconv.Itoa(models.MaxSimilarProperties), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil || !property.IsPublic() {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+
+	similar, err := models.FindSimilarProperties(property, limit)
+	if err != nil {
+		utils.Logger.Printf("Failed to find properties similar to %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to fetch similar properties", http.StatusInternalServerError)
+		return
+	}
+	models.HideContacts(similar)
+	utils.WriteSuccessResponse(w, similar, http.StatusOK)
+}
+
 // AddProperty adds a new property
 func AddProperty(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string) // Get userID from context
@@ -62,10 +119,15 @@ func AddProperty(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if models.IsUserSuspended(userID) {
+		utils.WriteErrorResponse(w, suspendedMessage, http.StatusForbidden)
+		return
+	}
 
-	err := r.ParseMultipartForm(10 << 20) // 10 MB max
+	utils.PhotoUploadPolicy.LimitRequestBody(w, r)
+	err := r.ParseMultipartForm(10 << 20) // 10 MB in memory, the rest spills to temp files
 	if err != nil {
-		utils.WriteErrorResponse(w, "File too large (max 10MB)", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Request too large (max 50MB of photos)", http.StatusRequestEntityTooLarge)
 		return
 	}
 
@@ -85,24 +147,9 @@ func AddProperty(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate input
-	if property.PropertyType == "" || !utils.IsValidPropertyType(property.PropertyType) {
-		utils.WriteErrorResponse(w, "Invalid property type", http.StatusBadRequest)
-		return
-	}
-	if property.ListingType == "" || !utils.IsValidListingType(property.ListingType) {
-		utils.WriteErrorResponse(w, "Invalid listing type", http.StatusBadRequest)
-		return
-	}
-	if property.Rent <= 0 {
-		utils.WriteErrorResponse(w, "Rent cannot be negative", http.StatusBadRequest)
-		return
-	}
-	if property.Bedrooms < 0 || property.Bathrooms < 0 {
-		utils.WriteErrorResponse(w, "Bedrooms and bathrooms cannot be negative", http.StatusBadRequest)
-		return
-	}
-	if property.Location == "" {
-		utils.WriteErrorResponse(w, "Location cannot be empty", http.StatusBadRequest)
+	property.FillFromRooms()
+	if validationErrors := property.Validate(); len(validationErrors) > 0 {
+		utils.WriteErrorResponse(w, validationErrors[0], http.StatusBadRequest)
 		return
 	}
 
@@ -115,95 +162,171 @@ func AddProperty(w http.ResponseWriter, r *http.Request) {
 
 	// file uploads
 	files := r.MultipartForm.File["photoFiles"]
-	if len(files) > 8 {
-		utils.Logger.Printf("More than 8 files were uploaded")
-		utils.WriteErrorResponse(w, "Too many files, max 8 photos can be uploaded", http.StatusBadRequest)
+	if fileErrors := utils.PhotoUploadPolicy.CheckFiles(files); len(fileErrors) > 0 {
+		utils.Logger.Printf("Rejected photo upload for user %s: %v", userID, fileErrors)
+		utils.WriteErrorResponseWithDetails(w, "One or more photos were rejected", fileErrors, http.StatusBadRequest)
 		return
 	}
+
+	photoSet, fileErrors := uploadPropertyPhotos(userID, files)
+	if len(fileErrors) > 0 {
+		utils.WriteErrorResponseWithDetails(w, "Failed to upload one or more images", fileErrors, http.StatusInternalServerError)
+		return
+	}
+
 	var photoURLs []string
+	for _, photo := range photoSet {
+		photoURLs = append(photoURLs, photo.URL)
+	}
+	if len(photoSet) > 0 {
+		property.PhotoSet = photoSet
+	}
+	property.Photos = photoURLs
+	property.OwnerID = userID
 
-	if len(files) != 0 {
-		var wg sync.WaitGroup
-		var mu sync.Mutex
-		uploadErrChan := make(chan error, len(files))
-		for _, fileHeader := range files {
-			wg.Add(1)
-			go func(fileHeader *multipart.FileHeader) {
-				defer wg.Done()
-
-				file, err2 := fileHeader.Open()
-				if err2 != nil {
-					uploadErrChan <- fmt.Errorf("failed to open file: %w", err2)
-					return
-				}
-				defer file.Close()
-
-				fileName := fmt.Sprintf("/properties/user_%s/%s_%s%s",
-					userID,
-					time.Now().Format("20060102150405"),
-					uuid.New().String(),
-					filepath.Ext(fileHeader.Filename),
-				)
-				url, err3 := utils.UploadFileToS3(file, fileHeader, fileName, userID)
-				if err3 != nil {
-					uploadErrChan <- fmt.Errorf("failed to upload file to S3: %w", err3)
-					return
-				}
+	property.Status = models.StatusProcessing
 
-				mu.Lock()
-				photoURLs = append(photoURLs, url)
-				mu.Unlock()
-			}(fileHeader)
-		}
+	// the listing is saved right away and stays hidden until the cleanup worker publishes it
+	flagDuplicates(&property)
+	assessRisk(&property)
+	if err := models.AddProperty(&property); err != nil {
+		utils.Logger.Printf("Failed to add property to database: %v", err)
+		utils.WriteErrorResponse(w, "Failed to add property", http.StatusInternalServerError)
+		return
+	}
 
-		go func() {
-			wg.Wait()
-			close(uploadErrChan)
-		}()
-		var uploadErrors []error
-		for uploadErr := range uploadErrChan {
-			uploadErrors = append(uploadErrors, uploadErr)
+	if _, err := jobs.EnqueueCleanup(&property); err != nil {
+		// without a task nothing would ever publish the listing, so publish it as submitted
+		utils.Logger.Printf("Failed to queue cleanup of property %s: %v", property.ID.Hex(), err)
+		if _, err := models.FinishPropertyProcessing(property.ID.Hex(), nil, ""); err != nil {
+			utils.Logger.Printf("Failed to publish property %s: %v", property.ID.Hex(), err)
 		}
+		utils.WriteSuccessResponse(w, map[string]string{"message": "Property added successfully", "id": property.ID.Hex()}, http.StatusCreated)
+		return
+	}
 
-		if len(uploadErrors) > 0 {
-			for uploadErr := range uploadErrors {
-				utils.Logger.Printf("File upload error: %v", uploadErr)
-			}
-			utils.WriteErrorResponse(w, "Failed to upload one or more images", http.StatusInternalServerError)
+	utils.WriteSuccessResponse(w, map[string]string{
+		"message": "Property added and queued for processing",
+		"id":      property.ID.Hex(),
+		"status":  models.StatusProcessing,
+	}, http.StatusCreated)
+}
+
+// GetPropertyProcessing reports how far the AI cleanup of one of the caller's listings has got
+func GetPropertyProcessing(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	role, _ := r.Context().Value("userRole").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	if property.OwnerID != userID && role != "admin" {
+		utils.WriteErrorResponse(w, "Unauthorized to view this property", http.StatusForbidden)
+		return
+	}
+
+	task, err := models.GetCleanupTaskByPropertyID(propertyID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			utils.WriteErrorResponse(w, "Property has no processing history", http.StatusNotFound)
 			return
 		}
+		utils.Logger.Printf("Failed to load cleanup task of property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to load processing status", http.StatusInternalServerError)
+		return
 	}
 
-	property.Photos = photoURLs
-	property.OwnerID = userID
+	utils.WriteSuccessResponse(w, map[string]interface{}{
+		"propertyStatus": property.Status,
+		"processing":     task,
+	}, http.StatusOK)
+}
+
+// GetPropertyQuality shows the owner the quality score of a listing, part by part, with what
+// would raise it
+func GetPropertyQuality(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	role, _ := r.Context().Value("userRole").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	if property.OwnerID != userID && role != "admin" {
+		utils.WriteErrorResponse(w, "Unauthorized to view this property", http.StatusForbidden)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, property.Quality(), http.StatusOK)
+}
+
+// flagDuplicates never blocks a listing, a failed check only means it is not linked to its copies
+func flagDuplicates(property *models.Property) {
+	if err := jobs.FlagDuplicates(property); err != nil {
+		utils.Logger.Printf("Duplicate detection failed for user %s: %v", property.OwnerID, err)
+	}
+}
+
+// assessRisk never blocks a listing either, an unscored one is published like a low-risk one
+func assessRisk(property *models.Property) {
+	if err := jobs.AssessRisk(property); err != nil {
+		utils.Logger.Printf("Fraud risk assessment failed for user %s: %v", property.OwnerID, err)
+	}
+}
 
-	maxRetries := 3
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		cleanedProperty, err2 := jobs.CleanupJob(&property)
-		if err2 == nil {
-			err3 := models.AddProperty(cleanedProperty)
-			if err3 != nil {
-				utils.Logger.Printf("Failed to add property to database: %v", err3)
-				//utils.WriteErrorResponse(w, "Failed to add property", http.StatusInternalServerError)
-				continue
-			} else {
-				utils.WriteSuccessResponse(w, map[string]string{"message": "Property processed and added successfully"}, http.StatusCreated)
+// uploadPropertyPhotos stores every rendition of each photo concurrently, keeping the upload order
+func uploadPropertyPhotos(userID string, files []*multipart.FileHeader) ([]models.Photo, []utils.FileError) {
+	photoSet := make([]models.Photo, len(files))
+	var wg sync.WaitGroup
+	uploadErrChan := make(chan utils.FileError, len(files))
+	for i, fileHeader := range files {
+		wg.Add(1)
+		go func(i int, fileHeader *multipart.FileHeader) {
+			defer wg.Done()
+
+			file, err := fileHeader.Open()
+			if err != nil {
+				uploadErrChan <- utils.FileError{File: fileHeader.Filename, Error: "failed to open file"}
+				return
+			}
+			defer file.Close()
+
+			basePath := fmt.Sprintf("/properties/user_%s/%s_%s",
+				userID,
+				time.Now().Format("20060102150405"),
+				uuid.New().String(),
+			)
+			stored, err := utils.UploadImageRenditions(file, basePath, utils.PhotoUploadPolicy)
+			if err != nil {
+				utils.Logger.Printf("File upload error for %s: %v", fileHeader.Filename, err)
+				message := "failed to process or upload image"
+				if errors.Is(err, utils.ErrImageTooLarge) {
+					message = err.Error()
+				}
+				uploadErrChan <- utils.FileError{File: fileHeader.Filename, Error: message}
 				return
 			}
-		}
-		utils.Logger.Printf("Cleanup attempt %d failed for user %s: %v", attempt, userID, err2)
-		time.Sleep(time.Second * time.Duration(attempt)) // exponential backoff
-	}
 
-	// if cleanUp is failing then directly upload
-	err2 := models.AddProperty(&property)
-	if err2 != nil {
-		utils.Logger.Printf("Failed to add property to database: %v", err2)
-		utils.WriteErrorResponse(w, "Failed to add property", http.StatusInternalServerError)
-		return
+			photoSet[i] = models.Photo{
+				URL:        utils.PickRendition(stored.Renditions, "full", "jpeg"),
+				Renditions: stored.Renditions,
+				Hash:       stored.PerceptualHash,
+			}
+		}(i, fileHeader)
 	}
+	wg.Wait()
+	close(uploadErrChan)
 
-	utils.WriteSuccessResponse(w, map[string]string{"message": "Property added successfully"}, http.StatusCreated)
+	var fileErrors []utils.FileError
+	for fileErr := range uploadErrChan {
+		fileErrors = append(fileErrors, fileErr)
+	}
+	return photoSet, fileErrors
 }
 
 // UpdateProperty updates an existing property
@@ -217,6 +340,29 @@ func UpdateProperty(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	if updatedProperty.HasCoordinates() {
+		if updatedProperty.Latitude == 0 || updatedProperty.Longitude == 0 {
+			utils.WriteErrorResponse(w, "Latitude and longitude must be updated together", http.StatusBadRequest)
+			return
+		}
+		if !models.ValidCoordinates(updatedProperty.Latitude, updatedProperty.Longitude) {
+			utils.WriteErrorResponse(w, "Invalid coordinates", http.StatusBadRequest)
+			return
+		}
+	}
+	if validationErrors := updatedProperty.ValidateLease(); len(validationErrors) > 0 {
+		utils.WriteErrorResponse(w, validationErrors[0], http.StatusBadRequest)
+		return
+	}
+	updatedProperty.FillFromRooms()
+	if validationErrors := updatedProperty.ValidateFlatmate(); len(validationErrors) > 0 {
+		utils.WriteErrorResponse(w, validationErrors[0], http.StatusBadRequest)
+		return
+	}
+	if validationErrors := updatedProperty.ValidateSale(); len(validationErrors) > 0 {
+		utils.WriteErrorResponse(w, validationErrors[0], http.StatusBadRequest)
+		return
+	}
 
 	// Authorization: Check if the user is the owner of the property
 	property, err := models.GetPropertyByID(propertyID)
@@ -228,16 +374,69 @@ func UpdateProperty(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, "Unauthorized to update this property", http.StatusForbidden)
 		return
 	}
+	if models.IsUserSuspended(userID) {
+		utils.WriteErrorResponse(w, suspendedMessage, http.StatusForbidden)
+		return
+	}
 
-	err = models.UpdateProperty(propertyID, &updatedProperty)
+	priceChange, err := models.UpdateProperty(propertyID, &updatedProperty)
 	if err != nil {
 		utils.Logger.Printf("Failed to update property in database: %v", err)
 		utils.WriteErrorResponse(w, "Failed to update property", http.StatusInternalServerError)
 		return
 	}
+	if jobs.ReassessRisk(propertyID) {
+		go jobs.NotifyModeration(propertyID, property.OwnerID, jobs.PropertyHeldEvent)
+	}
+	if priceChange != nil {
+		go jobs.NotifyPriceDrop(priceChange)
+	}
 	utils.WriteSuccessResponse(w, map[string]string{"message": "Property updated successfully"}, http.StatusOK)
 }
 
+// PublishProperty makes one of the caller's draft listings public
+func PublishProperty(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	if property.OwnerID != userID {
+		utils.WriteErrorResponse(w, "Unauthorized to publish this property", http.StatusForbidden)
+		return
+	}
+	if property.Status != models.StatusDraft {
+		utils.WriteErrorResponse(w, "Only draft listings can be published", http.StatusConflict)
+		return
+	}
+	if models.IsUserSuspended(userID) {
+		utils.WriteErrorResponse(w, suspendedMessage, http.StatusForbidden)
+		return
+	}
+
+	status, err := models.PublishProperty(propertyID)
+	if err != nil {
+		utils.Logger.Printf("Failed to publish property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to publish property", http.StatusInternalServerError)
+		return
+	}
+	if status == "" {
+		utils.WriteErrorResponse(w, "Only draft listings can be published", http.StatusConflict)
+		return
+	}
+	if status == models.StatusOnHold {
+		utils.WriteSuccessResponse(w, map[string]string{
+			"message": "Property will be published once it has been reviewed",
+			"status":  status,
+		}, http.StatusAccepted)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Property published successfully", "status": status}, http.StatusOK)
+}
+
 // DeleteProperty deletes a property by its ID
 func DeleteProperty(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string) // Get userID from context
@@ -254,6 +453,17 @@ func DeleteProperty(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, "Unauthorized to delete this property", http.StatusForbidden)
 		return
 	}
+	// deleting would take the listing out of the moderators' reach
+	reported, err := models.HasOpenReports(propertyID)
+	if err != nil {
+		utils.Logger.Printf("Failed to check reports of property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to delete property", http.StatusInternalServerError)
+		return
+	}
+	if reported {
+		utils.WriteErrorResponse(w, "A reported listing cannot be deleted until a moderator has reviewed it", http.StatusConflict)
+		return
+	}
 
 	err = models.DeleteProperty(propertyID)
 	if err != nil {
@@ -268,9 +478,10 @@ func DeleteProperty(w http.ResponseWriter, r *http.Request) {
 func UploadFile(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string) // Get userID from context
 
-	err := r.ParseMultipartForm(10 << 20) // 10 MB max
+	utils.PhotoUploadPolicy.LimitRequestBody(w, r)
+	err := r.ParseMultipartForm(10 << 20) // 10 MB in memory, the rest spills to temp files
 	if err != nil {
-		utils.WriteErrorResponse(w, "File too large (max 10MB)", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Request too large (max 50MB of photos)", http.StatusRequestEntityTooLarge)
 		return
 	}
 
@@ -279,6 +490,10 @@ func UploadFile(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, "No files uploaded", http.StatusBadRequest)
 		return
 	}
+	if fileErrors := utils.PhotoUploadPolicy.CheckFiles(files); len(fileErrors) > 0 {
+		utils.WriteErrorResponseWithDetails(w, "One or more photos were rejected", fileErrors, http.StatusBadRequest)
+		return
+	}
 
 	for _, fileHeader := range files {
 		file, err := fileHeader.Open()
@@ -288,14 +503,14 @@ func UploadFile(w http.ResponseWriter, r *http.Request) {
 		}
 		defer file.Close()
 
-		fileName := fmt.Sprintf("/properties/user_%s/%s%s", userID, time.Now().Format("20060102150405"), filepath.Ext(fileHeader.Filename))
-		url, err := utils.UploadFileToS3(file, fileHeader, fileName, userID)
+		basePath := fmt.Sprintf("/properties/user_%s/%s_%s", userID, time.Now().Format("20060102150405"), uuid.New().String())
+		stored, err := utils.UploadImageRenditions(file, basePath, utils.PhotoUploadPolicy)
 		if err != nil {
 			utils.Logger.Printf("Failed to upload file to Supabase: %v", err)
 			utils.WriteErrorResponse(w, "Failed to upload image", http.StatusInternalServerError)
 			return
 		}
-		utils.Logger.Printf("File uploaded to Supabase: %s", url)
+		utils.Logger.Printf("File uploaded to Supabase: %s", utils.PickRendition(stored.Renditions, "full", "jpeg"))
 	}
 
 	utils.WriteSuccessResponse(w, map[string]string{"message": "Files uploaded successfully"}, http.StatusOK)
@@ -311,6 +526,9 @@ func UpdatePropertyViews(w http.ResponseWriter, r *http.Request) {
 	utils.Logger.Printf("Incrementing views for property ID: %s", propertyID)
 
 	go services.IncrementPropertyView(propertyID) // async call to avoid blocking
+	if userID, ok := r.Context().Value("userID").(string); ok {
+		recordPropertySignal(userID, models.SignalView, propertyID)
+	}
 
 	utils.WriteSuccessResponse(w, map[string]string{
 		"message": "View recorded successfully",
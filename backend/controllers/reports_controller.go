@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"backend/jobs"
+	"backend/models"
+	"backend/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const suspendedMessage = "Your account is suspended from listing"
+
+type reportRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+type reportActionRequest struct {
+	Action  string `json:"action"`
+	Message string `json:"message"` // sent to the owner with contact_owner
+}
+
+// ReportProperty records the caller's report of a listing. Reporting the same listing again
+// replaces their earlier report; once reports from enough users are open the listing is hidden.
+func ReportProperty(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	var req reportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if !models.ValidReportReason(req.Reason) {
+		utils.WriteErrorResponse(w, "reason must be one of "+strings.Join(models.ReportReasons, ", "), http.StatusBadRequest)
+		return
+	}
+	if req.Reason == models.ReportOther && req.Details == "" {
+		utils.WriteErrorResponse(w, "details are required when the reason is other", http.StatusBadRequest)
+		return
+	}
+	if len(req.Details) > models.MaxReportDetailsChars {
+		utils.WriteErrorResponse(w, "details must be at most "+strconv.Itoa(models.MaxReportDetailsChars)+" characters", http.StatusBadRequest)
+		return
+	}
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil || !property.IsPublic() {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	report, hidden, err := models.ReportListing(property, userID, req.Reason, req.Details)
+	if err == models.ErrOwnListingReport {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		utils.Logger.Printf("Failed to report property %s by user %s: %v", propertyID, userID, err)
+		utils.WriteErrorResponse(w, "Failed to report property", http.StatusInternalServerError)
+		return
+	}
+	if hidden {
+		utils.Logger.Printf("Property %s hidden after %d user reports", propertyID, models.ReportAutoHideThreshold)
+		go jobs.NotifyModeration(propertyID, property.OwnerID, jobs.PropertyHiddenEvent)
+	}
+	utils.WriteSuccessResponse(w, report, http.StatusCreated)
+}
+
+// GetReportQueue lists the reported listings awaiting a moderator, most reported first, with
+// their reports grouped by reason
+func GetReportQueue(w http.ResponseWriter, r *http.Request) {
+	limit := int64(models.DefaultReportQueuePage)
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 1 || parsed > models.MaxReportQueuePage {
+			utils.WriteErrorResponse(w, "limit must be between 1 and "+strconv.Itoa(models.MaxReportQueuePage), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	queue, err := models.GetReportQueue(limit)
+	if err != nil {
+		utils.Logger.Printf("Failed to load report queue: %v", err)
+		utils.WriteErrorResponse(w, "Failed to load reports", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, queue, http.StatusOK)
+}
+
+// ResolveReports acts on the reports of a listing: dismiss them, hide the listing, message the
+// owner, or suspend the owner and hide all their listings. Messaging the owner leaves the
+// reports open. Reports of a listing that is gone still reach its owner through the reports.
+func ResolveReports(w http.ResponseWriter, r *http.Request) {
+	adminID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	var req reportActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+
+	var ownerID string
+	if property, err := models.FindPropertyByID(propertyID); err == nil {
+		ownerID = property.OwnerID
+	} else if ownerID, err = models.ReportedOwnerID(propertyID); err != nil {
+		utils.Logger.Printf("Failed to load reports of property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to load reports", http.StatusInternalServerError)
+		return
+	}
+	if ownerID == "" {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+
+	status := models.ReportActioned
+	switch req.Action {
+	case models.ReportActionDismiss:
+		status = models.ReportDismissed
+		restored, err := models.RestoreReportedProperty(propertyID)
+		if err != nil {
+			utils.Logger.Printf("Failed to restore property %s: %v", propertyID, err)
+			utils.WriteErrorResponse(w, "Failed to dismiss reports", http.StatusInternalServerError)
+			return
+		}
+		switch restored {
+		case models.StatusPublished:
+			go jobs.NotifyModeration(propertyID, ownerID, jobs.PropertyRestoredEvent)
+		case models.StatusOnHold:
+			go jobs.NotifyModeration(propertyID, ownerID, jobs.PropertyHeldEvent)
+		}
+	case models.ReportActionHide:
+		hidden, err := models.HideProperty(propertyID, models.HiddenByModerator)
+		if err != nil {
+			utils.Logger.Printf("Failed to hide property %s: %v", propertyID, err)
+			utils.WriteErrorResponse(w, "Failed to hide property", http.StatusInternalServerError)
+			return
+		}
+		if hidden {
+			go jobs.NotifyModeration(propertyID, ownerID, jobs.PropertyHiddenEvent)
+		}
+	case models.ReportActionContactOwner:
+		if req.Message == "" {
+			utils.WriteErrorResponse(w, "message is required to contact the owner", http.StatusBadRequest)
+			return
+		}
+		utils.Logger.Printf("Admin %s messaged the owner of reported property %s", adminID, propertyID)
+		go jobs.MessageOwner(propertyID, ownerID, req.Message)
+		utils.WriteSuccessResponse(w, map[string]string{"message": "Owner contacted"}, http.StatusOK)
+		return
+	case models.ReportActionSuspendOwner:
+		if err := models.SuspendUser(ownerID); err != nil {
+			utils.Logger.Printf("Failed to suspend user %s: %v", ownerID, err)
+			utils.WriteErrorResponse(w, "Failed to suspend owner", http.StatusInternalServerError)
+			return
+		}
+		hiddenIDs, err := models.HideOwnerProperties(ownerID)
+		if err != nil {
+			utils.Logger.Printf("Failed to hide listings of user %s: %v", ownerID, err)
+			utils.WriteErrorResponse(w, "Failed to hide owner listings", http.StatusInternalServerError)
+			return
+		}
+		for _, id := range hiddenIDs {
+			if id != propertyID {
+				if _, err := models.ResolveListingReports(id, status, adminID); err != nil {
+					utils.Logger.Printf("Failed to resolve reports of property %s: %v", id, err)
+				}
+			}
+		}
+		go jobs.NotifyModeration(propertyID, ownerID, jobs.OwnerSuspendedEvent)
+	default:
+		utils.WriteErrorResponse(w, "action must be one of dismiss, hide, contact_owner, suspend_owner", http.StatusBadRequest)
+		return
+	}
+
+	resolved, err := models.ResolveListingReports(propertyID, status, adminID)
+	if err != nil {
+		utils.Logger.Printf("Failed to resolve reports of property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to resolve reports", http.StatusInternalServerError)
+		return
+	}
+	utils.Logger.Printf("Admin %s resolved %d reports of property %s: %s", adminID, resolved, propertyID, req.Action)
+	utils.WriteSuccessResponse(w, map[string]interface{}{"message": "Reports " + status, "resolved": resolved}, http.StatusOK)
+}
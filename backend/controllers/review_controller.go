@@ -34,6 +34,7 @@ func AddReview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	review.UserID = userID
+	review.SocietyID = "" // society reviews go through ReviewSociety
 
 	if err := models.AddReview(&review); err != nil {
 		utils.Logger.Printf("Error adding review: %v", err)
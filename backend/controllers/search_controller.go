@@ -5,6 +5,7 @@ import (
 	"backend/services"
 	"backend/utils"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 )
@@ -16,6 +17,10 @@ func SearchProperties(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if userID, ok := r.Context().Value("userID").(string); ok {
+		recordSignal(models.NewSearchSignal(userID, filters))
+	}
+
 	limit := int64(10)
 	if limitParam, ok := filters["limit"].(float64); ok && limitParam > 0 { // JSON decoders in Go parse numeric values into float64 by default.
 		limit = int64(limitParam)
@@ -53,16 +58,22 @@ func SearchProperties(w http.ResponseWriter, r *http.Request) {
 	}
 
 	properties, err := models.SearchProperties(filters, limit)
+	if errors.Is(err, models.ErrInvalidSearchFilter) {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		utils.Logger.Printf("Error searching properties in database: %v", err)
 		utils.WriteErrorResponse(w, "Failed to search properties", http.StatusInternalServerError)
 		return
 	}
 
+	models.HideContacts(properties)
 	utils.WriteSuccessResponse(w, properties, http.StatusOK)
 }
 
-// GetPopularPlaces retrieves the top N most searched cities.
+// GetPopularPlaces retrieves the top N most searched places by canonical name, with IDs, kinds
+// and search counts when detailed=true.
 func GetPopularPlaces(w http.ResponseWriter, r *http.Request) {
 	limitStr := r.URL.Query().Get("limit")
 	limit := 5 // Default limit
@@ -74,6 +85,17 @@ func GetPopularPlaces(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	if r.URL.Query().Get("detailed") == "true" {
+		places, err := services.GetTopSearchedPlacesDetailed(ctx, limit)
+		if err != nil {
+			utils.Logger.Printf("Error getting top searched places from Redis: %v", err)
+			utils.WriteErrorResponse(w, "Failed to retrieve popular places", http.StatusInternalServerError)
+			return
+		}
+		utils.WriteSuccessResponse(w, places, http.StatusOK)
+		return
+	}
+
 	topPlaces, err := services.GetTopSearchedPlaces(ctx, limit)
 	if err != nil {
 		utils.Logger.Printf("Error getting top searched places from Redis: %v", err)
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const maxShortlistNameChars = 60
+
+type shortlistRequest struct {
+	Name string `json:"name"`
+}
+
+func shortlistName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req shortlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return "", false
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxShortlistNameChars {
+		utils.WriteErrorResponse(w, "Shortlist name must be 1 to 60 characters", http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// writeShortlistError answers the limits a user can fix with 409 and the rest with 500
+func writeShortlistError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, models.ErrShortlistNameTaken), errors.Is(err, models.ErrTooManyShortlists), errors.Is(err, models.ErrShortlistFull):
+		utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Logger.Printf("Failed to %s: %v", action, err)
+		utils.WriteErrorResponse(w, "Failed to "+action, http.StatusInternalServerError)
+	}
+}
+
+// GetShortlists lists the caller's shortlists with their saved listing IDs, notes and tags
+func GetShortlists(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	shortlists, err := models.GetUserShortlists(userID)
+	if err != nil {
+		writeShortlistError(w, err, "load shortlists")
+		return
+	}
+	utils.WriteSuccessResponse(w, shortlists, http.StatusOK)
+}
+
+func CreateShortlist(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	name, ok := shortlistName(w, r)
+	if !ok {
+		return
+	}
+	shortlist, err := models.CreateShortlist(userID, name)
+	if err != nil {
+		writeShortlistError(w, err, "create shortlist")
+		return
+	}
+	utils.WriteSuccessResponse(w, shortlist, http.StatusCreated)
+}
+
+// GetShortlist returns one shortlist with the saved listings filled in. Listings that were
+// deleted or hidden since keep their note and tags but have no property.
+func GetShortlist(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	shortlist, err := models.GetShortlist(userID, mux.Vars(r)["shortlistId"])
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			utils.WriteErrorResponse(w, "Shortlist not found", http.StatusNotFound)
+			return
+		}
+		writeShortlistError(w, err, "load shortlist")
+		return
+	}
+
+	ids := make([]string, len(shortlist.Items))
+	for i, item := range shortlist.Items {
+		ids[i] = item.PropertyID
+	}
+	properties, err := models.FindPropertiesByIDs(ids)
+	if err != nil {
+		writeShortlistError(w, err, "load shortlist")
+		return
+	}
+	models.HideContacts(properties)
+	byID := make(map[string]*models.Property, len(properties))
+	for _, property := range properties {
+		byID[property.ID.Hex()] = property
+	}
+	for i := range shortlist.Items {
+		shortlist.Items[i].Property = byID[shortlist.Items[i].PropertyID]
+	}
+	utils.WriteSuccessResponse(w, shortlist, http.StatusOK)
+}
+
+func RenameShortlist(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	name, ok := shortlistName(w, r)
+	if !ok {
+		return
+	}
+	found, err := models.RenameShortlist(userID, mux.Vars(r)["shortlistId"], name)
+	if err != nil {
+		writeShortlistError(w, err, "rename shortlist")
+		return
+	}
+	if !found {
+		utils.WriteErrorResponse(w, "Shortlist not found", http.StatusNotFound)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Shortlist renamed"}, http.StatusOK)
+}
+
+func DeleteShortlist(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	found, err := models.DeleteShortlist(userID, mux.Vars(r)["shortlistId"])
+	if err != nil {
+		writeShortlistError(w, err, "delete shortlist")
+		return
+	}
+	if !found {
+		utils.WriteErrorResponse(w, "Shortlist not found", http.StatusNotFound)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Shortlist deleted"}, http.StatusOK)
+}
+
+// SaveShortlistItem saves a listing to a shortlist with an optional private note and tags, or
+// updates them when it is already saved
+func SaveShortlistItem(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	params := mux.Vars(r)
+
+	var item models.ShortlistItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if message := models.CleanShortlistItem(&item); message != "" {
+		utils.WriteErrorResponse(w, message, http.StatusBadRequest)
+		return
+	}
+	item.PropertyID = params["propertyId"]
+	item.Property = nil
+
+	property, err := models.FindPropertyByID(item.PropertyID)
+	if err != nil || !property.IsPublic() {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+
+	found, err := models.SaveShortlistItem(userID, params["shortlistId"], item)
+	if err != nil {
+		writeShortlistError(w, err, "save to shortlist")
+		return
+	}
+	if !found {
+		utils.WriteErrorResponse(w, "Shortlist not found", http.StatusNotFound)
+		return
+	}
+	recordSignal(models.NewPropertySignal(userID, models.SignalFavorite, property))
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Saved to shortlist"}, http.StatusOK)
+}
+
+func RemoveShortlistItem(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	params := mux.Vars(r)
+
+	removed, err := models.RemoveShortlistItem(userID, params["shortlistId"], params["propertyId"])
+	if err != nil {
+		writeShortlistError(w, err, "remove from shortlist")
+		return
+	}
+	if !removed {
+		utils.WriteErrorResponse(w, "Property is not on this shortlist", http.StatusNotFound)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Removed from shortlist"}, http.StatusOK)
+}
+
+// CompareProperties lines up ?ids= (2 to 4 listing IDs) field by field with rent per sq ft,
+// move-in cost and the distance to ?offices=, or to the caller's saved offices
+func CompareProperties(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	query := r.URL.Query()
+
+	var ids []string
+	for _, id := range strings.Split(query.Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 || len(ids) > models.MaxComparedProperties {
+		utils.WriteErrorResponse(w, "ids must list 2 to 4 property IDs", http.StatusBadRequest)
+		return
+	}
+
+	var officeQueries []string
+	if officesParam := query.Get("offices"); officesParam != "" {
+		officeQueries = strings.Split(officesParam, ",")
+	} else if user, err := models.FindUserByID(userID); err == nil {
+		officeQueries = user.Offices
+	}
+
+	properties, err := models.FindPropertiesByIDs(ids)
+	if err != nil {
+		utils.Logger.Printf("Failed to load properties to compare: %v", err)
+		utils.WriteErrorResponse(w, "Failed to compare properties", http.StatusInternalServerError)
+		return
+	}
+	if len(properties) < 2 {
+		utils.WriteErrorResponse(w, "At least two of the properties must exist", http.StatusNotFound)
+		return
+	}
+
+	models.HideContacts(properties)
+	comparison, err := models.CompareProperties(properties, models.ResolveOffices(officeQueries))
+	if err != nil {
+		utils.Logger.Printf("Failed to compare properties: %v", err)
+		utils.WriteErrorResponse(w, "Failed to compare properties", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, comparison, http.StatusOK)
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const maxSocietyReviewChars = 2000
+
+// SuggestSocieties autocompletes society names for ?q=, optionally in ?city=. ?limit= caps the
+// results (8 by default, at most 20).
+func SuggestSocieties(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := models.DefaultSocietySuggestions
+	if limitParam := query.Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 || parsed > models.MaxSocietySuggestions {
+			utils.WriteErrorResponse(w, "limit must be between 1 and "+strconv.Itoa(models.MaxSocietySuggestions), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	societies, err := models.SuggestSocieties(query.Get("q"), query.Get("city"), limit)
+	if err != nil {
+		utils.Logger.Printf("Failed to suggest societies for %q: %v", query.Get("q"), err)
+		utils.WriteErrorResponse(w, "Failed to fetch societies", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, societies, http.StatusOK)
+}
+
+// GetSociety returns a society page: the society with its live listings, rent range and reviews
+func GetSociety(w http.ResponseWriter, r *http.Request) {
+	societyID := mux.Vars(r)["id"]
+	page, err := models.GetSocietyPage(societyID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+			utils.WriteErrorResponse(w, "Society not found", http.StatusNotFound)
+			return
+		}
+		utils.Logger.Printf("Failed to load society %s: %v", societyID, err)
+		utils.WriteErrorResponse(w, "Failed to fetch society", http.StatusInternalServerError)
+		return
+	}
+	models.HideContacts(page.Listings)
+	utils.WriteSuccessResponse(w, page, http.StatusOK)
+}
+
+func CreateSociety(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var society models.Society
+	if err := json.NewDecoder(r.Body).Decode(&society); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if validationErrors := society.Validate(); len(validationErrors) > 0 {
+		utils.WriteErrorResponse(w, validationErrors[0], http.StatusBadRequest)
+		return
+	}
+	society.CreatedBy = userID
+
+	if err := models.CreateSociety(&society); err != nil {
+		if errors.Is(err, models.ErrSocietyExists) {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+			return
+		}
+		utils.Logger.Printf("Failed to create society: %v", err)
+		utils.WriteErrorResponse(w, "Failed to create society", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, society, http.StatusCreated)
+}
+
+// UpdateSociety replaces the details of a society; only whoever created it and admins may
+func UpdateSociety(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	role, _ := r.Context().Value("userRole").(string)
+	societyID := mux.Vars(r)["id"]
+
+	stored, err := models.FindSocietyByID(societyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Society not found", http.StatusNotFound)
+		return
+	}
+	if stored.CreatedBy != userID && role != "admin" {
+		utils.WriteErrorResponse(w, "Unauthorized to update this society", http.StatusForbidden)
+		return
+	}
+
+	var society models.Society
+	if err := json.NewDecoder(r.Body).Decode(&society); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if validationErrors := society.Validate(); len(validationErrors) > 0 {
+		utils.WriteErrorResponse(w, validationErrors[0], http.StatusBadRequest)
+		return
+	}
+
+	if err := models.UpdateSociety(societyID, &society); err != nil {
+		if errors.Is(err, models.ErrSocietyExists) {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+			return
+		}
+		utils.Logger.Printf("Failed to update society %s: %v", societyID, err)
+		utils.WriteErrorResponse(w, "Failed to update society", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Society updated successfully"}, http.StatusOK)
+}
+
+// ReviewSociety saves the caller's rating and comment of a society, replacing their earlier review
+func ReviewSociety(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	societyID := mux.Vars(r)["id"]
+
+	var review models.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if review.Rating < 1 || review.Rating > 5 {
+		utils.WriteErrorResponse(w, "Rating must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+	review.Comment = strings.TrimSpace(review.Comment)
+	if len([]rune(review.Comment)) > maxSocietyReviewChars {
+		utils.WriteErrorResponse(w, "Comment must be at most 2000 characters", http.StatusBadRequest)
+		return
+	}
+	if _, err := models.FindSocietyByID(societyID); err != nil {
+		utils.WriteErrorResponse(w, "Society not found", http.StatusNotFound)
+		return
+	}
+
+	review.UserID = userID
+	review.SocietyID = societyID
+	if err := models.SaveSocietyReview(&review); err != nil {
+		utils.Logger.Printf("Failed to save review of society %s: %v", societyID, err)
+		utils.WriteErrorResponse(w, "Failed to save review", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, review, http.StatusOK)
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"backend/jobs"
+	"backend/models"
+	"backend/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const presignedUploadExpiry = 15 * time.Minute
+
+var uploadExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// uploadPrefix is the only part of the bucket a user may upload raw files to
+func uploadPrefix(userID string, purpose string) string {
+	return fmt.Sprintf("/uploads/user_%s/%s/", userID, purpose)
+}
+
+func uploadPolicyFor(purpose string) (utils.UploadPolicy, bool) {
+	switch purpose {
+	case "property", "society":
+		return utils.PhotoUploadPolicy, true
+	case "profile":
+		return utils.ProfilePictureUploadPolicy, true
+	}
+	return utils.UploadPolicy{}, false
+}
+
+// PresignUpload issues a short-lived URL so the client can PUT a photo straight to storage
+func PresignUpload(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var payload struct {
+		Purpose     string `json:"purpose"` // "property", "society" or "profile"
+		ContentType string `json:"contentType"`
+		Size        int64  `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	policy, ok := uploadPolicyFor(payload.Purpose)
+	if !ok {
+		utils.WriteErrorResponse(w, "Purpose must be 'property', 'society' or 'profile'", http.StatusBadRequest)
+		return
+	}
+	ext, ok := uploadExtensions[payload.ContentType]
+	if !ok {
+		utils.WriteErrorResponse(w, utils.ErrUnsupportedFileType.Error(), http.StatusBadRequest)
+		return
+	}
+	if payload.Size <= 0 || payload.Size > policy.MaxFileBytes {
+		utils.WriteErrorResponse(w, fmt.Sprintf("Size must be between 1 byte and %d MB", policy.MaxFileBytes>>20), http.StatusBadRequest)
+		return
+	}
+
+	key := fmt.Sprintf("%s%s_%s%s", uploadPrefix(userID, payload.Purpose), time.Now().Format("20060102150405"), uuid.New().String(), ext)
+	uploadURL, err := utils.PresignPutURL(key, payload.ContentType, payload.Size, presignedUploadExpiry)
+	if err != nil {
+		utils.Logger.Printf("Failed to presign upload for user %s: %v", userID, err)
+		utils.WriteErrorResponse(w, "Failed to create upload URL", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, map[string]interface{}{
+		"uploadUrl": uploadURL,
+		"key":       key,
+		"method":    http.MethodPut,
+		"headers": map[string]string{
+			"Content-Type":   payload.ContentType,
+			"Content-Length": fmt.Sprintf("%d", payload.Size),
+		},
+		"expiresAt": time.Now().Add(presignedUploadExpiry),
+	}, http.StatusOK)
+}
+
+// CompleteUpload verifies a presigned upload, turns it into renditions and attaches it
+// to a listing, a society or the user's profile. The raw upload is deleted afterwards.
+func CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var payload struct {
+		Key        string `json:"key"`
+		PropertyID string `json:"propertyId,omitempty"` // required when the upload is a listing photo
+		SocietyID  string `json:"societyId,omitempty"`  // required when the upload is a society photo
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	purpose := ""
+	for _, p := range []string{"property", "society", "profile"} {
+		if strings.HasPrefix(payload.Key, uploadPrefix(userID, p)) && !strings.Contains(payload.Key, "..") {
+			purpose = p
+		}
+	}
+	if purpose == "" {
+		utils.WriteErrorResponse(w, "Upload key does not belong to this user", http.StatusForbidden)
+		return
+	}
+	if purpose != "profile" && models.IsUserSuspended(userID) {
+		utils.WriteErrorResponse(w, suspendedMessage, http.StatusForbidden)
+		return
+	}
+	policy, _ := uploadPolicyFor(purpose)
+
+	var property *models.Property
+	if purpose == "property" {
+		var err error
+		property, err = models.FindPropertyByID(payload.PropertyID)
+		if err != nil {
+			utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+			return
+		}
+		if property.OwnerID != userID {
+			utils.WriteErrorResponse(w, "Unauthorized to update this property", http.StatusForbidden)
+			return
+		}
+		if len(property.Photos) >= policy.MaxFiles {
+			utils.WriteErrorResponse(w, fmt.Sprintf("Too many files, max %d photos can be uploaded", policy.MaxFiles), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if purpose == "society" {
+		role, _ := r.Context().Value("userRole").(string)
+		society, err := models.FindSocietyByID(payload.SocietyID)
+		if err != nil {
+			utils.WriteErrorResponse(w, "Society not found", http.StatusNotFound)
+			return
+		}
+		// whoever added the society, admins and owners of a live listing in it share its photos
+		allowed := society.CreatedBy == userID || role == "admin"
+		if !allowed {
+			allowed, err = models.OwnsPublishedListingInSociety(userID, payload.SocietyID)
+			if err != nil {
+				utils.Logger.Printf("Failed to check listings of user %s in society %s: %v", userID, payload.SocietyID, err)
+				utils.WriteErrorResponse(w, "Failed to attach photo", http.StatusInternalServerError)
+				return
+			}
+		}
+		if !allowed {
+			utils.WriteErrorResponse(w, "Unauthorized to add photos to this society", http.StatusForbidden)
+			return
+		}
+		if len(society.PhotoSet) >= policy.MaxFiles {
+			utils.WriteErrorResponse(w, fmt.Sprintf("Too many files, max %d photos can be uploaded", policy.MaxFiles), http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := utils.GetObjectBytes(payload.Key, policy.MaxFileBytes)
+	if errors.Is(err, utils.ErrBlobNotFound) {
+		utils.WriteErrorResponse(w, "Uploaded file not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, utils.ErrFileTooLarge) {
+		utils.DeleteObject(payload.Key)
+		utils.WriteErrorResponse(w, utils.ErrFileTooLarge.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		utils.Logger.Printf("Failed to download upload %s: %v", payload.Key, err)
+		utils.WriteErrorResponse(w, "Failed to read uploaded file", http.StatusInternalServerError)
+		return
+	}
+	// the raw upload still carries EXIF, never keep it around
+	defer func() {
+		if err := utils.DeleteObject(payload.Key); err != nil {
+			utils.Logger.Printf("Failed to delete raw upload %s: %v", payload.Key, err)
+		}
+	}()
+
+	if err := policy.CheckBytes(data); err != nil {
+		utils.WriteErrorResponseWithDetails(w, "Uploaded file was rejected", []utils.FileError{{File: payload.Key, Error: err.Error()}}, http.StatusBadRequest)
+		return
+	}
+
+	var basePath string
+	switch purpose {
+	case "property":
+		basePath = fmt.Sprintf("/properties/user_%s/%s_%s", userID, time.Now().Format("20060102150405"), uuid.New().String())
+	case "society":
+		basePath = fmt.Sprintf("/societies/%s/%s_%s", payload.SocietyID, time.Now().Format("20060102150405"), uuid.New().String())
+	default:
+		basePath = fmt.Sprintf("/profile_picture/user_%s/%s", userID, time.Now().Format("20060102150405"))
+	}
+	stored, err := utils.StoreImageRenditions(data, basePath, policy)
+	if err != nil {
+		utils.Logger.Printf("Failed to process upload %s: %v", payload.Key, err)
+		utils.WriteErrorResponse(w, "Failed to process image", http.StatusInternalServerError)
+		return
+	}
+
+	if purpose == "property" || purpose == "society" {
+		photo := models.Photo{
+			URL:        utils.PickRendition(stored.Renditions, "full", "jpeg"),
+			Renditions: stored.Renditions,
+			Hash:       stored.PerceptualHash,
+		}
+		target := payload.PropertyID
+		if purpose == "property" {
+			err = models.AddPropertyPhoto(target, photo)
+		} else {
+			target = payload.SocietyID
+			err = models.AddSocietyPhoto(target, photo)
+		}
+		if err != nil {
+			utils.Logger.Printf("Failed to attach photo to %s %s: %v", purpose, target, err)
+			utils.WriteErrorResponse(w, "Failed to attach photo", http.StatusInternalServerError)
+			return
+		}
+		if purpose == "property" {
+			// the photo may be one another owner's listing already uses
+			if err := jobs.RefreshFingerprint(target); err != nil {
+				utils.Logger.Printf("Failed to refresh fingerprint of property %s: %v", target, err)
+			}
+			if jobs.ReassessRisk(target) {
+				go jobs.NotifyModeration(target, property.OwnerID, jobs.PropertyHeldEvent)
+			}
+		}
+		utils.WriteSuccessResponse(w, photo, http.StatusCreated)
+		return
+	}
+
+	user, err := models.FindUserByID(userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "User not found", http.StatusNotFound)
+		return
+	}
+	user.Picture = utils.PickRendition(stored.Renditions, "card", "jpeg")
+	if err := models.UpdateUser(userID, user); err != nil {
+		utils.WriteErrorResponse(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]interface{}{"picture": user.Picture, "renditions": stored.Renditions}, http.StatusCreated)
+}
@@ -5,7 +5,7 @@ import (
 	"backend/utils"
 	"fmt"
 	"net/http"
-	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -24,6 +24,7 @@ func GetUserProfile(w http.ResponseWriter, r *http.Request) {
 
 func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(string)
+	utils.ProfilePictureUploadPolicy.LimitRequestBody(w, r)
 	err := r.ParseMultipartForm(10 << 20) // 10 MB
 	if err != nil {
 		utils.WriteErrorResponse(w, "Failed to parse form", http.StatusBadRequest)
@@ -32,18 +33,42 @@ func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 
 	name := r.FormValue("name")
 
+	// offices is a comma separated list of workplaces; sending it empty clears them
+	var offices []string
+	_, setOffices := r.MultipartForm.Value["offices"]
+	if setOffices {
+		offices = []string{}
+		for _, query := range strings.Split(r.FormValue("offices"), ",") {
+			if strings.TrimSpace(query) == "" {
+				continue
+			}
+			office := models.FindWorkplace(query)
+			if office == nil {
+				utils.WriteErrorResponse(w, fmt.Sprintf("Unknown office %q", query), http.StatusBadRequest)
+				return
+			}
+			offices = append(offices, office.ID)
+		}
+	}
+
 	file, fileHeader, err := r.FormFile("profilePicture")
 	var pictureURL string
 	if err == nil {
 		defer file.Close()
 
-		fileName := fmt.Sprintf("/profile_picture/user_%s/%s%s", userID, time.Now().Format("20060102150405"), filepath.Ext(fileHeader.Filename))
-		pictureURL, err = utils.UploadFileToS3(file, fileHeader, fileName, userID)
+		if err := utils.ProfilePictureUploadPolicy.CheckFile(fileHeader); err != nil {
+			utils.WriteErrorResponseWithDetails(w, "Profile picture was rejected", []utils.FileError{{File: fileHeader.Filename, Error: err.Error()}}, http.StatusBadRequest)
+			return
+		}
+
+		basePath := fmt.Sprintf("/profile_picture/user_%s/%s", userID, time.Now().Format("20060102150405"))
+		stored, err := utils.UploadImageRenditions(file, basePath, utils.ProfilePictureUploadPolicy)
 		if err != nil {
 			utils.Logger.Printf("Failed to upload file to Supabase: %v", err)
 			utils.WriteErrorResponse(w, "Failed to upload image", http.StatusInternalServerError)
 			return
 		}
+		pictureURL = utils.PickRendition(stored.Renditions, "card", "jpeg")
 	}
 
 	user, err := models.FindUserByID(userID)
@@ -58,6 +83,9 @@ func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 	if pictureURL != "" {
 		user.Picture = pictureURL
 	}
+	if setOffices {
+		user.Offices = offices
+	}
 
 	err = models.UpdateUser(userID, user)
 	if err != nil {
@@ -75,6 +103,7 @@ func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 			"name":    user.Name,
 			"role":    user.Role,
 			"picture": user.Picture,
+			"offices": user.Offices,
 		},
 	}, http.StatusOK)
 }
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"backend/jobs"
+	"backend/models"
+	"backend/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const defaultVisitOpeningDays = 7
+
+type visitRequest struct {
+	StartsAt time.Time `json:"startsAt"`
+	Note     string    `json:"note"`
+}
+
+// writeVisitError answers the booking errors a tenant can fix with 409 and the rest with 500
+func writeVisitError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, models.ErrVisitUnavailable), errors.Is(err, models.ErrVisitFull),
+		errors.Is(err, models.ErrVisitAlreadyBooked), errors.Is(err, models.ErrVisitNotUpcoming):
+		utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Logger.Printf("Failed to %s visit: %v", action, err)
+		utils.WriteErrorResponse(w, "Failed to "+action+" visit", http.StatusInternalServerError)
+	}
+}
+
+// GetVisitSlots returns a listing's visiting slots and the visit times still open over the next
+// ?days= days (7 by default)
+func GetVisitSlots(w http.ResponseWriter, r *http.Request) {
+	propertyID := mux.Vars(r)["id"]
+	if _, err := models.FindPropertyByID(propertyID); err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+
+	days := defaultVisitOpeningDays
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		parsed, err := strconv.Atoi(daysParam)
+		if err != nil || parsed <= 0 || parsed > models.MaxVisitOpeningDays {
+			utils.WriteErrorResponse(w, "days must be between 1 and "+strconv.Itoa(models.MaxVisitOpeningDays), http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	slots, err := models.GetVisitSlots(propertyID)
+	if err != nil {
+		utils.Logger.Printf("Failed to load visit slots of property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to load visit slots", http.StatusInternalServerError)
+		return
+	}
+	openings, err := models.GetVisitOpenings(propertyID, time.Now(), days)
+	if err != nil {
+		utils.Logger.Printf("Failed to load visit openings of property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to load visit slots", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]interface{}{
+		"slots":    slots,
+		"openings": openings,
+	}, http.StatusOK)
+}
+
+// AddVisitSlot publishes a weekly or one-off visiting slot for one of the caller's listings
+func AddVisitSlot(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	if property.OwnerID != userID {
+		utils.WriteErrorResponse(w, "Unauthorized to manage visits of this property", http.StatusForbidden)
+		return
+	}
+
+	var slot models.VisitSlot
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if validationErrors := slot.Validate(); len(validationErrors) > 0 {
+		utils.WriteErrorResponse(w, validationErrors[0], http.StatusBadRequest)
+		return
+	}
+	slot.PropertyID = propertyID
+
+	if err := models.AddVisitSlot(&slot); err != nil {
+		utils.Logger.Printf("Failed to add visit slot to property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to add visit slot", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, slot, http.StatusCreated)
+}
+
+// DeleteVisitSlot removes a visiting slot; visits already booked in it are kept
+func DeleteVisitSlot(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	params := mux.Vars(r)
+	propertyID := params["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	if property.OwnerID != userID {
+		utils.WriteErrorResponse(w, "Unauthorized to manage visits of this property", http.StatusForbidden)
+		return
+	}
+
+	deleted, err := models.DeleteVisitSlot(propertyID, params["slotId"])
+	if err != nil {
+		utils.Logger.Printf("Failed to delete visit slot %s: %v", params["slotId"], err)
+		utils.WriteErrorResponse(w, "Failed to delete visit slot", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		utils.WriteErrorResponse(w, "Visit slot not found", http.StatusNotFound)
+		return
+	}
+	utils.WriteSuccessResponse(w, map[string]string{"message": "Visit slot deleted"}, http.StatusOK)
+}
+
+// GetPropertyVisits lists every visit of a listing to its owner and the caller's own visits to
+// anyone else. ?upcoming=true leaves out past and cancelled visits.
+func GetPropertyVisits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+
+	filter := bson.M{"propertyId": propertyID}
+	if property.OwnerID != userID {
+		filter["tenantId"] = userID
+	}
+	visits, err := models.GetVisits(filter, r.URL.Query().Get("upcoming") == "true")
+	if err != nil {
+		utils.Logger.Printf("Failed to load visits of property %s: %v", propertyID, err)
+		utils.WriteErrorResponse(w, "Failed to load visits", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, visits, http.StatusOK)
+}
+
+// BookVisit books the caller a visit at one of the listing's open visit times
+func BookVisit(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	propertyID := mux.Vars(r)["id"]
+
+	var req visitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.StartsAt.IsZero() {
+		utils.WriteErrorResponse(w, "startsAt must be an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil || !property.IsPublic() {
+		utils.WriteErrorResponse(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	if property.OwnerID == userID {
+		utils.WriteErrorResponse(w, "You cannot book a visit to your own listing", http.StatusBadRequest)
+		return
+	}
+
+	visit, err := models.BookVisit(property, userID, req.StartsAt, req.Note)
+	if err != nil {
+		writeVisitError(w, err, "book")
+		return
+	}
+	go jobs.NotifyVisitChange(visit, jobs.VisitBookedEvent, userID)
+	utils.WriteSuccessResponse(w, visit, http.StatusCreated)
+}
+
+// visitForRequest loads the visit in the URL and checks the caller is its tenant or owner
+func visitForRequest(w http.ResponseWriter, r *http.Request) (*models.Visit, string, bool) {
+	userID := r.Context().Value("userID").(string)
+	params := mux.Vars(r)
+
+	visit, err := models.GetVisitByID(params["visitId"])
+	if err != nil || visit.PropertyID != params["id"] {
+		utils.WriteErrorResponse(w, "Visit not found", http.StatusNotFound)
+		return nil, "", false
+	}
+	if visit.TenantID != userID && visit.OwnerID != userID {
+		utils.WriteErrorResponse(w, "Unauthorized to change this visit", http.StatusForbidden)
+		return nil, "", false
+	}
+	return visit, userID, true
+}
+
+// RescheduleVisit moves a visit to another open visit time; tenant and owner may both move it
+func RescheduleVisit(w http.ResponseWriter, r *http.Request) {
+	visit, userID, ok := visitForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req visitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.StartsAt.IsZero() {
+		utils.WriteErrorResponse(w, "startsAt must be an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.RescheduleVisit(visit, req.StartsAt); err != nil {
+		writeVisitError(w, err, "reschedule")
+		return
+	}
+	go jobs.NotifyVisitChange(visit, jobs.VisitRescheduledEvent, userID)
+	utils.WriteSuccessResponse(w, visit, http.StatusOK)
+}
+
+// CancelVisit cancels a visit; tenant and owner may both cancel it
+func CancelVisit(w http.ResponseWriter, r *http.Request) {
+	visit, userID, ok := visitForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := models.CancelVisit(visit, userID); err != nil {
+		writeVisitError(w, err, "cancel")
+		return
+	}
+	go jobs.NotifyVisitChange(visit, jobs.VisitCancelledEvent, userID)
+	utils.WriteSuccessResponse(w, visit, http.StatusOK)
+}
+
+// GetUserVisits lists the caller's visits as a tenant, or with ?as=owner the visits booked to
+// their listings. ?upcoming=true leaves out past and cancelled visits.
+func GetUserVisits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	query := r.URL.Query()
+
+	filter := bson.M{"tenantId": userID}
+	switch query.Get("as") {
+	case "", "tenant":
+	case "owner":
+		filter = bson.M{"ownerId": userID}
+	default:
+		utils.WriteErrorResponse(w, "as must be tenant or owner", http.StatusBadRequest)
+		return
+	}
+
+	visits, err := models.GetVisits(filter, query.Get("upcoming") == "true")
+	if err != nil {
+		utils.Logger.Printf("Failed to load visits of user %s: %v", userID, err)
+		utils.WriteErrorResponse(w, "Failed to load visits", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, visits, http.StatusOK)
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/utils"
+	"net/http"
+)
+
+// GetWorkplaces lists the offices and tech parks a search can be ranked by, filtered by ?q= and ?city=
+func GetWorkplaces(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	workplaces := models.SearchWorkplaces(query.Get("q"), query.Get("city"))
+	if workplaces == nil {
+		workplaces = []models.Workplace{}
+	}
+	utils.WriteSuccessResponse(w, workplaces, http.StatusOK)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"backend/jobs"
+	"backend/models"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runImportCommand is the CLI for bulk imports:
+//
+//	backend import -owner <userID> -file listings.csv [-mapping mapping.json] [-format csv]
+//	backend import -resume <jobID>
+func runImportCommand(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	filePath := flags.String("file", "", "CSV, XLSX or NDJSON file to import")
+	mappingPath := flags.String("mapping", "", "JSON file mapping source columns to property fields")
+	ownerID := flags.String("owner", "", "user ID the listings are imported for")
+	format := flags.String("format", "", "csv, xlsx or ndjson (defaults to the file extension)")
+	resumeID := flags.String("resume", "", "ID of a failed or interrupted import to resume")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	jobID := *resumeID
+	if jobID == "" {
+		if *filePath == "" || *ownerID == "" {
+			fmt.Fprintln(os.Stderr, "import: -file and -owner are required unless -resume is given")
+			flags.Usage()
+			return 2
+		}
+		data, err := os.ReadFile(*filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "import: %v\n", err)
+			return 1
+		}
+		var mapping map[string]string
+		if *mappingPath != "" {
+			mappingJSON, err := os.ReadFile(*mappingPath)
+			if err == nil {
+				err = json.Unmarshal(mappingJSON, &mapping)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "import: invalid mapping file: %v\n", err)
+				return 1
+			}
+		}
+		if *format == "" {
+			*format = jobs.ImportFormatFromFileName(*filePath)
+		}
+
+		job, err := jobs.CreateImport(*ownerID, *format, data, mapping)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "import: %v\n", err)
+			return 1
+		}
+		jobID = job.ID.Hex()
+		fmt.Printf("Created import job %s with %d rows\n", jobID, job.TotalRows)
+	}
+
+	runErr := jobs.RunImportJob(jobID)
+
+	job, err := models.GetImportJobByID(jobID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: failed to load job %s: %v\n", jobID, err)
+		return 1
+	}
+	for _, row := range job.Rows {
+		if row.Accepted {
+			fmt.Printf("row %d: accepted as %s\n", row.Row, row.PropertyID)
+		} else {
+			fmt.Printf("row %d: rejected: %v\n", row.Row, row.Errors)
+		}
+	}
+	fmt.Printf("Import %s %s: %d/%d rows processed, %d accepted, %d rejected\n",
+		jobID, job.Status, job.ProcessedRows, job.TotalRows, job.Accepted, job.Rejected)
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "import: %v (resume with -resume %s)\n", runErr, jobID)
+		return 1
+	}
+	return 0
+}
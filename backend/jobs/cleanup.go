@@ -7,9 +7,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/google/generative-ai-go/genai"
+	"go.mongodb.org/mongo-driver/bson"
 	"google.golang.org/api/option"
 	"os"
 	"strings"
+	"time"
 )
 
 func CleanupJob(property *models.Property) (*models.Property, error) {
@@ -59,6 +61,16 @@ func CleanupJob(property *models.Property) (*models.Property, error) {
 	  SecurityDeposit int
 	  MaintenanceCharges int
 	  LeaseTerm string
+	  AvailableFrom string // YYYY-MM-DD
+	  MinimumLeaseMonths int
+	  LockInMonths int
+	  NoticePeriodDays int
+	  SalePrice int // sale listings only, in rupees
+	  CarpetAreaSqft float64 // sale listings only
+	  BuiltUpAreaSqft float64 // sale listings only
+	  PossessionStatus string // sale listings only: "Ready to move", "Under construction"
+	  PropertyAgeYears int // sale listings only
+	  Ownership string // sale listings only: "Freehold", "Leasehold", "Co-operative society", "Power of attorney"
 	  Photos []string
 	  CreatedAt string // ISO-8601 format
 	  UpdatedAt string // ISO-8601 format
@@ -78,10 +90,13 @@ func CleanupJob(property *models.Property) (*models.Property, error) {
 	- Double check Bedrooms and Bathrooms, IsFamilyPreferred (if bachlors are allowed then IsFamilyPreferred is false)  from description
 	- Determining listing type ("Rent", "Sale", "Flatmate") and owner/broker post (IsOwnerListing or IsBrokerListing bool) and dietary preference from description
 	- Inferring security deposit and maintenance charges from the description if they are missing
-	- If there is any Tech park name or a Land mark in description then append add it in location (like kadubeesanahalli, Embassy Tech Village)
+	- For Sale listings: putting the asking price in SalePrice, not Rent, and leaving Rent, SecurityDeposit and the lease fields out. Convert "85 lakh" to 8500000 and "1.2 Cr" to 12000000. Extract CarpetAreaSqft and BuiltUpAreaSqft when the description tells them apart (super built-up counts as built-up), PossessionStatus, PropertyAgeYears and Ownership, leaving out what is not stated
+	- For Rent and Flatmate listings: leaving all the sale fields out
+	- Extracting AvailableFrom, MinimumLeaseMonths, LockInMonths and NoticePeriodDays from LeaseTerm and the description, leaving them out when not stated. Today is %s, so "immediately" is today and "from next month" is the 1st of next month
+	- Keeping Location as the locality given, without appending tech parks or landmarks to it
 	- If Link is empty in the struct and if contact number exist in description then add one of them as Link.
 	- Returning only the final cleaned JSON object only and only json 
-	`, string(propertyJson))
+	`, string(propertyJson), time.Now().Format(models.DateLayout))
 
 	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 	if err != nil {
@@ -104,6 +119,9 @@ func CleanupJob(property *models.Property) (*models.Property, error) {
 					utils.Logger.Errorf("Failed to unmarshal cleaned property: %v", err3)
 					continue
 				}
+				// photo renditions are produced by us, never by the model
+				cleaned.Photos = property.Photos
+				cleaned.PhotoSet = property.PhotoSet
 				return &cleaned, nil
 			} else {
 				utils.Logger.Printf("Skipping non-text part: %T", part)
@@ -114,3 +132,40 @@ func CleanupJob(property *models.Property) (*models.Property, error) {
 	utils.Logger.Printf("No valid candidate found in model response")
 	return nil, fmt.Errorf("no valid cleaned property returned")
 }
+
+// mergeCleanedProperty copies the CleanableFields the model filled in onto a copy of the stored
+// listing. Empty values never erase data. The broker flag is the model's answer; the preference
+// flags have no "unknown" state, so the model may only add a preference the description states.
+func mergeCleanedProperty(original *models.Property, result *models.Property) (*models.Property, error) {
+	raw, err := bson.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	cleanedFields := bson.M{}
+	for _, field := range models.CleanableFields {
+		if value, ok := doc[field]; ok {
+			cleanedFields[field] = value
+		}
+	}
+	raw, err = bson.Marshal(cleanedFields)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *original
+	if err := bson.Unmarshal(raw, &merged); err != nil {
+		return nil, err
+	}
+	merged.IsBrokerListing = result.IsBrokerListing
+	if original.SocietyID != "" {
+		merged.SocietyName = original.SocietyName // the linked society's canonical name
+	}
+	if !models.ValidDate(merged.AvailableFrom) {
+		merged.AvailableFrom = original.AvailableFrom // the model's date is unusable, keep the owner's
+	}
+	return &merged, nil
+}
@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"fmt"
+	"time"
+)
+
+const (
+	cleanupMaxAttempts  = 5
+	cleanupBaseBackoff  = 5 * time.Second
+	cleanupMaxBackoff   = 5 * time.Minute
+	cleanupPollInterval = 10 * time.Second
+	cleanupStaleAfter   = 2 * time.Minute
+
+	// PropertyProcessedEvent is the websocket message type sent to the owner when cleanup ends
+	PropertyProcessedEvent = "property.processed"
+)
+
+// cleanupWake lets EnqueueCleanup start a queued task right away instead of at the next poll
+var cleanupWake = make(chan struct{}, 1)
+
+// EnqueueCleanup queues the AI cleanup of a listing that was saved in the processing state
+func EnqueueCleanup(property *models.Property) (*models.CleanupTask, error) {
+	task, err := models.EnqueueCleanupTask(property.ID.Hex(), property.OwnerID, cleanupMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case cleanupWake <- struct{}{}:
+	default:
+	}
+	return task, nil
+}
+
+// StartCleanupWorker runs queued cleanup tasks in the background, one at a time
+func StartCleanupWorker() {
+	go func() {
+		ticker := time.NewTicker(cleanupPollInterval)
+		defer ticker.Stop()
+		for {
+			runDueCleanupTasks()
+			select {
+			case <-ticker.C:
+			case <-cleanupWake:
+			}
+		}
+	}()
+}
+
+func runDueCleanupTasks() {
+	for {
+		task, err := models.ClaimNextCleanupTask(cleanupStaleAfter)
+		if err != nil {
+			utils.Logger.Printf("Failed to claim cleanup task: %v", err)
+			return
+		}
+		if task == nil {
+			return
+		}
+		runCleanupTask(task)
+	}
+}
+
+func runCleanupTask(task *models.CleanupTask) {
+	property, err := models.FindPropertyByID(task.PropertyID)
+	if err != nil {
+		// the listing was deleted while queued
+		models.FinishCleanupTask(task.ID, models.CleanupFailed, "listing not found")
+		return
+	}
+	if property.Status != models.StatusProcessing {
+		models.FinishCleanupTask(task.ID, models.CleanupCompleted, "")
+		return
+	}
+
+	cleaned, err := cleanProperty(property)
+	if err == nil {
+		finishCleanup(task, property, cleaned, models.CleanupCompleted, "")
+		return
+	}
+
+	utils.Logger.Printf("Cleanup attempt %d/%d failed for property %s: %v", task.Attempts, task.MaxAttempts, task.PropertyID, err)
+	if task.Attempts < task.MaxAttempts {
+		if err := models.RetryCleanupTask(task.ID, time.Now().Add(cleanupBackoff(task.Attempts)), err.Error()); err != nil {
+			utils.Logger.Printf("Failed to reschedule cleanup of property %s: %v", task.PropertyID, err)
+		}
+		return
+	}
+
+	// out of attempts, publish the listing as the owner submitted it, with a position if one is found
+	var located *models.Property
+	if LocateProperty(property) {
+		located = property
+	}
+	finishCleanup(task, property, located, models.CleanupFailed, err.Error())
+}
+
+// cleanProperty runs the AI cleanup and merges the result onto the stored listing. A result that
+// breaks the listing rules counts as a failed attempt.
+func cleanProperty(property *models.Property) (*models.Property, error) {
+	result, err := CleanupJob(property)
+	if err != nil {
+		return nil, err
+	}
+	cleaned, err := mergeCleanedProperty(property, result)
+	if err != nil {
+		return nil, err
+	}
+	LocateProperty(cleaned)
+	cleaned.NormalizeLocality()
+	cleaned.FillPricePerSqft()
+	if validationErrors := cleaned.Validate(); len(validationErrors) > 0 {
+		return nil, fmt.Errorf("cleaned listing is invalid: %v", validationErrors)
+	}
+	cleaned.Fingerprint = BuildFingerprint(cleaned)
+	return cleaned, nil
+}
+
+func finishCleanup(task *models.CleanupTask, property *models.Property, cleaned *models.Property, status string, errMessage string) {
+	released, err := models.FinishPropertyProcessing(task.PropertyID, cleaned, status)
+	if err != nil {
+		utils.Logger.Printf("Failed to apply cleanup to property %s: %v", task.PropertyID, err)
+		if task.Attempts < task.MaxAttempts {
+			models.RetryCleanupTask(task.ID, time.Now().Add(cleanupBackoff(task.Attempts)), err.Error())
+			return
+		}
+		status, errMessage = models.CleanupFailed, err.Error()
+	}
+	if err := models.FinishCleanupTask(task.ID, status, errMessage); err != nil {
+		utils.Logger.Printf("Failed to record cleanup result of property %s: %v", task.PropertyID, err)
+	}
+	if released == "" {
+		return
+	}
+	// the cleanup may have rewritten the rent or description the listing was scored on
+	if cleaned != nil && released == models.StatusPublished && ReassessRisk(task.PropertyID) {
+		released = models.StatusOnHold
+	}
+
+	text := "Your listing is live"
+	if released == models.StatusOnHold {
+		text = "Your listing is being reviewed before it goes live"
+	} else if status == models.CleanupFailed {
+		text = "Your listing is live without automatic cleanup"
+	}
+	services.Broadcast <- services.Message{
+		Sender:     "system",
+		Receiver:   property.OwnerID,
+		Message:    text,
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Type:       PropertyProcessedEvent,
+		PropertyID: task.PropertyID,
+	}
+}
+
+// cleanupBackoff doubles the wait after every failed attempt: 5s, 10s, 20s, ... up to 5 minutes
+func cleanupBackoff(attempt int) time.Duration {
+	backoff := cleanupBaseBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > cleanupMaxBackoff {
+		return cleanupMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"backend/models"
+	"backend/utils"
+	"math"
+	"strings"
+)
+
+const (
+	duplicateThreshold   = 0.7 // score at which a listing is flagged as a copy
+	duplicateCandidates  = 50
+	descriptionSketchLen = 64
+)
+
+// BuildFingerprint derives the comparable parts of a listing
+func BuildFingerprint(property *models.Property) *models.Fingerprint {
+	location := utils.NormalizeText(property.Location)
+	if location == "" {
+		location = utils.NormalizeText(strings.Join([]string{property.Area, property.City}, " "))
+	}
+
+	var photoHashes []string
+	for _, photo := range property.PhotoSet {
+		if photo.Hash != "" {
+			photoHashes = append(photoHashes, photo.Hash)
+		}
+	}
+
+	return &models.Fingerprint{
+		LocationKey:         location,
+		SocietyKey:          utils.NormalizeText(property.SocietyName),
+		Rent:                property.Rent,
+		Bedrooms:            property.Bedrooms,
+		DescriptionShingles: utils.TextSketch(property.Description, descriptionSketchLen),
+		PhotoHashes:         photoHashes,
+		PhotoKeys:           utils.PhotoSetKeys(photoHashes),
+	}
+}
+
+// RefreshFingerprint rebuilds the stored fingerprint of a listing whose photos changed, so later
+// listings reusing them are recognised
+func RefreshFingerprint(propertyID string) error {
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		return err
+	}
+	return models.SetPropertyFingerprint(propertyID, BuildFingerprint(property))
+}
+
+// DuplicateScore is a 0-1 similarity between two fingerprints. Shared photos are the strongest
+// signal, brokers reword descriptions and round rents but reuse the owner's pictures.
+func DuplicateScore(a, b *models.Fingerprint) float64 {
+	score := 0.0
+
+	if a.LocationKey != "" && a.LocationKey == b.LocationKey {
+		score += 0.2
+	}
+	if a.SocietyKey != "" && a.SocietyKey == b.SocietyKey {
+		score += 0.15
+	}
+	if a.Bedrooms > 0 && a.Bedrooms == b.Bedrooms {
+		score += 0.1
+	}
+	if a.Rent > 0 && b.Rent > 0 {
+		diff := math.Abs(float64(a.Rent-b.Rent)) / math.Max(float64(a.Rent), float64(b.Rent))
+		score += 0.15 * math.Max(0, 1-diff*5) // full marks when equal, none at 20% apart
+	}
+	score += 0.2 * utils.SketchSimilarity(a.DescriptionShingles, b.DescriptionShingles)
+	score += 0.2 * photoOverlap(a.PhotoHashes, b.PhotoHashes)
+
+	// one identical photo in the same place is already convincing on its own
+	samePlace := (a.LocationKey != "" && a.LocationKey == b.LocationKey) || (a.SocietyKey != "" && a.SocietyKey == b.SocietyKey)
+	if samePlace && photoOverlap(a.PhotoHashes, b.PhotoHashes) > 0 {
+		score = math.Max(score, 0.85)
+	}
+	return math.Min(score, 1)
+}
+
+// photoOverlap is the share of the smaller photo set that has a near-identical photo in the other
+func photoOverlap(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	matched := 0
+	for _, ha := range a {
+		for _, hb := range b {
+			if utils.SamePhoto(ha, hb) {
+				matched++
+				break
+			}
+		}
+	}
+	return float64(matched) / float64(len(a))
+}
+
+// FlagDuplicates fingerprints a new listing before it is inserted and, if it looks like a copy of
+// an existing or deleted listing, records the best match and joins (or starts) its cluster. Any
+// cluster the client sent is dropped first, so no one can hide a listing behind their own.
+func FlagDuplicates(property *models.Property) error {
+	property.ClearDuplicateFields()
+	fp := BuildFingerprint(property)
+	property.Fingerprint = fp
+
+	candidates, err := models.FindDuplicateCandidates(fp, duplicateCandidates)
+	if err != nil {
+		return err
+	}
+
+	var best *models.Property
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		if candidate.Fingerprint == nil {
+			continue
+		}
+		if score := DuplicateScore(fp, candidate.Fingerprint); score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+
+	if best != nil && bestScore >= duplicateThreshold {
+		clusterID := best.DuplicateClusterID
+		if clusterID == "" {
+			clusterID = best.ID.Hex()
+			if err := models.SetDuplicateCluster(clusterID, clusterID); err != nil {
+				return err
+			}
+		}
+		property.DuplicateClusterID = clusterID
+		property.DuplicateOf = best.ID.Hex()
+		property.DuplicateScore = bestScore
+		utils.Logger.Printf("Listing by %s flagged as duplicate of %s (score %.2f)", property.OwnerID, best.ID.Hex(), bestScore)
+		return nil
+	}
+
+	deleted, err := models.FindDeletedDuplicateCandidates(fp, duplicateCandidates)
+	if err != nil {
+		return err
+	}
+	for _, d := range deleted {
+		if score := DuplicateScore(fp, d.Fingerprint); score >= duplicateThreshold && score > property.DuplicateScore {
+			property.DuplicateOf = d.PropertyID
+			property.DuplicateScore = score
+			property.IsRepost = true
+		}
+	}
+	if property.IsRepost {
+		utils.Logger.Printf("Listing by %s flagged as repost of deleted %s (score %.2f)", property.OwnerID, property.DuplicateOf, property.DuplicateScore)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"backend/models"
+	"testing"
+)
+
+// ownerListing is the original a broker copies
+func ownerListing() *models.Property {
+	return &models.Property{
+		Location:    "Koramangala 5th Block, Bangalore",
+		SocietyName: "Prestige Acropolis",
+		Rent:        30000,
+		Bedrooms:    2,
+		Description: "Sunny two bedroom flat on the fourth floor with a large balcony, covered parking, " +
+			"power backup and a modular kitchen. Five minutes from the Forum mall, families preferred.",
+		PhotoSet: []models.Photo{{Hash: "f0e1d2c3b4a59687"}, {Hash: "0123456789abcdef"}, {Hash: "8899aabbccddeeff"}},
+	}
+}
+
+func TestDuplicateScoreCatchesBrokerRepost(t *testing.T) {
+	original := BuildFingerprint(ownerListing())
+
+	// a broker rewords the description, rounds the rent up and re-uploads two of the photos
+	repost := ownerListing()
+	repost.Rent = 32000
+	repost.Description = "Premium 2BHK with big balcony and covered parking, power backup, modular kitchen, near Forum mall. Brokerage one month."
+	repost.PhotoSet = []models.Photo{{Hash: "f0e1d2c3b4a59686"}, {Hash: "0123456789abcdee"}}
+	if score := DuplicateScore(original, BuildFingerprint(repost)); score < duplicateThreshold {
+		t.Errorf("DuplicateScore of a broker repost = %.2f, want at least %v", score, duplicateThreshold)
+	}
+
+	// the owner relisting it months later with the same text
+	if score := DuplicateScore(original, BuildFingerprint(ownerListing())); score < 0.99 {
+		t.Errorf("DuplicateScore of an identical listing = %.2f, want 1", score)
+	}
+}
+
+func TestDuplicateScoreSparesNeighbours(t *testing.T) {
+	original := BuildFingerprint(ownerListing())
+
+	// another flat in the same society: similar wording, different size, rent and photos
+	neighbour := ownerListing()
+	neighbour.Rent = 42000
+	neighbour.Bedrooms = 3
+	neighbour.PhotoSet = []models.Photo{{Hash: "ffffffff00000000"}, {Hash: "00000000ffffffff"}}
+	if score := DuplicateScore(original, BuildFingerprint(neighbour)); score >= duplicateThreshold {
+		t.Errorf("DuplicateScore of a neighbouring flat = %.2f, want below %v", score, duplicateThreshold)
+	}
+
+	// a stock photo shared by two listings that say nothing about where they are
+	a := BuildFingerprint(&models.Property{PhotoSet: []models.Photo{{Hash: "f0e1d2c3b4a59687"}}})
+	b := BuildFingerprint(&models.Property{PhotoSet: []models.Photo{{Hash: "f0e1d2c3b4a59687"}}})
+	if score := DuplicateScore(a, b); score >= duplicateThreshold {
+		t.Errorf("DuplicateScore of placeless listings sharing a photo = %.2f", score)
+	}
+
+	// listings missing the bedrooms do not match on them
+	if score := DuplicateScore(&models.Fingerprint{Rent: 20000}, &models.Fingerprint{Rent: 20000}); score != 0.15 {
+		t.Errorf("DuplicateScore without bedrooms = %v, want only the rent's 0.15", score)
+	}
+}
+
+func TestPhotoOverlap(t *testing.T) {
+	mine := []string{"0123456789abcdef", "ffffffffffffffff"}
+	theirs := []string{"0123456789abcd0f", "1111111111111111", "2222222222222222"}
+	// one of the smaller set's two photos reappears, re-encoded
+	if got := photoOverlap(mine, theirs); got != 0.5 {
+		t.Errorf("photoOverlap = %v, want 0.5", got)
+	}
+	if got := photoOverlap(theirs, mine); got != 0.5 {
+		t.Errorf("photoOverlap reversed = %v, want 0.5", got)
+	}
+	if got := photoOverlap(nil, theirs); got != 0 {
+		t.Errorf("photoOverlap without photos = %v, want 0", got)
+	}
+}
@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"backend/models"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultExportFields are exported when the caller does not pick any
+var DefaultExportFields = []string{
+	"id", "status", "listingType", "propertyType", "location", "area", "city", "societyName",
+	"bedrooms", "bathrooms", "areaSqft", "rent", "securityDeposit", "maintenanceCharges",
+	"isAvailable", "availableFrom", "views", "createdAt",
+}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ndjson": "application/x-ndjson",
+}
+
+type exportField struct {
+	bsonName string
+	index    []int
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// exportableFields maps a column name to its property field. Columns use the JSON field names,
+// so an export can be imported again without a column mapping.
+var exportableFields = func() map[string]exportField {
+	fields := make(map[string]exportField)
+	t := reflect.TypeOf(models.Property{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		bsonName := strings.Split(field.Tag.Get("bson"), ",")[0]
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || bsonName == "" || bsonName == "-" {
+			continue
+		}
+		if name == "" {
+			name = bsonName // createdAt and updatedAt have no JSON tag
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type != timeType && field.Type != reflect.TypeOf(primitive.ObjectID{}) {
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.String {
+			continue
+		}
+		if field.Type.Kind() == reflect.Ptr || field.Type.Kind() == reflect.Map {
+			continue
+		}
+		fields[name] = exportField{bsonName: bsonName, index: field.Index}
+	}
+	return fields
+}()
+
+// ExportContentType returns the MIME type of an export format, or "" if the format is unknown
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// ValidateExportFields rejects unknown column names
+func ValidateExportFields(fields []string) error {
+	for _, field := range fields {
+		if _, ok := exportableFields[field]; !ok {
+			return fmt.Errorf("%q is not an exportable property field", field)
+		}
+	}
+	return nil
+}
+
+// ExportProjection lists the stored field names the export needs to load
+func ExportProjection(fields []string) []string {
+	projection := make([]string, 0, len(fields))
+	for _, field := range fields {
+		projection = append(projection, exportableFields[field].bsonName)
+	}
+	return projection
+}
+
+// exportValues reads the chosen fields of a listing as plain values
+func exportValues(property *models.Property, fields []string) []interface{} {
+	source := reflect.ValueOf(property).Elem()
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		switch v := source.FieldByIndex(exportableFields[field].index).Interface().(type) {
+		case primitive.ObjectID:
+			values[i] = v.Hex()
+		case time.Time:
+			if v.IsZero() {
+				values[i] = ""
+			} else {
+				values[i] = v.UTC().Format(time.RFC3339)
+			}
+		default:
+			values[i] = v
+		}
+	}
+	return values
+}
+
+// flatExportValues joins lists with ";" for the spreadsheet formats, as the importer expects them
+func flatExportValues(property *models.Property, fields []string) []interface{} {
+	values := exportValues(property, fields)
+	for i, value := range values {
+		if list, ok := value.([]string); ok {
+			values[i] = strings.Join(list, ";")
+		}
+	}
+	return values
+}
+
+// WriteExport streams every listing from the cursor to w in the given format and returns how
+// many were written. CSV and NDJSON are written row by row; XLSX goes through excelize's stream
+// writer, which spills large sheets to a temp file instead of holding them in memory.
+func WriteExport(ctx context.Context, w io.Writer, format string, cursor *mongo.Cursor, fields []string) (int, error) {
+	switch format {
+	case "csv":
+		return writeCSVExport(ctx, w, cursor, fields)
+	case "ndjson":
+		return writeNDJSONExport(ctx, w, cursor, fields)
+	case "xlsx":
+		return writeXLSXExport(ctx, w, cursor, fields)
+	}
+	return 0, fmt.Errorf("unsupported export format %q, expected csv, xlsx or ndjson", format)
+}
+
+// csvFormulaPrefixes make spreadsheet apps read a CSV cell as a formula
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// escapeCSVFormula quotes a text cell that would otherwise run as a formula when the export is
+// opened in a spreadsheet, e.g. a description starting with "=HYPERLINK(".
+func escapeCSVFormula(cell string) string {
+	if cell != "" && strings.ContainsRune(csvFormulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func writeCSVExport(ctx context.Context, w io.Writer, cursor *mongo.Cursor, fields []string) (int, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(fields); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	record := make([]string, len(fields))
+	for cursor.Next(ctx) {
+		var property models.Property
+		if err := cursor.Decode(&property); err != nil {
+			return count, err
+		}
+		for i, value := range flatExportValues(&property, fields) {
+			if text, ok := value.(string); ok {
+				record[i] = escapeCSVFormula(text) // numbers are written as is, negative ones included
+			} else {
+				record[i] = fmt.Sprint(value)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return count, err
+	}
+	return count, cursor.Err()
+}
+
+func writeNDJSONExport(ctx context.Context, w io.Writer, cursor *mongo.Cursor, fields []string) (int, error) {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+
+	count := 0
+	for cursor.Next(ctx) {
+		var property models.Property
+		if err := cursor.Decode(&property); err != nil {
+			return count, err
+		}
+		object := make(map[string]interface{}, len(fields))
+		for i, value := range exportValues(&property, fields) {
+			object[fields[i]] = value
+		}
+		if err := encoder.Encode(object); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := buffered.Flush(); err != nil {
+		return count, err
+	}
+	return count, cursor.Err()
+}
+
+func writeXLSXExport(ctx context.Context, w io.Writer, cursor *mongo.Cursor, fields []string) (int, error) {
+	book := excelize.NewFile()
+	defer book.Close()
+
+	sheet := book.GetSheetName(0)
+	stream, err := book.NewStreamWriter(sheet)
+	if err != nil {
+		return 0, err
+	}
+
+	header := make([]interface{}, len(fields))
+	for i, field := range fields {
+		header[i] = field
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for cursor.Next(ctx) {
+		var property models.Property
+		if err := cursor.Decode(&property); err != nil {
+			return count, err
+		}
+		cell, err := excelize.CoordinatesToCellName(1, count+2)
+		if err != nil {
+			return count, err
+		}
+		if err := stream.SetRow(cell, flatExportValues(&property, fields)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	if err := stream.Flush(); err != nil {
+		return count, err
+	}
+	_, err = book.WriteTo(w)
+	return count, err
+}
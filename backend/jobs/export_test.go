@@ -0,0 +1,22 @@
+package jobs
+
+import "testing"
+
+func TestEscapeCSVFormula(t *testing.T) {
+	tests := map[string]string{
+		"":                                 "",
+		"2BHK near the metro":              "2BHK near the metro",
+		"=HYPERLINK(\"http://x\",\"Pay\")": "'=HYPERLINK(\"http://x\",\"Pay\")",
+		"+91 98450 12345":                  "'+91 98450 12345",
+		"-1+1":                             "'-1+1",
+		"@SUM(A1:A2)":                      "'@SUM(A1:A2)",
+		"\t=1+1":                           "'\t=1+1",
+		"\r=1+1":                           "'\r=1+1",
+		"rent = 25000":                     "rent = 25000",
+	}
+	for cell, want := range tests {
+		if got := escapeCSVFormula(cell); got != want {
+			t.Errorf("escapeCSVFormula(%q) = %q, want %q", cell, got, want)
+		}
+	}
+}
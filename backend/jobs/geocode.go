@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	minGeocodeConfidence = 0.5
+	geocodeTimeout       = 10 * time.Second
+)
+
+// LocateProperty gives a listing without coordinates the position of its Location, Area and City,
+// and fills in a missing city or state on the way. It reports whether a position was found.
+func LocateProperty(property *models.Property) bool {
+	if property.HasCoordinates() || services.DefaultGeocoder == nil {
+		return false
+	}
+
+	var parts []string
+	for _, part := range []string{property.Location, property.Area, property.City, property.State} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), geocodeTimeout)
+	defer cancel()
+	result, err := services.DefaultGeocoder.Geocode(ctx, strings.Join(parts, ", "))
+	if err != nil {
+		if !errors.Is(err, services.ErrNoGeocodeMatch) {
+			utils.Logger.Printf("Geocoding %q failed: %v", strings.Join(parts, ", "), err)
+		}
+		return false
+	}
+	if result.Confidence < minGeocodeConfidence {
+		return false
+	}
+
+	property.Latitude = result.Latitude
+	property.Longitude = result.Longitude
+	if property.City == "" {
+		property.City = result.City
+	}
+	if property.State == "" {
+		property.State = result.State
+	}
+	return true
+}
+
+// GeocodeMissingCoordinates locates every stored listing that has no position yet and returns how
+// many were found
+func GeocodeMissingCoordinates() (int, error) {
+	ctx := context.Background()
+	cursor, err := models.StreamPropertiesWithoutCoordinates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	located := 0
+	for cursor.Next(ctx) {
+		var property models.Property
+		if err := cursor.Decode(&property); err != nil {
+			return located, err
+		}
+		if !LocateProperty(&property) {
+			continue
+		}
+		if err := models.SetPropertyCoordinates(property.ID, property.Latitude, property.Longitude); err != nil {
+			return located, err
+		}
+		located++
+	}
+	return located, cursor.Err()
+}
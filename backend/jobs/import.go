@@ -0,0 +1,391 @@
+package jobs
+
+import (
+	"backend/models"
+	"backend/utils"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	MaxImportFileBytes = 20 << 20
+	MaxImportRows      = 5000
+	importStaleAfter   = 5 * time.Minute
+)
+
+// fields an import may never set, they belong to the system or to the photo pipeline
+var nonImportableFields = map[string]bool{
+	"id": true, "owner_id": true, "photos": true, "photoSet": true, "views": true, "status": true,
+	"importJobId": true, "duplicateClusterId": true, "duplicateOf": true, "duplicateScore": true, "isRepost": true,
+	"distanceKm": true, "commuteKm": true, "cityId": true, "localityId": true,
+	"priceHistory": true, "similarityScore": true, "cleanupStatus": true, "qualityScore": true,
+	"pricePerSqft": true,
+}
+
+// importableFields maps a property's JSON field name to its struct field
+var importableFields = func() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	t := reflect.TypeOf(models.Property{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || nonImportableFields[name] {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Int, reflect.Float64, reflect.Bool:
+		case reflect.Slice:
+			if field.Type.Elem().Kind() != reflect.String {
+				continue
+			}
+		default:
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}()
+
+// ImportFormatFromFileName picks the parser from a file extension
+func ImportFormatFromFileName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return "csv"
+	case ".xlsx":
+		return "xlsx"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	return ""
+}
+
+// ValidateImportMapping rejects mappings that target unknown or protected property fields
+func ValidateImportMapping(mapping map[string]string) error {
+	for column, field := range mapping {
+		if _, ok := importableFields[field]; !ok {
+			return fmt.Errorf("column %q maps to %q, which is not an importable property field", column, field)
+		}
+	}
+	return nil
+}
+
+// ReadImportRows parses a CSV, XLSX or NDJSON file into one column->value map per data row
+func ReadImportRows(format string, data []byte) ([]map[string]string, error) {
+	switch format {
+	case "csv":
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		return recordsToRows(records), nil
+	case "xlsx":
+		book, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX: %w", err)
+		}
+		defer book.Close()
+		sheets := book.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("XLSX has no sheets")
+		}
+		records, err := book.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX: %w", err)
+		}
+		return recordsToRows(records), nil
+	case "ndjson":
+		return readNDJSONRows(data)
+	}
+	return nil, fmt.Errorf("unsupported import format %q, expected csv, xlsx or ndjson", format)
+}
+
+// recordsToRows uses the first record as the header
+func recordsToRows(records [][]string) []map[string]string {
+	if len(records) < 2 {
+		return nil
+	}
+	header := records[0]
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func readNDJSONRows(data []byte) ([]map[string]string, error) {
+	var rows []map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &object); err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d: %w", line, err)
+		}
+		row := make(map[string]string)
+		for key, value := range object {
+			row[key] = stringifyJSONValue(value)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+func stringifyJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, stringifyJSONValue(item))
+		}
+		return strings.Join(parts, ";")
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// RowToProperty applies the column mapping and converts every value to its field's type.
+// Without a mapping, columns are expected to be named like the property JSON fields.
+func RowToProperty(row map[string]string, mapping map[string]string) (*models.Property, []string) {
+	var property models.Property
+	var rowErrors []string
+	target := reflect.ValueOf(&property).Elem()
+
+	for column, raw := range row {
+		fieldName := column
+		if len(mapping) > 0 {
+			mapped, ok := mapping[column]
+			if !ok {
+				continue
+			}
+			fieldName = mapped
+		}
+		field, ok := importableFields[fieldName]
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setImportedValue(target.FieldByIndex(field.Index), raw); err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("%s: %v", column, err))
+		}
+	}
+	return &property, rowErrors
+}
+
+func setImportedValue(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := parseImportNumber(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := parseImportNumber(raw)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Bool:
+		switch strings.ToLower(raw) {
+		case "true", "yes", "y", "1":
+			field.SetBool(true)
+		case "false", "no", "n", "0":
+			field.SetBool(false)
+		default:
+			return fmt.Errorf("%q is not yes/no", raw)
+		}
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == '|' || r == ',' }) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// parseImportNumber accepts spreadsheet style amounts like "45,000", "₹ 45000" or "45k"
+func parseImportNumber(raw string) (float64, error) {
+	cleaned := strings.ToLower(strings.TrimSpace(raw))
+	cleaned = strings.NewReplacer(",", "", "₹", "", "rs.", "", "rs", "", "inr", "", " ", "").Replace(cleaned)
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(cleaned, "k"):
+		multiplier, cleaned = 1000, strings.TrimSuffix(cleaned, "k")
+	case strings.HasSuffix(cleaned, "l"):
+		multiplier, cleaned = 100000, strings.TrimSuffix(cleaned, "l")
+	}
+	n, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", raw)
+	}
+	return n * multiplier, nil
+}
+
+// RunImportJob processes an import from its last recorded row. It is safe to call again on a
+// failed or interrupted job; rows that already produced a listing are never inserted twice.
+func RunImportJob(jobID string) error {
+	job, err := models.GetImportJobByID(jobID)
+	if err != nil {
+		return err
+	}
+	claimed, err := models.ClaimImportJob(job.ID, importStaleAfter)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("import job %s is already %s", jobID, job.Status)
+	}
+
+	if err := processImportRows(job); err != nil {
+		utils.Logger.Printf("Import job %s failed at row %d: %v", jobID, job.ProcessedRows, err)
+		models.SetImportJobStatus(job.ID, models.ImportFailed, err.Error())
+		return err
+	}
+	return models.SetImportJobStatus(job.ID, models.ImportCompleted, "")
+}
+
+func processImportRows(job *models.ImportJob) error {
+	data, err := utils.GetObjectBytes(job.SourceKey, MaxImportFileBytes)
+	if err != nil {
+		return fmt.Errorf("failed to read import file: %w", err)
+	}
+	rows, err := ReadImportRows(job.Format, data)
+	if err != nil {
+		return err
+	}
+	if len(rows) > MaxImportRows {
+		return fmt.Errorf("import has %d rows, max %d allowed", len(rows), MaxImportRows)
+	}
+	if err := models.SetImportJobTotal(job.ID, len(rows)); err != nil {
+		return err
+	}
+
+	jobID := job.ID.Hex()
+	for i := job.ProcessedRows; i < len(rows); i++ {
+		result := importRow(jobID, job.OwnerID, i+1, rows[i], job.Mapping)
+		if err := models.RecordImportRow(job.ID, result); err != nil {
+			return err
+		}
+		job.ProcessedRows = i + 1
+	}
+	return nil
+}
+
+// importRow validates one row with the AddProperty rules and inserts it as a draft
+func importRow(jobID string, ownerID string, rowNumber int, row map[string]string, mapping map[string]string) models.ImportRowResult {
+	result := models.ImportRowResult{Row: rowNumber}
+
+	existing, err := models.FindImportedProperty(jobID, rowNumber)
+	if err == nil {
+		result.Accepted = true
+		result.PropertyID = existing.ID.Hex()
+		return result
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		result.Errors = []string{"failed to check for an earlier attempt: " + err.Error()}
+		return result
+	}
+
+	property, rowErrors := RowToProperty(row, mapping)
+	rowErrors = append(rowErrors, property.Validate()...)
+	if len(rowErrors) > 0 {
+		result.Errors = rowErrors
+		return result
+	}
+
+	property.OwnerID = ownerID
+	property.Status = models.StatusDraft
+	property.ImportJobID = jobID
+	property.ImportRow = rowNumber
+	LocateProperty(property)
+	if err := FlagDuplicates(property); err != nil {
+		utils.Logger.Printf("Duplicate detection failed for import %s row %d: %v", jobID, rowNumber, err)
+	}
+	if err := AssessRisk(property); err != nil {
+		utils.Logger.Printf("Fraud risk assessment failed for import %s row %d: %v", jobID, rowNumber, err)
+	}
+	if err := models.AddProperty(property); err != nil {
+		result.Errors = []string{"failed to save listing: " + err.Error()}
+		return result
+	}
+
+	result.Accepted = true
+	result.PropertyID = property.ID.Hex()
+	return result
+}
+
+// CreateImport stores the source file and creates a pending job for it
+func CreateImport(ownerID string, format string, data []byte, mapping map[string]string) (*models.ImportJob, error) {
+	if len(data) > MaxImportFileBytes {
+		return nil, fmt.Errorf("import file is larger than %d MB", MaxImportFileBytes>>20)
+	}
+	if err := ValidateImportMapping(mapping); err != nil {
+		return nil, err
+	}
+	// parse once up front so a broken file is rejected before a job exists
+	rows, err := ReadImportRows(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("import file has no data rows")
+	}
+	if len(rows) > MaxImportRows {
+		return nil, fmt.Errorf("import has %d rows, max %d allowed", len(rows), MaxImportRows)
+	}
+
+	sourceKey := fmt.Sprintf("/imports/user_%s/%s_%s.%s", ownerID, time.Now().Format("20060102150405"), uuid.New().String(), format)
+	if err := utils.UploadPrivateBytes(data, sourceKey, "application/octet-stream"); err != nil {
+		return nil, fmt.Errorf("failed to store import file: %w", err)
+	}
+
+	job := &models.ImportJob{
+		OwnerID:   ownerID,
+		Format:    format,
+		SourceKey: sourceKey,
+		Mapping:   mapping,
+		TotalRows: len(rows),
+		Rows:      []models.ImportRowResult{},
+	}
+	if err := models.CreateImportJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"context"
+	"fmt"
+	"time"
+)
+
+// PriceDropEvent is the socket message type sent to a listing's price alert subscribers when
+// its rent goes down
+const PriceDropEvent = "property.price_dropped"
+
+// NotifyPriceDrop tells every tenant subscribed to the listing that its rent dropped. Other
+// price changes only go to the price history.
+func NotifyPriceDrop(change *models.PriceChange) {
+	if !change.IsRentDrop() {
+		return
+	}
+
+	ctx := context.Background()
+	cursor, err := models.StreamPriceAlerts(ctx, change.PropertyID)
+	if err != nil {
+		utils.Logger.Printf("Failed to load price alerts of property %s: %v", change.PropertyID, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	text := fmt.Sprintf("Rent dropped from ₹%d to ₹%d", change.Previous.Rent, change.Current.Rent)
+	notified := 0
+	for cursor.Next(ctx) {
+		var alert models.PriceAlert
+		if err := cursor.Decode(&alert); err != nil {
+			utils.Logger.Printf("Failed to decode price alert: %v", err)
+			continue
+		}
+		services.Broadcast <- services.Message{
+			Sender:     "system",
+			Receiver:   alert.UserID,
+			Message:    text,
+			Timestamp:  time.Now().Format(time.RFC3339),
+			Type:       PriceDropEvent,
+			PropertyID: change.PropertyID,
+		}
+		notified++
+	}
+	if err := cursor.Err(); err != nil {
+		utils.Logger.Printf("Failed to read price alerts of property %s: %v", change.PropertyID, err)
+	}
+
+	if notified > 0 {
+		if err := models.MarkPriceAlertsNotified(change.PropertyID); err != nil {
+			utils.Logger.Printf("Failed to mark price alerts of property %s notified: %v", change.PropertyID, err)
+		}
+		utils.Logger.Printf("Notified %d subscribers of the price drop of property %s", notified, change.PropertyID)
+	}
+}
@@ -0,0 +1,216 @@
+package jobs
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// socket message types sent to owners about moderation
+const (
+	PropertyHeldEvent     = "property.held"
+	PropertyApprovedEvent = "property.approved"
+	PropertyRejectedEvent = "property.rejected"
+	PropertyHiddenEvent   = "property.hidden"
+	PropertyRestoredEvent = "property.restored"
+	OwnerSuspendedEvent   = "owner.suspended"
+	ModeratorMessageEvent = "moderator.message"
+)
+
+const (
+	lowRentRatio       = 0.5  // rent below this share of the median is a strong signal
+	belowRentRatio     = 0.65 // and below this a weak one
+	sharedContactLimit = 3    // other accounts on the same number before it is a strong signal
+	ownerListingLimit  = 8    // listings an owner account has before it looks like a broker
+)
+
+// riskKeywordRule flags listing text that matches pattern
+type riskKeywordRule struct {
+	rule    string
+	points  int
+	detail  string
+	pattern *regexp.Regexp
+}
+
+var riskKeywordRules = []riskKeywordRule{
+	{"advance_before_visit", 40, "Asks for money before a visit",
+		regexp.MustCompile(`(?i)\b(advance|token|deposit|booking amount|pay)\b.{0,40}\b(before|without)\b.{0,20}\b(visit|visiting|seeing|viewing)\b`)},
+	{"upi_payment", 20, "Asks for payment over UPI or a wallet",
+		regexp.MustCompile(`(?i)\b(upi|gpay|google pay|phonepe|paytm|bhim)\b`)},
+	{"forces_story", 30, "Owner claims to be a transferred armed forces officer",
+		regexp.MustCompile(`(?i)\b(army|military|defen[cs]e|navy|air force|crpf|bsf|cisf)\b.{0,30}\b(officer|personnel|posted|transferred|posting)\b`)},
+	{"keys_by_courier", 25, "Offers to send the keys by courier",
+		regexp.MustCompile(`(?i)\b(courier|send)\b.{0,20}\bkeys?\b`)},
+}
+
+// brokeragePattern finds brokerage mentions; a "no"/"zero" in front makes it a claim of none
+var brokeragePattern = regexp.MustCompile(`(?i)(\b(?:no|zero|nil|without)\s+)?\b(brokerage|commission|broker charges?)\b`)
+
+// AssessRisk scores how likely a listing is a scam from rules: rent far below the local median,
+// photos reused from other accounts, a phone number shared across accounts, payment and story
+// keywords, and brokers posing as owners. It sets Risk and ContactNumbers on the listing, and
+// normalizes the locality of a new one so the rent has a median to compare with.
+func AssessRisk(property *models.Property) error {
+	if property.CityID == "" && property.LocalityID == "" {
+		property.NormalizeLocality()
+	}
+
+	var reasons []models.RiskReason
+	add := func(rule string, points int, detail string) {
+		reasons = append(reasons, models.RiskReason{Rule: rule, Points: points, Detail: detail})
+	}
+
+	median, scope, err := models.MedianRent(property)
+	if err != nil {
+		return err
+	}
+	if reason := rentOutlierReason(property.Rent, median, scope); reason != nil {
+		reasons = append(reasons, *reason)
+	}
+
+	var hashes []string
+	for _, photo := range property.PhotoSet {
+		if photo.Hash != "" {
+			hashes = append(hashes, photo.Hash)
+		}
+	}
+	reused, err := models.CountPhotoReuse(hashes, property.OwnerID)
+	if err != nil {
+		return err
+	}
+	if reused > 0 {
+		add("photo_reuse", 40, fmt.Sprintf("Photos also appear on %d listings by other accounts", reused))
+	}
+
+	// the cleanup moves contact details out of the description into Link
+	property.ContactNumbers = utils.FindPhoneNumbers(property.Description + "\n" + property.Link)
+	accounts, err := models.CountContactAccounts(property.ContactNumbers, property.OwnerID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case accounts >= sharedContactLimit:
+		add("contact_reuse", 35, fmt.Sprintf("Phone number is also given by %d other accounts", accounts))
+	case accounts > 0:
+		add("contact_reuse", 15, fmt.Sprintf("Phone number is also given by %d other accounts", accounts))
+	}
+
+	reasons = append(reasons, textRiskReasons(property.Description, property.IsBrokerListing)...)
+
+	if !property.IsBrokerListing {
+		listings, err := models.CountOwnerListings(property.OwnerID, property.ID)
+		if err != nil {
+			return err
+		}
+		if listings >= ownerListingLimit {
+			add("broker_as_owner", 20, fmt.Sprintf("Owner account has %d other listings", listings))
+		}
+	}
+
+	property.Risk = models.NewRiskAssessment(reasons)
+	if property.Risk.Level != models.RiskLow {
+		utils.Logger.Printf("Listing by %s scored %s fraud risk %d: %v", property.OwnerID, property.Risk.Level, property.Risk.Score, property.Risk.Reasons)
+	}
+	return nil
+}
+
+// rentOutlierReason flags a rent far below the median of scope, or returns nil
+func rentOutlierReason(rent int, median int, scope string) *models.RiskReason {
+	if median <= 0 || rent <= 0 {
+		return nil
+	}
+	ratio := float64(rent) / float64(median)
+	reason := &models.RiskReason{
+		Rule:   "rent_outlier",
+		Detail: fmt.Sprintf("Rent %d is %.0f%% below the %s median of %d", rent, (1-ratio)*100, scope, median),
+	}
+	switch {
+	case ratio < lowRentRatio:
+		reason.Points = 35
+	case ratio < belowRentRatio:
+		reason.Points = 15
+	default:
+		return nil
+	}
+	return reason
+}
+
+// textRiskReasons applies the keyword rules, and for owner listings the brokerage check, to a
+// description
+func textRiskReasons(description string, isBrokerListing bool) []models.RiskReason {
+	var reasons []models.RiskReason
+	for _, rule := range riskKeywordRules {
+		if rule.pattern.MatchString(description) {
+			reasons = append(reasons, models.RiskReason{Rule: rule.rule, Points: rule.points, Detail: rule.detail})
+		}
+	}
+	if !isBrokerListing {
+		for _, match := range brokeragePattern.FindAllStringSubmatch(description, -1) {
+			if match[1] == "" {
+				reasons = append(reasons, models.RiskReason{Rule: "broker_as_owner", Points: 25, Detail: "Mentions brokerage but is listed by an owner"})
+				break
+			}
+		}
+	}
+	return reasons
+}
+
+// ReassessRisk scores a stored listing again after it changed and holds it for moderation if it
+// turned high-risk. It reports whether the listing was held.
+func ReassessRisk(propertyID string) bool {
+	property, err := models.FindPropertyByID(propertyID)
+	if err != nil {
+		utils.Logger.Printf("Failed to reload property %s for risk assessment: %v", propertyID, err)
+		return false
+	}
+	if err := AssessRisk(property); err != nil {
+		utils.Logger.Printf("Fraud risk assessment failed for property %s: %v", propertyID, err)
+		return false
+	}
+	held, err := models.SetPropertyRisk(propertyID, property.Risk, property.ContactNumbers)
+	if err != nil {
+		utils.Logger.Printf("Failed to store fraud risk of property %s: %v", propertyID, err)
+		return false
+	}
+	return held
+}
+
+// NotifyModeration tells the owner their listing was held, approved, rejected, hidden or restored,
+// or that their account was suspended
+func NotifyModeration(propertyID string, ownerID string, event string) {
+	var text string
+	switch event {
+	case PropertyHeldEvent:
+		text = "Your listing is being reviewed before it goes live"
+	case PropertyApprovedEvent:
+		text = "Your listing was approved and is live"
+	case PropertyRejectedEvent:
+		text = "Your listing was rejected by a moderator"
+	case PropertyHiddenEvent:
+		text = "Your listing was taken off the site after reports from users"
+	case PropertyRestoredEvent:
+		text = "Your listing was reviewed and is live again"
+	case OwnerSuspendedEvent:
+		text = "Your account was suspended from listing after reports from users"
+	}
+	sendOwnerMessage(propertyID, ownerID, event, text)
+}
+
+// MessageOwner sends an owner a moderator's message about one of their listings
+func MessageOwner(propertyID string, ownerID string, text string) {
+	sendOwnerMessage(propertyID, ownerID, ModeratorMessageEvent, text)
+}
+
+func sendOwnerMessage(propertyID string, ownerID string, event string, text string) {
+	services.Broadcast <- services.Message{
+		Sender:     "system",
+		Receiver:   ownerID,
+		Message:    text,
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Type:       event,
+		PropertyID: propertyID,
+	}
+}
@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"backend/models"
+	"testing"
+)
+
+func TestRentOutlierReason(t *testing.T) {
+	const median = 30000
+	tests := []struct {
+		rent   int
+		points int // 0 when the rent is not flagged
+	}{
+		{10000, 35},
+		{14999, 35},
+		{15000, 15}, // exactly half the median is only a weak signal
+		{19400, 15},
+		{19500, 0}, // at 65% it is a bargain, not an outlier
+		{30000, 0},
+		{45000, 0},
+	}
+	for _, tt := range tests {
+		reason := rentOutlierReason(tt.rent, median, "locality")
+		points := 0
+		if reason != nil {
+			points = reason.Points
+		}
+		if points != tt.points {
+			t.Errorf("rentOutlierReason(%d, %d) = %d points, want %d", tt.rent, median, points, tt.points)
+		}
+	}
+
+	if reason := rentOutlierReason(10000, 0, ""); reason != nil {
+		t.Errorf("rentOutlierReason without a median = %+v, want nil", reason)
+	}
+	if reason := rentOutlierReason(0, median, "city"); reason != nil {
+		t.Errorf("rentOutlierReason without a rent = %+v, want nil", reason)
+	}
+	if reason := rentOutlierReason(12000, median, "city"); reason.Detail != "Rent 12000 is 60% below the city median of 30000" {
+		t.Errorf("rentOutlierReason detail = %q", reason.Detail)
+	}
+}
+
+// every keyword rule with a line a scammer actually wrote
+var riskKeywordExamples = map[string]string{
+	"advance_before_visit": "Pay token amount before visiting, flat is in high demand",
+	"upi_payment":          "Send the deposit on GPay to block the flat",
+	"forces_story":         "I am an army officer transferred to Pune so cannot show the flat",
+	"keys_by_courier":      "I will courier the keys once the deposit is received",
+}
+
+func TestRiskKeywordRules(t *testing.T) {
+	for _, rule := range riskKeywordRules {
+		example, ok := riskKeywordExamples[rule.rule]
+		if !ok {
+			t.Errorf("keyword rule %s has no example", rule.rule)
+			continue
+		}
+		reasons := textRiskReasons(example, true)
+		if !hasRule(reasons, rule.rule) {
+			t.Errorf("%s did not match %q", rule.rule, example)
+		}
+	}
+
+	// ordinary listings mention visits, deposits and payment without tripping anything
+	for _, description := range []string{
+		"Spacious 2BHK near the metro, visit any evening after 6",
+		"Deposit of two months, rent to be paid by the 5th",
+		"Walking distance to the army cantonment and the air force school",
+	} {
+		if reasons := textRiskReasons(description, false); len(reasons) > 0 {
+			t.Errorf("textRiskReasons(%q) = %v, want none", description, reasons)
+		}
+	}
+}
+
+func TestBrokerageMentions(t *testing.T) {
+	if reasons := textRiskReasons("Brokerage of one month applies", false); !hasRule(reasons, "broker_as_owner") {
+		t.Errorf("owner listing charging brokerage not flagged: %v", reasons)
+	}
+	if reasons := textRiskReasons("Brokerage of one month applies", true); len(reasons) > 0 {
+		t.Errorf("broker listing charging brokerage flagged: %v", reasons)
+	}
+	for _, description := range []string{"Direct owner, no brokerage", "Zero commission, owner listing"} {
+		if reasons := textRiskReasons(description, false); len(reasons) > 0 {
+			t.Errorf("textRiskReasons(%q) = %v, want none", description, reasons)
+		}
+	}
+	// a claim of no brokerage does not cover a charge further on
+	reasons := textRiskReasons("No brokerage for the flat, broker charges for parking only", false)
+	if !hasRule(reasons, "broker_as_owner") {
+		t.Errorf("later brokerage charge not flagged: %v", reasons)
+	}
+}
+
+func TestTypicalScamIsHeld(t *testing.T) {
+	reasons := textRiskReasons("Army officer posted to Delhi, pay advance before visit on PhonePe and I will courier the keys", false)
+	reasons = append(reasons, *rentOutlierReason(12000, 30000, "locality"))
+	if risk := models.NewRiskAssessment(reasons); risk.Level != models.RiskHigh {
+		t.Errorf("NewRiskAssessment(%v) = %s (%d), want high", reasons, risk.Level, risk.Score)
+	}
+}
+
+func hasRule(reasons []models.RiskReason, rule string) bool {
+	for _, reason := range reasons {
+		if reason.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
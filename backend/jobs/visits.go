@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"context"
+	"time"
+)
+
+// socket message types sent about visits
+const (
+	VisitBookedEvent      = "visit.booked"
+	VisitRescheduledEvent = "visit.rescheduled"
+	VisitCancelledEvent   = "visit.cancelled"
+	VisitReminderEvent    = "visit.reminder"
+
+	visitReminderInterval = time.Minute
+)
+
+// visitTime formats a visit's start for messages, e.g. "Sat 2 Nov, 11:30"
+func visitTime(visit *models.Visit) string {
+	return visit.StartsAt.In(models.VisitTimeZone).Format("Mon 2 Jan, 15:04")
+}
+
+func sendVisitMessage(visit *models.Visit, receiver string, event string, text string) {
+	services.Broadcast <- services.Message{
+		Sender:     "system",
+		Receiver:   receiver,
+		Message:    text,
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Type:       event,
+		PropertyID: visit.PropertyID,
+	}
+}
+
+// NotifyVisitChange tells the other side of a visit that userID booked, moved or cancelled it
+func NotifyVisitChange(visit *models.Visit, event string, userID string) {
+	receiver := visit.OwnerID
+	if userID == visit.OwnerID {
+		receiver = visit.TenantID
+	}
+
+	var text string
+	switch event {
+	case VisitBookedEvent:
+		text = "New visit booked for " + visitTime(visit)
+	case VisitRescheduledEvent:
+		text = "Visit moved to " + visitTime(visit)
+	case VisitCancelledEvent:
+		text = "Visit on " + visitTime(visit) + " was cancelled"
+	}
+	sendVisitMessage(visit, receiver, event, text)
+}
+
+// StartVisitReminders reminds tenants and owners of their visits a day and an hour before
+func StartVisitReminders() {
+	go func() {
+		ticker := time.NewTicker(visitReminderInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			sendVisitReminders()
+		}
+	}()
+}
+
+func sendVisitReminders() {
+	ctx := context.Background()
+	for _, reminder := range models.VisitReminders {
+		cursor, err := models.StreamVisitsDueForReminder(ctx, reminder)
+		if err != nil {
+			utils.Logger.Printf("Failed to load visits due for the %s reminder: %v", reminder.Name, err)
+			continue
+		}
+		for cursor.Next(ctx) {
+			var visit models.Visit
+			if err := cursor.Decode(&visit); err != nil {
+				utils.Logger.Printf("Failed to decode visit: %v", err)
+				continue
+			}
+			claimed, err := models.ClaimVisitReminder(visit.ID, reminder)
+			if err != nil {
+				utils.Logger.Printf("Failed to claim the %s reminder of visit %s: %v", reminder.Name, visit.ID.Hex(), err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
+			text := "Reminder: property visit on " + visitTime(&visit)
+			sendVisitMessage(&visit, visit.TenantID, VisitReminderEvent, text)
+			sendVisitMessage(&visit, visit.OwnerID, VisitReminderEvent, text)
+		}
+		cursor.Close(ctx)
+	}
+}
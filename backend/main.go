@@ -2,6 +2,8 @@ package main
 
 import (
 	"backend/config"
+	"backend/jobs"
+	"backend/models"
 	"backend/routes"
 	"backend/services"
 	"backend/utils"
@@ -21,9 +23,42 @@ func main() {
 
 	config.ConnectDB()
 
+	if err := services.InitGeocoder(models.WorkplaceLandmarks()); err != nil {
+		utils.Logger.Fatalf("Geocoder initialization failed: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := utils.InitStorage(); err != nil {
+			utils.Logger.Fatalf("Storage initialization failed: %v", err)
+		}
+		os.Exit(runImportCommand(os.Args[2:]))
+	}
+
 	services.InitFirebase()
 
-	utils.InitS3()
+	if err := utils.InitStorage(); err != nil {
+		utils.Logger.Fatalf("Storage initialization failed: %v", err)
+	}
+
+	jobs.StartCleanupWorker()
+	jobs.StartVisitReminders()
+	go func() {
+		if updated, err := models.RefreshOfficeDistances(); err != nil {
+			utils.Logger.Printf("Failed to refresh office distances: %v", err)
+		} else if updated > 0 {
+			utils.Logger.Printf("Refreshed office distances of %d properties", updated)
+		}
+		if scored, err := models.RefreshQualityScores(); err != nil {
+			utils.Logger.Printf("Failed to refresh quality scores: %v", err)
+		} else if scored > 0 {
+			utils.Logger.Printf("Refreshed quality scores of %d properties", scored)
+		}
+		if located, err := jobs.GeocodeMissingCoordinates(); err != nil {
+			utils.Logger.Printf("Failed to geocode properties without coordinates: %v", err)
+		} else if located > 0 {
+			utils.Logger.Printf("Geocoded %d properties without coordinates", located)
+		}
+	}()
 
 	router := mux.NewRouter()
 
@@ -38,3 +38,27 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// OptionalAuthMiddleware adds userID and role to the context when the request carries a valid
+// token, and lets anonymous requests through unchanged
+func OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := utils.ValidateJWT(token)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, _ := claims["userID"].(string)
+		role, _ := claims["role"].(string)
+
+		ctx := context.WithValue(r.Context(), "userID", userID)
+		ctx = context.WithValue(ctx, "userRole", role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"backend/utils"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ipRateLimiter counts requests per client IP in fixed windows. Counts are dropped when a
+// window ends, so memory only grows with the clients seen within one window.
+type ipRateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	counts  map[string]int
+	resetAt time.Time
+}
+
+func (l *ipRateLimiter) allow(ip string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !now.Before(l.resetAt) {
+		l.counts = make(map[string]int)
+		l.resetAt = now.Add(l.window)
+	}
+	l.counts[ip]++
+	return l.counts[ip] <= l.limit
+}
+
+// RateLimitMiddleware answers 429 once a client IP made more than limit requests within window.
+// Routes wrapped by the same middleware share the limit.
+func RateLimitMiddleware(limit int, window time.Duration) func(http.Handler) http.Handler {
+	limiter := &ipRateLimiter{limit: limit, window: window}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+			if !limiter.allow(ip, time.Now()) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(window.Seconds())))
+				utils.WriteErrorResponse(w, "Too many requests, try again shortly", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -16,6 +16,7 @@ type Chat struct {
 	SenderID   string             `json:"senderId" bson:"senderId"`
 	ReceiverID string             `json:"receiverId" bson:"receiverId"`
 	Message    string             `json:"message" bson:"message"`
+	PropertyID string             `json:"propertyId,omitempty" bson:"propertyId,omitempty"` // the listing the chat is about, if any
 	Timestamp  time.Time          `json:"timestamp" bson:"timestamp"`
 }
 
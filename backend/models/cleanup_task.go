@@ -0,0 +1,118 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	CleanupQueued    = "queued"
+	CleanupRunning   = "running"
+	CleanupCompleted = "completed"
+	CleanupFailed    = "failed" // every attempt failed, the listing went live with the owner's data
+)
+
+// CleanupTask is one queued AI cleanup of a new listing. Tasks live in Mongo so queued work
+// survives a restart; a worker claims the oldest due task, and a failed attempt is rescheduled
+// with backoff until MaxAttempts is reached.
+type CleanupTask struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	PropertyID    string             `bson:"propertyId" json:"propertyId"`
+	OwnerID       string             `bson:"ownerId" json:"-"`
+	Status        string             `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	MaxAttempts   int                `bson:"maxAttempts" json:"maxAttempts"`
+	LastError     string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	NextAttemptAt time.Time          `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+func GetCleanupTaskCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("cleanup_tasks")
+}
+
+// EnqueueCleanupTask queues the cleanup of a listing, due immediately
+func EnqueueCleanupTask(propertyID string, ownerID string, maxAttempts int) (*CleanupTask, error) {
+	task := &CleanupTask{
+		ID:            primitive.NewObjectID(),
+		PropertyID:    propertyID,
+		OwnerID:       ownerID,
+		Status:        CleanupQueued,
+		MaxAttempts:   maxAttempts,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	_, err := GetCleanupTaskCollection().InsertOne(context.Background(), task)
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// ClaimNextCleanupTask marks the oldest due task as running and returns it, or nil if nothing is
+// due. A task left running for staleAfter belongs to a crashed worker and is claimed again.
+func ClaimNextCleanupTask(staleAfter time.Duration) (*CleanupTask, error) {
+	now := time.Now()
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": CleanupQueued, "nextAttemptAt": bson.M{"$lte": now}},
+			{"status": CleanupRunning, "updatedAt": bson.M{"$lt": now.Add(-staleAfter)}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"status": CleanupRunning, "updatedAt": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"nextAttemptAt": 1}).
+		SetReturnDocument(options.After)
+
+	var task CleanupTask
+	err := GetCleanupTaskCollection().FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// RetryCleanupTask puts a failed attempt back in the queue
+func RetryCleanupTask(id primitive.ObjectID, nextAttemptAt time.Time, errMessage string) error {
+	update := bson.M{"$set": bson.M{
+		"status":        CleanupQueued,
+		"lastError":     errMessage,
+		"nextAttemptAt": nextAttemptAt,
+		"updatedAt":     time.Now(),
+	}}
+	_, err := GetCleanupTaskCollection().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
+
+// FinishCleanupTask records the final outcome of a task
+func FinishCleanupTask(id primitive.ObjectID, status string, errMessage string) error {
+	update := bson.M{"$set": bson.M{"status": status, "lastError": errMessage, "updatedAt": time.Now()}}
+	_, err := GetCleanupTaskCollection().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
+
+// GetCleanupTaskByPropertyID returns the most recent cleanup task of a listing
+func GetCleanupTaskByPropertyID(propertyID string) (*CleanupTask, error) {
+	var task CleanupTask
+	opts := options.FindOne().SetSort(bson.M{"createdAt": -1})
+	err := GetCleanupTaskCollection().FindOne(context.Background(), bson.M{"propertyId": propertyID}, opts).Decode(&task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+)
+
+const MaxComparedProperties = 4
+
+// comparedFields are the listing fields lined up by CompareProperties, in display order
+var comparedFields = []string{
+	"listingType", "propertyType", "location", "area", "city", "societyName",
+	"bedrooms", "bathrooms", "balconies", "areaSqft",
+	"rent", "securityDeposit", "maintenanceCharges",
+	"leaseTerm", "availableFrom", "minimumLeaseMonths", "lockInMonths", "noticePeriodDays",
+	"isFamilyPreferred", "isVegetarianPreferred", "genderPreference", "amenities",
+}
+
+// flags are omitted from JSON when false, so a missing one means false rather than unknown
+var comparedFlags = map[string]bool{"isFamilyPreferred": true, "isVegetarianPreferred": true}
+
+// comparedDirection marks the rows where the lowest (-1) or highest (1) value is best
+var comparedDirection = map[string]int{
+	"areaSqft": 1, "rent": -1, "securityDeposit": -1, "maintenanceCharges": -1,
+	"lockInMonths": -1, "noticePeriodDays": -1, "rentPerSqft": -1, "moveInCost": -1,
+}
+
+// ComparisonRow is one field across the compared listings
+type ComparisonRow struct {
+	Field  string        `json:"field"`
+	Label  string        `json:"label,omitempty"` // only on derived rows
+	Values []interface{} `json:"values"`          // one per listing, null when the listing does not say
+	Best   []int         `json:"best,omitempty"`  // indexes of the best values, on rows where lower or higher is better
+}
+
+// PropertyComparison lines listings up field by field, followed by derived metrics: rent per
+// sq ft, move-in cost (deposit plus the first month's rent and maintenance) and the distance to
+// each office
+type PropertyComparison struct {
+	Properties []*Property      `json:"properties"`
+	Offices    []*Workplace     `json:"offices,omitempty"`
+	Rows       []*ComparisonRow `json:"rows"`
+}
+
+// CompareProperties builds the comparison of properties, in their order
+func CompareProperties(properties []*Property, offices []*Workplace) (*PropertyComparison, error) {
+	docs := make([]map[string]interface{}, len(properties))
+	for i, property := range properties {
+		raw, err := json.Marshal(property)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &docs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	comparison := &PropertyComparison{Properties: properties, Offices: offices}
+	for _, field := range comparedFields {
+		row := &ComparisonRow{Field: field, Values: make([]interface{}, len(properties))}
+		for i, doc := range docs {
+			value, ok := doc[field]
+			if !ok && comparedFlags[field] {
+				value = false
+			}
+			row.Values[i] = value
+		}
+		comparison.Rows = append(comparison.Rows, row)
+	}
+
+	rentPerSqft := &ComparisonRow{Field: "rentPerSqft", Label: "Rent per sq ft", Values: make([]interface{}, len(properties))}
+	moveInCost := &ComparisonRow{Field: "moveInCost", Label: "Move-in cost", Values: make([]interface{}, len(properties))}
+	for i, p := range properties {
+		if p.Rent > 0 && p.AreaSqft > 0 {
+			rentPerSqft.Values[i] = math.Round(float64(p.Rent)/p.AreaSqft*10) / 10
+		}
+		if p.Rent > 0 {
+			moveInCost.Values[i] = p.SecurityDeposit + p.Rent + p.MaintenanceCharges
+		}
+	}
+	comparison.Rows = append(comparison.Rows, rentPerSqft, moveInCost)
+
+	for _, office := range offices {
+		row := &ComparisonRow{Field: "distanceKm:" + office.ID, Label: "Distance to " + office.Name + " (km)", Values: make([]interface{}, len(properties))}
+		for i, p := range properties {
+			if p.HasCoordinates() {
+				row.Values[i] = office.DistanceKm(p.Latitude, p.Longitude)
+			}
+		}
+		row.Best = bestComparedValues(row.Values, -1)
+		comparison.Rows = append(comparison.Rows, row)
+	}
+
+	for _, row := range comparison.Rows {
+		if direction, ok := comparedDirection[row.Field]; ok {
+			row.Best = bestComparedValues(row.Values, direction)
+		}
+	}
+	return comparison, nil
+}
+
+// bestComparedValues returns the indexes holding the lowest (direction -1) or highest (1)
+// number, or nil when fewer than two listings have one
+func bestComparedValues(values []interface{}, direction int) []int {
+	var best []int
+	bestValue, known := 0.0, 0
+	for i, value := range values {
+		n, ok := comparedNumber(value)
+		if !ok {
+			continue
+		}
+		known++
+		switch {
+		case len(best) == 0 || n*float64(direction) > bestValue*float64(direction):
+			best, bestValue = []int{i}, n
+		case n == bestValue:
+			best = append(best, i)
+		}
+	}
+	if known < 2 {
+		return nil
+	}
+	return best
+}
+
+func comparedNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// ResolveOffices looks up workplace IDs, names or aliases, skipping unknown ones, and keeps at
+// most the number of offices a search allows
+func ResolveOffices(queries []string) []*Workplace {
+	var offices []*Workplace
+	seen := make(map[string]bool)
+	for _, query := range queries {
+		office := FindWorkplace(query)
+		if office == nil || seen[office.ID] {
+			continue
+		}
+		seen[office.ID] = true
+		offices = append(offices, office)
+		if len(offices) == maxSearchOffices {
+			break
+		}
+	}
+	return offices
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareProperties(t *testing.T) {
+	indiranagar := &Property{Rent: 35000, AreaSqft: 1000, SecurityDeposit: 100000, MaintenanceCharges: 3000,
+		IsFamilyPreferred: true, Latitude: 12.9784, Longitude: 77.6408}
+	whitefield := &Property{Rent: 28000, AreaSqft: 1400, SecurityDeposit: 150000,
+		Latitude: 12.9698, Longitude: 77.7500}
+	unpinned := &Property{Rent: 28000}
+	office := &Workplace{ID: "itpl", Name: "ITPL", Latitude: 12.9866, Longitude: 77.7366}
+
+	comparison, err := CompareProperties([]*Property{indiranagar, whitefield, unpinned}, []*Workplace{office})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make(map[string]*ComparisonRow)
+	for _, row := range comparison.Rows {
+		rows[row.Field] = row
+	}
+	if len(comparison.Rows) != len(comparedFields)+3 {
+		t.Errorf("CompareProperties gave %d rows, want every field, two metrics and the office", len(comparison.Rows))
+	}
+
+	// both cheaper listings share the best rent
+	if best := rows["rent"].Best; !reflect.DeepEqual(best, []int{1, 2}) {
+		t.Errorf("best rent = %v, want [1 2]", best)
+	}
+	if values := rows["rentPerSqft"].Values; values[0] != 35.0 || values[1] != 20.0 || values[2] != nil {
+		t.Errorf("rent per sq ft = %v", values)
+	}
+	if values := rows["moveInCost"].Values; values[0] != 138000 || values[1] != 178000 || values[2] != 28000 {
+		t.Errorf("move-in cost = %v", values)
+	}
+	// a missing flag is false, not unknown
+	if values := rows["isFamilyPreferred"].Values; values[0] != true || values[1] != false {
+		t.Errorf("isFamilyPreferred = %v", values)
+	}
+
+	commute := rows["distanceKm:itpl"]
+	if commute.Label != "Distance to ITPL (km)" || commute.Values[2] != nil {
+		t.Errorf("office row = %+v", commute)
+	}
+	if !reflect.DeepEqual(commute.Best, []int{1}) {
+		t.Errorf("closest to the office = %v, want Whitefield", commute.Best)
+	}
+}
+
+func TestBestComparedValues(t *testing.T) {
+	if best := bestComparedValues([]interface{}{2.5, 4.0, 4}, 1); !reflect.DeepEqual(best, []int{1, 2}) {
+		t.Errorf("highest of floats and ints = %v, want [1 2]", best)
+	}
+	// nothing to compare against
+	if best := bestComparedValues([]interface{}{nil, "n/a", 30000}, -1); best != nil {
+		t.Errorf("single known value = %v, want nil", best)
+	}
+}
@@ -0,0 +1,279 @@
+package models
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultFeedSize = 8
+	MaxFeedSize     = 50
+
+	feedSignalHistory  = 200 // latest signals a profile is built from
+	feedCandidatePool  = 300 // listings ranked in memory per feed
+	feedSignalHalfLife = 14 * 24 * time.Hour
+	feedFreshHalfLife  = 7 * 24 * time.Hour
+	feedTopCities      = 3
+	feedTopLocalities  = 8
+	feedSeenPenalty    = 0.7 // listings the user already opened are kept, but lower
+)
+
+// feedSignalWeights is how much each kind of signal says about what the user wants
+var feedSignalWeights = map[string]float64{
+	SignalSearch:   1,
+	SignalView:     1,
+	SignalFavorite: 3,
+	SignalChat:     4,
+}
+
+// weights of the feed ranking, adding up to 1
+const (
+	feedAffinityWeight   = 0.6
+	feedFreshnessWeight  = 0.2
+	feedPopularityWeight = 0.2
+)
+
+// tasteProfile sums a user's signals, weighted by kind and fading with age
+type tasteProfile struct {
+	cities        map[string]float64
+	localities    map[string]float64
+	propertyTypes map[string]float64
+	bedrooms      map[int]float64
+	listingTypes  map[string]float64
+	rentSum       float64
+	rentWeight    float64
+	seen          map[string]bool // viewed listings
+	engaged       map[string]bool // favorited or chatted about, left out of the feed
+}
+
+func buildTasteProfile(signals []*UserSignal, now time.Time) *tasteProfile {
+	profile := &tasteProfile{
+		cities:        make(map[string]float64),
+		localities:    make(map[string]float64),
+		propertyTypes: make(map[string]float64),
+		bedrooms:      make(map[int]float64),
+		listingTypes:  make(map[string]float64),
+		seen:          make(map[string]bool),
+		engaged:       make(map[string]bool),
+	}
+	for _, signal := range signals {
+		weight := feedSignalWeights[signal.Kind] * halfLifeDecay(now.Sub(signal.CreatedAt), feedSignalHalfLife)
+		if signal.Count > 1 {
+			weight *= 1 + math.Log(float64(signal.Count))
+		}
+		if signal.CityID != "" {
+			profile.cities[signal.CityID] += weight
+		}
+		if signal.LocalityID != "" {
+			profile.localities[signal.LocalityID] += weight
+		}
+		if signal.PropertyType != "" {
+			profile.propertyTypes[strings.ToLower(signal.PropertyType)] += weight
+		}
+		if signal.Bedrooms > 0 {
+			profile.bedrooms[signal.Bedrooms] += weight
+		}
+		if signal.ListingType != "" {
+			profile.listingTypes[signal.ListingType] += weight
+		}
+		if signal.Rent > 0 {
+			profile.rentSum += float64(signal.Rent) * weight
+			profile.rentWeight += weight
+		}
+		switch signal.Kind {
+		case SignalView:
+			profile.seen[signal.PropertyID] = true
+		case SignalFavorite, SignalChat:
+			profile.engaged[signal.PropertyID] = true
+		}
+	}
+	return profile
+}
+
+// rent is the weighted average rent the user looked at, or 0 when unknown
+func (t *tasteProfile) rent() float64 {
+	if t.rentWeight == 0 {
+		return 0
+	}
+	return t.rentSum / t.rentWeight
+}
+
+// affinity is a 0-1 match between a listing and the profile
+func (t *tasteProfile) affinity(p *Property) float64 {
+	score := 0.3*relativeWeight(t.localities, p.LocalityID) +
+		0.2*relativeWeight(t.cities, p.CityID) +
+		0.15*relativeWeight(t.propertyTypes, strings.ToLower(p.PropertyType)) +
+		0.1*relativeWeight(t.listingTypes, p.ListingType)
+	if strongest := maxOf(t.bedrooms); strongest > 0 {
+		score += 0.1 * t.bedrooms[p.Bedrooms] / strongest
+	}
+	if rent := t.rent(); rent > 0 {
+		score += 0.15 * rentCloseness(int(rent), p.Rent)
+	}
+	return score
+}
+
+// GetPersonalizedFeed ranks listings for the user by how well they fit the places, kinds of
+// home and rents they searched for, viewed, saved and asked about, blended with freshness and
+// views. Users without signals get the most viewed listings; personalized reports which one it is.
+func GetPersonalizedFeed(userID string, limit int) (properties []*Property, personalized bool, err error) {
+	signals, err := GetRecentUserSignals(userID, feedSignalHistory)
+	if err != nil {
+		return nil, false, err
+	}
+	profile := buildTasteProfile(signals, time.Now())
+	if len(profile.cities) == 0 && len(profile.localities) == 0 {
+		properties, err := GetTopProperties(limit)
+		return properties, false, err
+	}
+
+	candidates, err := findFeedCandidates(profile)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(candidates) == 0 {
+		properties, err := GetTopProperties(limit)
+		return properties, false, err
+	}
+
+	now := time.Now()
+	maxViews := 0
+	for _, p := range candidates {
+		if p.Views > maxViews {
+			maxViews = p.Views
+		}
+	}
+	scores := make(map[*Property]float64, len(candidates))
+	for _, p := range candidates {
+		popularity := 0.0
+		if maxViews > 0 {
+			popularity = math.Log1p(float64(p.Views)) / math.Log1p(float64(maxViews))
+		}
+		score := feedAffinityWeight*profile.affinity(p) +
+			feedFreshnessWeight*halfLifeDecay(now.Sub(p.CreatedAt), feedFreshHalfLife) +
+			feedPopularityWeight*popularity
+		if profile.seen[p.ID.Hex()] {
+			score *= feedSeenPenalty
+		}
+		scores[p] = score
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return scores[candidates[i]] > scores[candidates[j]] })
+	candidates = collapseDuplicateClusters(candidates)
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	// top up a thin feed with the most viewed listings
+	if len(candidates) < limit {
+		top, err := GetTopProperties(limit)
+		if err != nil {
+			return nil, false, err
+		}
+		included := make(map[primitive.ObjectID]bool, len(candidates))
+		for _, p := range candidates {
+			included[p.ID] = true
+		}
+		for _, p := range top {
+			if len(candidates) == limit {
+				break
+			}
+			if !included[p.ID] && !profile.engaged[p.ID.Hex()] {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	return candidates, true, nil
+}
+
+// findFeedCandidates loads the newest public listings in the user's top cities and localities
+func findFeedCandidates(profile *tasteProfile) ([]*Property, error) {
+	var places bson.A
+	if cities := topKeys(profile.cities, feedTopCities); len(cities) > 0 {
+		places = append(places, bson.M{"cityId": bson.M{"$in": cities}})
+	}
+	if localities := topKeys(profile.localities, feedTopLocalities); len(localities) > 0 {
+		places = append(places, bson.M{"localityId": bson.M{"$in": localities}})
+	}
+	filter := publicListingFilter()
+	filter["$or"] = places
+
+	var engaged []primitive.ObjectID
+	for id := range profile.engaged {
+		if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+			engaged = append(engaged, objID)
+		}
+	}
+	if len(engaged) > 0 {
+		filter["_id"] = bson.M{"$nin": engaged}
+	}
+
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(feedCandidatePool)
+	cursor, err := GetPropertyCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var properties []*Property
+	if err := cursor.All(ctx, &properties); err != nil {
+		return nil, err
+	}
+	return properties, nil
+}
+
+// halfLifeDecay halves every halfLife of age, from 1 at age 0
+func halfLifeDecay(age time.Duration, halfLife time.Duration) float64 {
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// relativeWeight is key's weight against the strongest one, so the user's favourite scores 1
+func relativeWeight(weights map[string]float64, key string) float64 {
+	if key == "" {
+		return 0
+	}
+	strongest := 0.0
+	for _, weight := range weights {
+		strongest = math.Max(strongest, weight)
+	}
+	if strongest == 0 {
+		return 0
+	}
+	return weights[key] / strongest
+}
+
+func maxOf(weights map[int]float64) float64 {
+	strongest := 0.0
+	for _, weight := range weights {
+		strongest = math.Max(strongest, weight)
+	}
+	return strongest
+}
+
+// topKeys returns up to n keys with the highest weight
+func topKeys(weights map[string]float64, n int) []string {
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if weights[keys[i]] != weights[keys[j]] {
+			return weights[keys[i]] > weights[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
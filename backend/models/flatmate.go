@@ -0,0 +1,195 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ListingTypeFlatmate marks a listing for a room in a flat someone already lives in
+const ListingTypeFlatmate = "Flatmate"
+
+// room sharing
+const (
+	RoomPrivate = "private"
+	RoomShared  = "shared"
+)
+
+// the values occupants are described with, so searches can filter on them
+var (
+	OccupantGenders     = []string{"Male", "Female", "Other"}
+	OccupantProfessions = []string{"Working", "Student", "Self-employed", "Other"}
+	OccupantHabits      = []string{"Smoking", "Drinking", "Non-vegetarian", "Pets", "Late nights", "Guests"}
+)
+
+// Room is a room on offer in a flatmate listing
+type Room struct {
+	Sharing          string `json:"sharing" bson:"sharing"` // RoomPrivate or RoomShared
+	AttachedBathroom bool   `json:"attachedBathroom,omitempty" bson:"attachedBathroom,omitempty"`
+	RentShare        int    `json:"rentShare" bson:"rentShare"`                             // monthly rent for this room
+	AvailableFrom    string `json:"availableFrom,omitempty" bson:"availableFrom,omitempty"` // YYYY-MM-DD, see DateLayout
+}
+
+// Occupants sums up who already lives in a flatmate listing
+type Occupants struct {
+	Count       int      `json:"count" bson:"count"`
+	Genders     []string `json:"genders,omitempty" bson:"genders,omitempty"`         // every gender among them, see OccupantGenders
+	Professions []string `json:"professions,omitempty" bson:"professions,omitempty"` // see OccupantProfessions
+	Habits      []string `json:"habits,omitempty" bson:"habits,omitempty"`           // see OccupantHabits
+}
+
+// FillFromRooms sets the listing rent and availability from its rooms when the owner left them
+// out: the cheapest room's share and the earliest date, so rent and availability searches
+// match flatmate listings too
+func (p *Property) FillFromRooms() {
+	fillRent, fillDate := p.Rent == 0, p.AvailableFrom == ""
+	for _, room := range p.Rooms {
+		if fillRent && room.RentShare > 0 && (p.Rent == 0 || room.RentShare < p.Rent) {
+			p.Rent = room.RentShare
+		}
+		if fillDate && room.AvailableFrom != "" && (p.AvailableFrom == "" || room.AvailableFrom < p.AvailableFrom) {
+			p.AvailableFrom = room.AvailableFrom
+		}
+	}
+}
+
+// ValidateFlatmate checks the rooms and occupants of a listing
+func (p *Property) ValidateFlatmate() []string {
+	var validationErrors []string
+	if p.ListingType != "" && p.ListingType != ListingTypeFlatmate && (len(p.Rooms) > 0 || p.Occupants != nil) {
+		validationErrors = append(validationErrors, "Rooms and occupants are only for flatmate listings")
+	}
+	for i, room := range p.Rooms {
+		if room.Sharing != RoomPrivate && room.Sharing != RoomShared {
+			validationErrors = append(validationErrors, fmt.Sprintf("Room %d must be private or shared", i+1))
+		}
+		if room.RentShare <= 0 {
+			validationErrors = append(validationErrors, fmt.Sprintf("Room %d needs a rent share", i+1))
+		}
+		if room.AvailableFrom != "" && !ValidDate(room.AvailableFrom) {
+			validationErrors = append(validationErrors, fmt.Sprintf("Room %d available from must be a date like 2006-01-02", i+1))
+		}
+	}
+
+	if o := p.Occupants; o != nil {
+		if o.Count < 0 {
+			validationErrors = append(validationErrors, "Occupant count cannot be negative")
+		} else if len(o.Genders) > o.Count || len(o.Professions) > o.Count {
+			validationErrors = append(validationErrors, "More occupant genders or professions than occupants")
+		}
+		checks := []struct {
+			name    string
+			values  []string
+			allowed []string
+		}{
+			{"gender", o.Genders, OccupantGenders},
+			{"profession", o.Professions, OccupantProfessions},
+			{"habit", o.Habits, OccupantHabits},
+		}
+		for _, check := range checks {
+			for _, value := range check.values {
+				if !containsString(check.allowed, value) {
+					validationErrors = append(validationErrors, fmt.Sprintf("Unknown occupant %s %q, use one of %s", check.name, value, strings.Join(check.allowed, ", ")))
+				}
+			}
+		}
+	}
+	return validationErrors
+}
+
+// applyFlatmateFilters adds the flatmate filters to a match stage; any of them limits the search
+// to flatmate listings:
+//   - roomSharing "private"/"shared", attachedBathroom, maxRentShare: a single room must offer
+//     all of them
+//   - occupantGender: everyone living there is of that gender
+//   - maxOccupants: at most that many people live there already
+//   - occupantProfession: someone living there has that profession
+//   - excludeHabits: nobody living there has any of these habits, a list or comma separated
+func applyFlatmateFilters(matchStage bson.M, filters map[string]interface{}) error {
+	flatmate := false
+
+	room := bson.M{}
+	if sharing, ok := filters["roomSharing"].(string); ok && sharing != "" {
+		if sharing != RoomPrivate && sharing != RoomShared {
+			return fmt.Errorf("%w: roomSharing must be private or shared", ErrInvalidSearchFilter)
+		}
+		room["sharing"] = sharing
+	}
+	if attached, ok := filters["attachedBathroom"].(bool); ok && attached {
+		room["attachedBathroom"] = true
+	}
+	if maxShare, ok := filters["maxRentShare"].(float64); ok {
+		if maxShare <= 0 {
+			return fmt.Errorf("%w: maxRentShare must be positive", ErrInvalidSearchFilter)
+		}
+		room["rentShare"] = bson.M{"$lte": maxShare}
+	}
+	if len(room) > 0 {
+		matchStage["rooms"] = bson.M{"$elemMatch": room}
+		flatmate = true
+	}
+
+	if gender, ok := filters["occupantGender"].(string); ok && gender != "" {
+		if !containsString(OccupantGenders, gender) {
+			return fmt.Errorf("%w: occupantGender must be one of %s", ErrInvalidSearchFilter, strings.Join(OccupantGenders, ", "))
+		}
+		// every occupant is of that gender, and at least one is known
+		matchStage["occupants.genders"] = bson.M{"$not": bson.M{"$elemMatch": bson.M{"$ne": gender}}}
+		matchStage["occupants.genders.0"] = bson.M{"$exists": true}
+		flatmate = true
+	}
+	if maxOccupants, ok := filters["maxOccupants"].(float64); ok {
+		if maxOccupants < 0 {
+			return fmt.Errorf("%w: maxOccupants cannot be negative", ErrInvalidSearchFilter)
+		}
+		matchStage["occupants.count"] = bson.M{"$lte": maxOccupants}
+		flatmate = true
+	}
+	if profession, ok := filters["occupantProfession"].(string); ok && profession != "" {
+		if !containsString(OccupantProfessions, profession) {
+			return fmt.Errorf("%w: occupantProfession must be one of %s", ErrInvalidSearchFilter, strings.Join(OccupantProfessions, ", "))
+		}
+		matchStage["occupants.professions"] = profession
+		flatmate = true
+	}
+
+	var habits []string
+	switch value := filters["excludeHabits"].(type) {
+	case string:
+		for _, habit := range strings.Split(value, ",") {
+			if habit = strings.TrimSpace(habit); habit != "" {
+				habits = append(habits, habit)
+			}
+		}
+	case []interface{}:
+		for _, habit := range value {
+			if s, ok := habit.(string); ok && s != "" {
+				habits = append(habits, s)
+			}
+		}
+	}
+	for _, habit := range habits {
+		if !containsString(OccupantHabits, habit) {
+			return fmt.Errorf("%w: unknown habit %q in excludeHabits", ErrInvalidSearchFilter, habit)
+		}
+	}
+	if len(habits) > 0 {
+		matchStage["occupants.habits"] = bson.M{"$nin": habits}
+		flatmate = true
+	}
+
+	if flatmate {
+		matchStage["listingType"] = ListingTypeFlatmate
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
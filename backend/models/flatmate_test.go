@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestValidateFlatmate(t *testing.T) {
+	listing := Property{
+		ListingType: ListingTypeFlatmate,
+		Rooms: []Room{
+			{Sharing: RoomPrivate, AttachedBathroom: true, RentShare: 14000, AvailableFrom: "2026-11-01"},
+			{Sharing: RoomShared, RentShare: 8000},
+		},
+		Occupants: &Occupants{Count: 2, Genders: []string{"Female"}, Professions: []string{"Working", "Student"}, Habits: []string{"Pets"}},
+	}
+	if got := listing.ValidateFlatmate(); len(got) != 0 {
+		t.Fatalf("ValidateFlatmate() = %v, want no errors", got)
+	}
+
+	listing.Rooms = append(listing.Rooms, Room{Sharing: "dorm", AvailableFrom: "01/11/2026"})
+	listing.Occupants.Count = 1
+	listing.Occupants.Habits = append(listing.Occupants.Habits, "Loud music")
+	want := []string{
+		"Room 3 must be private or shared",
+		"Room 3 needs a rent share",
+		"Room 3 available from must be a date like 2006-01-02",
+		"More occupant genders or professions than occupants",
+		`Unknown occupant habit "Loud music", use one of Smoking, Drinking, Non-vegetarian, Pets, Late nights, Guests`,
+	}
+	if got := listing.ValidateFlatmate(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateFlatmate() = %q, want %q", got, want)
+	}
+
+	listing.Occupants = &Occupants{Count: -1, Genders: []string{"Male"}}
+	listing.Rooms = nil
+	if got := listing.ValidateFlatmate(); !reflect.DeepEqual(got, []string{"Occupant count cannot be negative"}) {
+		t.Errorf("ValidateFlatmate() with a negative count = %q", got)
+	}
+
+	rent := Property{ListingType: "Rent", Rooms: []Room{{Sharing: RoomPrivate, RentShare: 12000}}}
+	if got := rent.ValidateFlatmate(); !reflect.DeepEqual(got, []string{"Rooms and occupants are only for flatmate listings"}) {
+		t.Errorf("ValidateFlatmate() on a rent listing = %q", got)
+	}
+}
+
+func TestFillFromRooms(t *testing.T) {
+	listing := Property{Rooms: []Room{
+		{RentShare: 15000, AvailableFrom: "2026-12-01"},
+		{RentShare: 11000},
+		{RentShare: 13000, AvailableFrom: "2026-11-15"},
+	}}
+	listing.FillFromRooms()
+	if listing.Rent != 11000 || listing.AvailableFrom != "2026-11-15" {
+		t.Errorf("FillFromRooms() = %d from %s, want the cheapest room and earliest date", listing.Rent, listing.AvailableFrom)
+	}
+
+	// what the owner gave for the whole flat is kept
+	listing.Rent, listing.AvailableFrom = 30000, "2027-01-01"
+	listing.FillFromRooms()
+	if listing.Rent != 30000 || listing.AvailableFrom != "2027-01-01" {
+		t.Errorf("FillFromRooms() overwrote the owner's %d from %s", listing.Rent, listing.AvailableFrom)
+	}
+}
+
+func TestApplyFlatmateFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		want    bson.M
+		invalid bool
+	}{
+		{"no flatmate filters", map[string]interface{}{"minRent": 1000.0}, bson.M{}, false},
+		{"room filters", map[string]interface{}{"roomSharing": RoomPrivate, "attachedBathroom": true, "maxRentShare": 12000.0}, bson.M{
+			"rooms":       bson.M{"$elemMatch": bson.M{"sharing": RoomPrivate, "attachedBathroom": true, "rentShare": bson.M{"$lte": 12000.0}}},
+			"listingType": ListingTypeFlatmate,
+		}, false},
+		{"occupant gender needs known occupants", map[string]interface{}{"occupantGender": "Female"}, bson.M{
+			"occupants.genders":   bson.M{"$not": bson.M{"$elemMatch": bson.M{"$ne": "Female"}}},
+			"occupants.genders.0": bson.M{"$exists": true},
+			"listingType":         ListingTypeFlatmate,
+		}, false},
+		{"excluded habits from a list", map[string]interface{}{"excludeHabits": "Smoking, Pets"}, bson.M{
+			"occupants.habits": bson.M{"$nin": []string{"Smoking", "Pets"}},
+			"listingType":      ListingTypeFlatmate,
+		}, false},
+		{"unknown sharing", map[string]interface{}{"roomSharing": "dorm"}, nil, true},
+		{"unknown gender", map[string]interface{}{"occupantGender": "female"}, nil, true},
+		{"negative rent share", map[string]interface{}{"maxRentShare": -1.0}, nil, true},
+		{"unknown habit", map[string]interface{}{"excludeHabits": []interface{}{"Snoring"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchStage := bson.M{}
+			err := applyFlatmateFilters(matchStage, tt.filters)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidSearchFilter) {
+					t.Errorf("applyFlatmateFilters() error = %v, want ErrInvalidSearchFilter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyFlatmateFilters() error = %v", err)
+			}
+			if !reflect.DeepEqual(matchStage, tt.want) {
+				t.Errorf("applyFlatmateFilters() = %v, want %v", matchStage, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	defaultNearDistanceKm   = 5
+	maxNearDistanceKm       = 100
+	defaultOfficeDistanceKm = 15
+	maxSearchOffices        = 3
+	commuteCandidatePool    = 500 // listings ranked in memory per commute search
+)
+
+// ErrInvalidSearchFilter wraps every rejected search filter so handlers can answer 400
+var ErrInvalidSearchFilter = errors.New("invalid search filter")
+
+// GeoPoint is a GeoJSON point. Coordinates are [longitude, latitude], the order MongoDB expects.
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+func NewGeoPoint(latitude, longitude float64) *GeoPoint {
+	return &GeoPoint{Type: "Point", Coordinates: []float64{longitude, latitude}}
+}
+
+// ValidCoordinates reports whether latitude and longitude are on the globe
+func ValidCoordinates(latitude, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
+
+// HasCoordinates reports whether the listing was given a position. (0, 0) is in the Atlantic and
+// stands for "no coordinates".
+func (p *Property) HasCoordinates() bool {
+	return p.Latitude != 0 || p.Longitude != 0
+}
+
+// syncGeoPoint derives the indexed GeoJSON point and the office distances from Latitude and Longitude
+func (p *Property) syncGeoPoint() {
+	if p.HasCoordinates() && ValidCoordinates(p.Latitude, p.Longitude) {
+		p.Geo = NewGeoPoint(p.Latitude, p.Longitude)
+		p.DistancesFromOffices = popularOfficeDistances(p.Latitude, p.Longitude)
+		p.OfficeDistancesVersion = WorkplacesVersion
+	} else {
+		p.Geo = nil
+		p.DistancesFromOffices = nil
+		p.OfficeDistancesVersion = 0
+	}
+}
+
+// withinRadius matches points within km of center
+func withinRadius(center *GeoPoint, km float64) bson.M {
+	return bson.M{"$geoWithin": bson.M{"$centerSphere": bson.A{center.Coordinates, km / utils.EarthRadiusKm}}}
+}
+
+// addGeoConstraint adds a condition on "geo", moving to $and once there is more than one
+func addGeoConstraint(filter bson.M, condition bson.M) {
+	existing, ok := filter["geo"]
+	if !ok {
+		if and, ok := filter["$and"].(bson.A); ok {
+			filter["$and"] = append(and, bson.M{"geo": condition})
+		} else {
+			filter["geo"] = condition
+		}
+		return
+	}
+	delete(filter, "geo")
+	and, _ := filter["$and"].(bson.A)
+	filter["$and"] = append(and, bson.M{"geo": existing}, bson.M{"geo": condition})
+}
+
+// nearFilter reads "near" as {"latitude": .., "longitude": ..} or "lat,lng", and "maxDistance" in
+// km. It returns nil when the search has no near filter.
+func nearFilter(filters map[string]interface{}) (*GeoPoint, float64, error) {
+	var latitude, longitude float64
+	switch near := filters["near"].(type) {
+	case nil:
+		return nil, 0, nil
+	case map[string]interface{}:
+		lat, latOK := near["latitude"].(float64)
+		lng, lngOK := near["longitude"].(float64)
+		if !latOK || !lngOK {
+			return nil, 0, fmt.Errorf("%w: near needs latitude and longitude", ErrInvalidSearchFilter)
+		}
+		latitude, longitude = lat, lng
+	case string:
+		parts := strings.Split(near, ",")
+		if len(parts) != 2 {
+			return nil, 0, fmt.Errorf("%w: near must be \"latitude,longitude\"", ErrInvalidSearchFilter)
+		}
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lng, errLng := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat != nil || errLng != nil {
+			return nil, 0, fmt.Errorf("%w: near must be \"latitude,longitude\"", ErrInvalidSearchFilter)
+		}
+		latitude, longitude = lat, lng
+	default:
+		return nil, 0, fmt.Errorf("%w: near must be an object with latitude and longitude", ErrInvalidSearchFilter)
+	}
+	if !ValidCoordinates(latitude, longitude) {
+		return nil, 0, fmt.Errorf("%w: near is not a valid coordinate", ErrInvalidSearchFilter)
+	}
+
+	maxDistance := float64(defaultNearDistanceKm)
+	if km, ok := filters["maxDistance"].(float64); ok {
+		if km <= 0 || km > maxNearDistanceKm {
+			return nil, 0, fmt.Errorf("%w: maxDistance must be between 0 and %d km", ErrInvalidSearchFilter, maxNearDistanceKm)
+		}
+		maxDistance = km
+	}
+	return NewGeoPoint(latitude, longitude), maxDistance, nil
+}
+
+// bboxFilter reads "bbox" as [west, south, east, north], the GeoJSON bounding box order, and
+// returns the matching $geoWithin query, or nil when the search has no bounding box
+func bboxFilter(filters map[string]interface{}) (bson.M, error) {
+	raw, ok := filters["bbox"]
+	if !ok {
+		return nil, nil
+	}
+
+	var box []float64
+	switch v := raw.(type) {
+	case []interface{}:
+		for _, n := range v {
+			f, ok := n.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%w: bbox must hold four numbers", ErrInvalidSearchFilter)
+			}
+			box = append(box, f)
+		}
+	case string:
+		for _, part := range strings.Split(v, ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bbox must hold four numbers", ErrInvalidSearchFilter)
+			}
+			box = append(box, f)
+		}
+	}
+	if len(box) != 4 {
+		return nil, fmt.Errorf("%w: bbox must be [west, south, east, north]", ErrInvalidSearchFilter)
+	}
+	west, south, east, north := box[0], box[1], box[2], box[3]
+	if !ValidCoordinates(south, west) || !ValidCoordinates(north, east) || south >= north || west >= east {
+		return nil, fmt.Errorf("%w: bbox is not a valid [west, south, east, north] box", ErrInvalidSearchFilter)
+	}
+
+	ring := [][]float64{{west, south}, {east, south}, {east, north}, {west, north}, {west, south}}
+	return bson.M{"$geoWithin": bson.M{"$geometry": bson.M{"type": "Polygon", "coordinates": [][][]float64{ring}}}}, nil
+}
+
+// officesFilter reads "offices" as a list (or comma separated string) of workplace IDs, names or
+// aliases, and "maxOfficeDistance" in km
+func officesFilter(filters map[string]interface{}) ([]*Workplace, float64, error) {
+	var queries []string
+	switch v := filters["offices"].(type) {
+	case nil:
+		return nil, 0, nil
+	case string:
+		queries = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			if query, ok := item.(string); ok {
+				queries = append(queries, query)
+			}
+		}
+	default:
+		return nil, 0, fmt.Errorf("%w: offices must be a list of office names", ErrInvalidSearchFilter)
+	}
+
+	var offices []*Workplace
+	for _, query := range queries {
+		if strings.TrimSpace(query) == "" {
+			continue
+		}
+		office := FindWorkplace(query)
+		if office == nil {
+			return nil, 0, fmt.Errorf("%w: unknown office %q", ErrInvalidSearchFilter, query)
+		}
+		offices = append(offices, office)
+	}
+	if len(offices) > maxSearchOffices {
+		return nil, 0, fmt.Errorf("%w: at most %d offices can be searched at once", ErrInvalidSearchFilter, maxSearchOffices)
+	}
+
+	maxDistance := float64(defaultOfficeDistanceKm)
+	if km, ok := filters["maxOfficeDistance"].(float64); ok {
+		if km <= 0 || km > maxNearDistanceKm {
+			return nil, 0, fmt.Errorf("%w: maxOfficeDistance must be between 0 and %d km", ErrInvalidSearchFilter, maxNearDistanceKm)
+		}
+		maxDistance = km
+	}
+	return offices, maxDistance, nil
+}
+
+// searchByCommute returns the listings within maxOfficeKm of every office, ranked by the longest
+// of their straight-line commutes. Candidates are the closest to the first office; with several
+// offices the ranking is exact within that pool.
+func searchByCommute(ctx context.Context, offices []*Workplace, maxOfficeKm float64, near *GeoPoint, maxDistanceKm float64, matchStage bson.M, limit int64) ([]*Property, error) {
+	for _, office := range offices[1:] {
+		addGeoConstraint(matchStage, withinRadius(NewGeoPoint(office.Latitude, office.Longitude), maxOfficeKm))
+	}
+	if near != nil {
+		addGeoConstraint(matchStage, withinRadius(near, maxDistanceKm))
+	}
+
+	first := NewGeoPoint(offices[0].Latitude, offices[0].Longitude)
+	candidates, err := searchNear(ctx, first, maxOfficeKm, matchStage, commuteCandidatePool)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range candidates {
+		p.DistanceKm = 0
+		if near != nil {
+			p.DistanceKm = utils.RoundKm(utils.HaversineKm(near.Coordinates[1], near.Coordinates[0], p.Latitude, p.Longitude))
+		}
+		if p.DistancesFromOffices == nil {
+			p.DistancesFromOffices = make(map[string]float64)
+		}
+		p.CommuteKm = 0
+		for _, office := range offices {
+			km := office.DistanceKm(p.Latitude, p.Longitude)
+			p.DistancesFromOffices[office.ID] = km
+			p.CommuteKm = math.Max(p.CommuteKm, km)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].CommuteKm < candidates[j].CommuteKm })
+
+	if int64(len(candidates)) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrImportRowOutOfOrder is returned for a row result that does not follow the last recorded
+// row, because it was already recorded or another run of the job got ahead
+var ErrImportRowOutOfOrder = errors.New("import row result out of order")
+
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ImportJob tracks one bulk listing import. Rows are processed in order and ProcessedRows is
+// saved after every row, so a crashed or failed import resumes where it stopped.
+type ImportJob struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OwnerID       string             `bson:"ownerId" json:"ownerId"`
+	Format        string             `bson:"format" json:"format"`   // "csv", "xlsx", "ndjson"
+	SourceKey     string             `bson:"sourceKey" json:"-"`     // uploaded file in the blob store
+	Mapping       map[string]string  `bson:"mapping" json:"mapping"` // source column -> property field
+	Status        string             `bson:"status" json:"status"`
+	Error         string             `bson:"error,omitempty" json:"error,omitempty"`
+	TotalRows     int                `bson:"totalRows" json:"totalRows"`
+	ProcessedRows int                `bson:"processedRows" json:"processedRows"`
+	Accepted      int                `bson:"accepted" json:"accepted"`
+	Rejected      int                `bson:"rejected" json:"rejected"`
+	Rows          []ImportRowResult  `bson:"rows" json:"rows"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ImportRowResult is one line of the import report. Row numbers are 1-based data rows.
+type ImportRowResult struct {
+	Row        int      `bson:"row" json:"row"`
+	Accepted   bool     `bson:"accepted" json:"accepted"`
+	PropertyID string   `bson:"propertyId,omitempty" json:"propertyId,omitempty"`
+	Errors     []string `bson:"errors,omitempty" json:"errors,omitempty"`
+}
+
+func GetImportJobCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("import_jobs")
+}
+
+func CreateImportJob(job *ImportJob) error {
+	job.ID = primitive.NewObjectID()
+	job.Status = ImportPending
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+	_, err := GetImportJobCollection().InsertOne(context.Background(), job)
+	return err
+}
+
+func GetImportJobByID(id string) (*ImportJob, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var job ImportJob
+	err = GetImportJobCollection().FindOne(context.Background(), bson.M{"_id": objID}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimImportJob marks a job as running unless it already is. A running job that has not
+// progressed for staleAfter is assumed to belong to a crashed process and may be claimed again.
+func ClaimImportJob(id primitive.ObjectID, staleAfter time.Duration) (bool, error) {
+	filter := bson.M{
+		"_id": id,
+		"$or": []bson.M{
+			{"status": bson.M{"$in": []string{ImportPending, ImportFailed}}},
+			{"status": ImportRunning, "updatedAt": bson.M{"$lt": time.Now().Add(-staleAfter)}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"status": ImportRunning, "error": "", "updatedAt": time.Now()}}
+	result, err := GetImportJobCollection().UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// SetImportJobStatus moves a job to a new status, recording the error if there is one
+func SetImportJobStatus(id primitive.ObjectID, status string, errMessage string) error {
+	update := bson.M{"$set": bson.M{"status": status, "error": errMessage, "updatedAt": time.Now()}}
+	_, err := GetImportJobCollection().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
+
+// SetImportJobTotal records how many data rows the source file has
+func SetImportJobTotal(id primitive.ObjectID, total int) error {
+	update := bson.M{"$set": bson.M{"totalRows": total, "updatedAt": time.Now()}}
+	_, err := GetImportJobCollection().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
+
+// RecordImportRow appends a row result and advances the resume cursor in one update. Results
+// must come in row order; any other is refused with ErrImportRowOutOfOrder.
+func RecordImportRow(id primitive.ObjectID, result ImportRowResult) error {
+	counter := "rejected"
+	if result.Accepted {
+		counter = "accepted"
+	}
+	update := bson.M{
+		"$push": bson.M{"rows": result},
+		"$set":  bson.M{"processedRows": result.Row, "updatedAt": time.Now()},
+		"$inc":  bson.M{counter: 1},
+	}
+	updated, err := GetImportJobCollection().UpdateOne(context.Background(), bson.M{"_id": id, "processedRows": result.Row - 1}, update)
+	if err != nil {
+		return err
+	}
+	if updated.MatchedCount == 0 {
+		return fmt.Errorf("%w: row %d of job %s", ErrImportRowOutOfOrder, result.Row, id.Hex())
+	}
+	return nil
+}
+
+// FindImportedProperty returns the listing already created for a row, so a resumed import
+// never inserts the same row twice
+func FindImportedProperty(jobID string, row int) (*Property, error) {
+	var property Property
+	err := GetPropertyCollection().FindOne(context.Background(), bson.M{"importJobId": jobID, "importRow": row}).Decode(&property)
+	if err != nil {
+		return nil, err
+	}
+	return &property, nil
+}
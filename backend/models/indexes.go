@@ -0,0 +1,109 @@
+package models
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes queries rely on. Creating an existing index is a no-op.
+func EnsureIndexes() error {
+	_, err := GetPropertyCollection().Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "geo", Value: "2dsphere"}}},
+		{Keys: bson.D{{Key: "contactNumbers", Value: 1}}},
+		{Keys: bson.D{{Key: "fingerprint.photoKeys", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "risk.score", Value: -1}}},
+		{Keys: bson.D{{Key: "societyId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetPriceHistoryCollection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "changedAt", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetPriceAlertCollection().Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "propertyId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "propertyId", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetVisitSlotCollection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "propertyId", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetVisitCollection().Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "startsAt", Value: 1}}},
+		{Keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "startsAt", Value: 1}}},
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "startsAt", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "startsAt", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = getVisitBookingCollection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "startsAt", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetShortlistCollection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetSocietyCollection().Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "cityId", Value: 1}, {Key: "nameKey", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "cityId", Value: 1}, {Key: "aliasKeys", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetReviewCollection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "societyId", Value: 1}, {Key: "createdat", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetContactLeadCollection().Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "firstRevealedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "lastRevealedAt", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetListingReportCollection().Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "reporterId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = GetUserSignalCollection().Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "kind", Value: 1}, {Key: "propertyId", Value: 1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(UserSignalRetention.Seconds()))},
+	})
+	return err
+}
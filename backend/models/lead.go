@@ -0,0 +1,145 @@
+package models
+
+import (
+	"backend/services"
+	"backend/utils"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ContactRevealLimit  = 20 // listings a user may reveal the contact of per ContactRevealWindow
+	ContactRevealWindow = 24 * time.Hour
+
+	DefaultLeadPage = 50
+	MaxLeadPage     = 200
+)
+
+// ContactLead records that a user revealed the contact details of a listing, so the owner knows
+// who to expect a call from. Revealing the same listing again only bumps RevealCount.
+type ContactLead struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PropertyID      string             `json:"propertyId" bson:"propertyId"`
+	OwnerID         string             `json:"-" bson:"ownerId"`
+	UserID          string             `json:"userId" bson:"userId"`
+	UserName        string             `json:"userName,omitempty" bson:"userName,omitempty"`
+	UserEmail       string             `json:"userEmail,omitempty" bson:"userEmail,omitempty"`
+	RevealCount     int                `json:"revealCount" bson:"revealCount"`
+	FirstRevealedAt time.Time          `json:"firstRevealedAt" bson:"firstRevealedAt"`
+	LastRevealedAt  time.Time          `json:"lastRevealedAt" bson:"lastRevealedAt"`
+}
+
+// ContactDetails are the ways to reach the owner of a listing
+type ContactDetails struct {
+	PropertyID string   `json:"propertyId"`
+	OwnerName  string   `json:"ownerName,omitempty"`
+	Phones     []string `json:"phones"`
+	Emails     []string `json:"emails"`
+	Link       string   `json:"link,omitempty"`
+}
+
+func GetContactLeadCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("contact_leads")
+}
+
+// HideContacts strips the phone numbers and email addresses from a listing shown to anyone but
+// its owner; they are only given out through RevealContact
+func (p *Property) HideContacts() {
+	p.Link = ""
+	p.Description = utils.MaskContacts(p.Description)
+}
+
+// HideContacts strips the contact details from every listing
+func HideContacts(properties []*Property) {
+	for _, property := range properties {
+		if property != nil {
+			property.HideContacts()
+		}
+	}
+}
+
+// ContactDetails collects the phone numbers and email addresses given in the listing, plus the
+// owner's verified phone
+func (p *Property) ContactDetails(owner *User) *ContactDetails {
+	details := &ContactDetails{
+		PropertyID: p.ID.Hex(),
+		Phones:     []string{},
+		Emails:     utils.FindEmails(p.Description),
+		Link:       p.Link,
+	}
+	seen := make(map[string]bool)
+	addPhones := func(numbers []string) {
+		for _, number := range numbers {
+			if !seen[number] {
+				seen[number] = true
+				details.Phones = append(details.Phones, number)
+			}
+		}
+	}
+	addPhones(utils.FindPhoneNumbers(p.Link))
+	addPhones(p.ContactNumbers)
+	addPhones(utils.FindPhoneNumbers(p.Description)) // listings from before ContactNumbers was kept
+	if owner != nil {
+		details.OwnerName = owner.Name
+		if owner.PhoneVerified {
+			addPhones(utils.FindPhoneNumbers(owner.Phone))
+		}
+	}
+	if details.Emails == nil {
+		details.Emails = []string{}
+	}
+	return details
+}
+
+// HasRevealedContact reports whether the user revealed the listing's contact before; repeat
+// reveals are not rate limited
+func HasRevealedContact(propertyID string, userID string) (bool, error) {
+	count, err := GetContactLeadCollection().CountDocuments(context.Background(), bson.M{"propertyId": propertyID, "userId": userID})
+	return count > 0, err
+}
+
+// CountRecentContactReveals counts the listings the user first revealed within ContactRevealWindow
+func CountRecentContactReveals(userID string) (int64, error) {
+	since := time.Now().Add(-ContactRevealWindow)
+	return GetContactLeadCollection().CountDocuments(context.Background(), bson.M{"userId": userID, "firstRevealedAt": bson.M{"$gte": since}})
+}
+
+// RecordContactReveal logs a reveal of the listing's contact by user as a lead for its owner
+func RecordContactReveal(property *Property, user *User) (*ContactLead, error) {
+	now := time.Now()
+	filter := bson.M{"propertyId": property.ID.Hex(), "userId": user.ID.Hex()}
+	update := bson.M{
+		"$setOnInsert": bson.M{"ownerId": property.OwnerID, "firstRevealedAt": now},
+		"$set":         bson.M{"userName": user.Name, "userEmail": user.Email, "lastRevealedAt": now},
+		"$inc":         bson.M{"revealCount": 1},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var lead ContactLead
+	if err := GetContactLeadCollection().FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&lead); err != nil {
+		return nil, err
+	}
+	return &lead, nil
+}
+
+// GetOwnerLeads lists the leads on the owner's listings, most recent first
+func GetOwnerLeads(ownerID string, limit int64) ([]*ContactLead, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.M{"lastRevealedAt": -1}).SetLimit(limit)
+	cursor, err := GetContactLeadCollection().Find(ctx, bson.M{"ownerId": ownerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	leads := []*ContactLead{}
+	if err := cursor.All(ctx, &leads); err != nil {
+		return nil, err
+	}
+	return leads, nil
+}
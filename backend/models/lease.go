@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DateLayout is how calendar dates such as AvailableFrom are written. Dates in this layout sort
+// as strings, so they are stored and compared as strings.
+const DateLayout = "2006-01-02"
+
+// ValidDate reports whether s is a calendar date in DateLayout
+func ValidDate(s string) bool {
+	_, err := time.Parse(DateLayout, s)
+	return err == nil
+}
+
+// ValidateLease checks the availability and lease terms of a listing
+func (p *Property) ValidateLease() []string {
+	var validationErrors []string
+	if p.AvailableFrom != "" && !ValidDate(p.AvailableFrom) {
+		validationErrors = append(validationErrors, "Available from must be a date like 2006-01-02")
+	}
+	if p.MinimumLeaseMonths < 0 || p.LockInMonths < 0 || p.NoticePeriodDays < 0 {
+		validationErrors = append(validationErrors, "Lease terms cannot be negative")
+	}
+	if p.MinimumLeaseMonths > 0 && p.LockInMonths > p.MinimumLeaseMonths {
+		validationErrors = append(validationErrors, "Lock-in cannot be longer than the minimum lease")
+	}
+	return validationErrors
+}
+
+// applyLeaseFilters adds the availability and lease filters to a match stage:
+//   - availableBy "YYYY-MM-DD": free on or before that date. A date already past only counts
+//     while the listing is marked available, as do listings without a date.
+//   - maxLeaseMonths, maxLockInMonths, maxNoticePeriodDays: only listings that state a term
+//     within the limit
+func applyLeaseFilters(matchStage bson.M, filters map[string]interface{}) error {
+	if availableBy, ok := filters["availableBy"].(string); ok && availableBy != "" {
+		if !ValidDate(availableBy) {
+			return fmt.Errorf("%w: availableBy must be a date like 2006-01-02", ErrInvalidSearchFilter)
+		}
+		today := time.Now().Format(DateLayout)
+		addCondition(matchStage, bson.M{"$or": bson.A{
+			bson.M{"availableFrom": bson.M{"$gt": today, "$lte": availableBy}},
+			bson.M{"availableFrom": bson.M{"$lte": availableBy}, "isAvailable": true},
+			bson.M{"availableFrom": bson.M{"$exists": false}, "isAvailable": true},
+		}})
+	}
+
+	limits := []struct{ filter, field string }{
+		{"maxLeaseMonths", "minimumLeaseMonths"},
+		{"maxLockInMonths", "lockInMonths"},
+		{"maxNoticePeriodDays", "noticePeriodDays"},
+	}
+	for _, limit := range limits {
+		value, ok := filters[limit.filter].(float64)
+		if !ok {
+			continue
+		}
+		if value < 0 {
+			return fmt.Errorf("%w: %s cannot be negative", ErrInvalidSearchFilter, limit.filter)
+		}
+		matchStage[limit.field] = bson.M{"$lte": value}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestValidateLease(t *testing.T) {
+	terms := Property{AvailableFrom: "2026-11-01", MinimumLeaseMonths: 11, LockInMonths: 6, NoticePeriodDays: 30}
+	if got := terms.ValidateLease(); len(got) != 0 {
+		t.Fatalf("ValidateLease() = %q, want no errors", got)
+	}
+
+	for _, date := range []string{"1 Nov 2026", "2026-02-30", "2026-11-1"} {
+		if got := (&Property{AvailableFrom: date}).ValidateLease(); len(got) != 1 {
+			t.Errorf("ValidateLease() with available from %q = %q, want one error", date, got)
+		}
+	}
+	if got := (&Property{MinimumLeaseMonths: 6, LockInMonths: 11}).ValidateLease(); len(got) != 1 {
+		t.Errorf("ValidateLease() with a lock-in past the lease = %q, want one error", got)
+	}
+	// a lock-in alone says nothing about the lease length
+	if got := (&Property{LockInMonths: 11}).ValidateLease(); len(got) != 0 {
+		t.Errorf("ValidateLease() with only a lock-in = %q, want no errors", got)
+	}
+}
+
+// TestAvailableBy runs the availableBy condition against listings the way MongoDB would
+func TestAvailableBy(t *testing.T) {
+	day := func(days int) string { return time.Now().AddDate(0, 0, days).Format(DateLayout) }
+	matchStage := bson.M{}
+	if err := applyLeaseFilters(matchStage, map[string]interface{}{"availableBy": day(30)}); err != nil {
+		t.Fatal(err)
+	}
+	condition := matchStage["$and"].(bson.A)[0].(bson.M)
+
+	listings := []struct {
+		name    string
+		listing bson.M
+		want    bool
+	}{
+		{"free next week", bson.M{"availableFrom": day(7), "isAvailable": true}, true},
+		{"free next week, flagged taken", bson.M{"availableFrom": day(7), "isAvailable": false}, true},
+		{"free after the date", bson.M{"availableFrom": day(60), "isAvailable": true}, false},
+		{"free since last month", bson.M{"availableFrom": day(-30), "isAvailable": true}, true},
+		{"free since last month, since taken", bson.M{"availableFrom": day(-30), "isAvailable": false}, false},
+		{"no date, available", bson.M{"isAvailable": true}, true},
+		{"no date, taken", bson.M{"isAvailable": false}, false},
+	}
+	for _, tt := range listings {
+		if got := matchesCondition(tt.listing, condition); got != tt.want {
+			t.Errorf("availableBy matches listing %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLeaseLimits(t *testing.T) {
+	matchStage := bson.M{}
+	if err := applyLeaseFilters(matchStage, map[string]interface{}{"maxLeaseMonths": 11.0, "maxNoticePeriodDays": 30.0}); err != nil {
+		t.Fatal(err)
+	}
+	if len(matchStage) != 2 || matchStage["minimumLeaseMonths"] == nil || matchStage["noticePeriodDays"] == nil {
+		t.Errorf("applyLeaseFilters() = %v", matchStage)
+	}
+
+	for _, filters := range []map[string]interface{}{{"availableBy": "next month"}, {"maxLockInMonths": -1.0}} {
+		if err := applyLeaseFilters(bson.M{}, filters); !errors.Is(err, ErrInvalidSearchFilter) {
+			t.Errorf("applyLeaseFilters(%v) = %v, want ErrInvalidSearchFilter", filters, err)
+		}
+	}
+}
+
+// matchesCondition evaluates the $or, $gt, $lte and $exists operators the lease filters use
+func matchesCondition(doc bson.M, condition bson.M) bool {
+	for field, want := range condition {
+		if field == "$or" {
+			matched := false
+			for _, branch := range want.(bson.A) {
+				matched = matched || matchesCondition(doc, branch.(bson.M))
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+		value, exists := doc[field]
+		ops, ok := want.(bson.M)
+		if !ok {
+			if value != want {
+				return false
+			}
+			continue
+		}
+		for op, arg := range ops {
+			switch op {
+			case "$exists":
+				if exists != arg.(bool) {
+					return false
+				}
+			case "$gt":
+				if !exists || value.(string) <= arg.(string) {
+					return false
+				}
+			case "$lte":
+				if !exists || value.(string) > arg.(string) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NormalizeLocality rewrites City, Area and Location to the registry's canonical spelling, fills
+// State from the city and sets CityID and LocalityID. Free-text Location and Area that only
+// mention a locality ("2BHK near HSR Sector 2") keep their text but still get its LocalityID.
+func (p *Property) NormalizeLocality() {
+	p.CityID, p.LocalityID = "", ""
+	if city := services.LookupCity(p.City); city != nil {
+		p.setCity(city)
+	}
+
+	locality := services.LookupLocality(p.Area, p.CityID)
+	if locality != nil && !locality.IsCity() {
+		p.Area = locality.Name
+	} else {
+		locality = services.FindLocalityIn(p.Location+" "+p.Area, p.CityID)
+	}
+	if locality != nil {
+		p.LocalityID = locality.ID
+		if p.Area == "" {
+			p.Area = locality.Name
+		}
+		if p.CityID == "" {
+			p.setCity(services.GetLocalityByID(locality.CityID))
+		}
+	}
+
+	if place := services.LookupLocality(p.Location, p.CityID); place != nil {
+		p.Location = place.Name
+		if place.IsCity() && p.CityID == "" {
+			p.setCity(place)
+		}
+	}
+}
+
+func (p *Property) setCity(city *services.Locality) {
+	if city == nil {
+		return
+	}
+	p.City, p.CityID = city.Name, city.ID
+	if p.State == "" {
+		p.State = city.State
+	}
+}
+
+// normalizeLocalityUpdate normalizes the place fields of a partial update against the stored
+// listing, so changing only the area still resolves within the stored city. The place fields of
+// updated are overwritten with the result and IDs that no longer resolve are unset.
+func normalizeLocalityUpdate(id primitive.ObjectID, updated *Property, update bson.M) error {
+	var stored Property
+	projection := bson.M{"location": 1, "area": 1, "city": 1, "state": 1}
+	err := GetPropertyCollection().FindOne(context.Background(), bson.M{"_id": id}, options.FindOne().SetProjection(projection)).Decode(&stored)
+	if err != nil {
+		return err
+	}
+	if updated.Location != "" {
+		stored.Location = updated.Location
+	}
+	if updated.Area != "" {
+		stored.Area = updated.Area
+	}
+	if updated.City != "" {
+		stored.City = updated.City
+		stored.State = updated.State // a new city brings its own state
+	} else if updated.State != "" {
+		stored.State = updated.State
+	}
+	stored.NormalizeLocality()
+
+	updated.Location, updated.Area, updated.City, updated.State = stored.Location, stored.Area, stored.City, stored.State
+	updated.CityID, updated.LocalityID = stored.CityID, stored.LocalityID
+	unset := bson.M{}
+	if stored.CityID == "" {
+		unset["cityId"] = ""
+	}
+	if stored.LocalityID == "" {
+		unset["localityId"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return nil
+}
+
+// cityCondition matches listings in city under any of its spellings, or nil when the registry
+// does not know the city
+func cityCondition(city string) bson.M {
+	known := services.LookupCity(city)
+	if known == nil {
+		return nil
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"cityId": known.ID},
+		bson.M{"city": bson.M{"$regex": primitive.Regex{Pattern: exactNamePattern(known), Options: "i"}}},
+	}}
+}
+
+// localityCondition matches listings in the place a location query names under any of its
+// spellings, or nil when the registry does not know it. cityID picks between localities of the
+// same name in different cities.
+func localityCondition(location string, cityID string) bson.M {
+	place := services.LookupLocality(location, cityID)
+	if place == nil {
+		return nil
+	}
+	mentioned := primitive.Regex{Pattern: `\b` + place.NamesPattern() + `\b`, Options: "i"}
+	if place.IsCity() {
+		return bson.M{"$or": bson.A{
+			bson.M{"cityId": place.ID},
+			bson.M{"city": bson.M{"$regex": primitive.Regex{Pattern: exactNamePattern(place), Options: "i"}}},
+			bson.M{"location": bson.M{"$regex": mentioned}},
+		}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"localityId": place.ID},
+		bson.M{"location": bson.M{"$regex": mentioned}},
+		bson.M{"area": bson.M{"$regex": mentioned}},
+	}}
+}
+
+// searchCityID is the canonical ID of a search's city filter, if the registry knows it
+func searchCityID(filters map[string]interface{}) string {
+	if city, ok := filters["city"].(string); ok {
+		if known := services.LookupCity(city); known != nil {
+			return known.ID
+		}
+	}
+	return ""
+}
+
+// exactNamePattern matches a field holding exactly one of the place's spellings
+func exactNamePattern(place *services.Locality) string {
+	return `^\s*` + place.NamesPattern() + `\s*$`
+}
+
+// addCondition ANDs condition into filter without overwriting other top-level operators
+func addCondition(filter bson.M, condition bson.M) {
+	and, _ := filter["$and"].(bson.A)
+	filter["$and"] = append(and, condition)
+}
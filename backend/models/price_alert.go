@@ -0,0 +1,76 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PriceAlert subscribes a tenant to rent drops of one listing, typically one they favorited or
+// viewed
+type PriceAlert struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID         string             `json:"userId" bson:"userId"`
+	PropertyID     string             `json:"propertyId" bson:"propertyId"`
+	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`
+	LastNotifiedAt *time.Time         `json:"lastNotifiedAt,omitempty" bson:"lastNotifiedAt,omitempty"`
+}
+
+func GetPriceAlertCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("price_alerts")
+}
+
+// SubscribePriceAlert subscribes a user to a listing's price drops; subscribing twice is a no-op
+func SubscribePriceAlert(userID string, propertyID string) error {
+	filter := bson.M{"userId": userID, "propertyId": propertyID}
+	update := bson.M{"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "createdAt": time.Now()}}
+	_, err := GetPriceAlertCollection().UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// UnsubscribePriceAlert reports whether the user was subscribed
+func UnsubscribePriceAlert(userID string, propertyID string) (bool, error) {
+	result, err := GetPriceAlertCollection().DeleteOne(context.Background(), bson.M{"userId": userID, "propertyId": propertyID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// GetUserPriceAlerts returns a user's subscriptions, newest first
+func GetUserPriceAlerts(userID string) ([]*PriceAlert, error) {
+	ctx := context.Background()
+	cursor, err := GetPriceAlertCollection().Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []*PriceAlert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// StreamPriceAlerts opens a cursor over a listing's subscriptions. The caller must close it.
+func StreamPriceAlerts(ctx context.Context, propertyID string) (*mongo.Cursor, error) {
+	return GetPriceAlertCollection().Find(ctx, bson.M{"propertyId": propertyID})
+}
+
+// MarkPriceAlertsNotified records when a listing's subscribers were last told about a drop
+func MarkPriceAlertsNotified(propertyID string) error {
+	_, err := GetPriceAlertCollection().UpdateMany(context.Background(), bson.M{"propertyId": propertyID}, bson.M{"$set": bson.M{"lastNotifiedAt": time.Now()}})
+	return err
+}
+
+// DeletePriceAlerts drops every subscription to a deleted listing
+func DeletePriceAlerts(propertyID string) error {
+	_, err := GetPriceAlertCollection().DeleteMany(context.Background(), bson.M{"propertyId": propertyID})
+	return err
+}
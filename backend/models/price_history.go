@@ -0,0 +1,83 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PriceSnapshot is what a listing asked for at one point in time
+type PriceSnapshot struct {
+	Rent               int `json:"rent" bson:"rent"`
+	SecurityDeposit    int `json:"securityDeposit" bson:"securityDeposit"`
+	MaintenanceCharges int `json:"maintenanceCharges" bson:"maintenanceCharges"`
+}
+
+// PriceChange records one edit of a listing's rent, deposit or maintenance
+type PriceChange struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PropertyID string             `json:"propertyId" bson:"propertyId"`
+	Previous   PriceSnapshot      `json:"previous" bson:"previous"`
+	Current    PriceSnapshot      `json:"current" bson:"current"`
+	ChangedAt  time.Time          `json:"changedAt" bson:"changedAt"`
+}
+
+func GetPriceHistoryCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("price_history")
+}
+
+func (p *Property) priceSnapshot() PriceSnapshot {
+	return PriceSnapshot{Rent: p.Rent, SecurityDeposit: p.SecurityDeposit, MaintenanceCharges: p.MaintenanceCharges}
+}
+
+// IsRentDrop reports whether the change lowered the rent
+func (c *PriceChange) IsRentDrop() bool {
+	return c.Current.Rent < c.Previous.Rent
+}
+
+// newPriceChange compares a stored listing with a partial update, where zero means "unchanged",
+// and returns nil when no price moved
+func newPriceChange(propertyID string, stored *Property, updated *Property) *PriceChange {
+	previous := stored.priceSnapshot()
+	current := previous
+	if updated.Rent != 0 {
+		current.Rent = updated.Rent
+	}
+	if updated.SecurityDeposit != 0 {
+		current.SecurityDeposit = updated.SecurityDeposit
+	}
+	if updated.MaintenanceCharges != 0 {
+		current.MaintenanceCharges = updated.MaintenanceCharges
+	}
+	if current == previous {
+		return nil
+	}
+	return &PriceChange{PropertyID: propertyID, Previous: previous, Current: current, ChangedAt: time.Now()}
+}
+
+func RecordPriceChange(change *PriceChange) error {
+	change.ID = primitive.NewObjectID()
+	_, err := GetPriceHistoryCollection().InsertOne(context.Background(), change)
+	return err
+}
+
+// GetPriceHistory returns every price change of a listing, oldest first
+func GetPriceHistory(propertyID string) ([]*PriceChange, error) {
+	ctx := context.Background()
+	cursor, err := GetPriceHistoryCollection().Find(ctx, bson.M{"propertyId": propertyID}, options.Find().SetSort(bson.M{"changedAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	history := []*PriceChange{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
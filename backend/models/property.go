@@ -4,6 +4,8 @@ import (
 	"backend/services"
 	"backend/utils"
 	"context"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,6 +14,30 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Listing states. Listings saved before statuses existed have none and count as published.
+const (
+	StatusPublished  = "published"
+	StatusDraft      = "draft"
+	StatusProcessing = "processing" // waiting for the AI cleanup, see CleanupTask
+	StatusOnHold     = "on_hold"    // high fraud risk, waiting for moderation, see RiskAssessment
+	StatusRejected   = "rejected"   // taken down by a moderator
+	StatusHidden     = "hidden"     // taken off the site by user reports or a moderator, see ListingReport
+)
+
+// hiddenStatuses never show up in public listings or search
+var hiddenStatuses = []string{StatusDraft, StatusProcessing, StatusOnHold, StatusRejected, StatusHidden}
+
+// CleanableFields are the stored fields the AI cleanup may rewrite. isAvailable is the owner's
+// alone: the description cannot tell whether the flat is still free.
+var CleanableFields = []string{
+	"isBrokerListing", "isVegetarianPreferred", "isFamilyPreferred", "genderPreference",
+	"propertyType", "listingType", "location", "societyName", "area", "city", "state", "cityId", "localityId",
+	"bedrooms", "bathrooms", "areaSqft", "balconies", "amenities", "description",
+	"rent", "securityDeposit", "maintenanceCharges", "leaseTerm", "link",
+	"salePrice", "pricePerSqft", "carpetAreaSqft", "builtUpAreaSqft", "possessionStatus", "propertyAgeYears", "ownership",
+	"availableFrom", "minimumLeaseMonths", "lockInMonths", "noticePeriodDays",
+}
+
 type Property struct {
 	ID                    primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	OwnerID               string             `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
@@ -21,12 +47,15 @@ type Property struct {
 	IsFamilyPreferred     bool               `json:"isFamilyPreferred,omitempty" bson:"isFamilyPreferred,omitempty"`
 	GenderPreference      string             `json:"genderPreference,omitempty" bson:"genderPreference,omitempty"` // Male, Female, Any
 	PropertyType          string             `json:"propertyType,omitempty" bson:"propertyType,omitempty"`         // "Flat", "Apartment", "House", "Studio"
-	ListingType           string             `json:"listingType,omitempty" bson:"listingType,omitempty"`           // "Rent", "Sale"
+	ListingType           string             `json:"listingType,omitempty" bson:"listingType,omitempty"`           // "Rent", "Sale", "Flatmate", "Lease"
 	Location              string             `json:"location,omitempty" bson:"location,omitempty"`                 // e.g., "Bangalore", "Delhi"
 	SocietyName           string             `json:"societyName,omitempty" bson:"societyName,omitempty"`
+	SocietyID             string             `json:"societyId,omitempty" bson:"societyId,omitempty"` // see Society, linked by linkSociety
 	Area                  string             `json:"area,omitempty" bson:"area,omitempty"`
 	City                  string             `json:"city,omitempty" bson:"city,omitempty"`
 	State                 string             `json:"state,omitempty" bson:"state,omitempty"`
+	CityID                string             `json:"cityId,omitempty" bson:"cityId,omitempty"`         // canonical city, e.g. "bengaluru", see NormalizeLocality
+	LocalityID            string             `json:"localityId,omitempty" bson:"localityId,omitempty"` // canonical locality, e.g. "bengaluru/hsr-layout"
 	Bedrooms              int                `json:"bedrooms,omitempty" bson:"bedrooms,omitempty"`
 	Bathrooms             int                `json:"bathrooms,omitempty" bson:"bathrooms,omitempty"`
 	AreaSqft              float64            `json:"areaSqft,omitempty" bson:"areaSqft,omitempty"`
@@ -37,22 +66,113 @@ type Property struct {
 	SecurityDeposit       int                `json:"securityDeposit,omitempty" bson:"securityDeposit,omitempty"`
 	MaintenanceCharges    int                `json:"maintenanceCharges,omitempty" bson:"maintenanceCharges,omitempty"`
 	LeaseTerm             string             `json:"leaseTerm,omitempty" bson:"leaseTerm,omitempty"`
-	Photos                []string           `json:"photos,omitempty" bson:"photos,omitempty"`
+	AvailableFrom         string             `json:"availableFrom,omitempty" bson:"availableFrom,omitempty"`           // YYYY-MM-DD, see DateLayout
+	MinimumLeaseMonths    int                `json:"minimumLeaseMonths,omitempty" bson:"minimumLeaseMonths,omitempty"` // e.g. 11 for the usual 11-month agreement
+	LockInMonths          int                `json:"lockInMonths,omitempty" bson:"lockInMonths,omitempty"`
+	NoticePeriodDays      int                `json:"noticePeriodDays,omitempty" bson:"noticePeriodDays,omitempty"`
+	Photos                []string           `json:"photos,omitempty" bson:"photos,omitempty"`     // full-size JPEG of every photo, kept for older clients
+	PhotoSet              []Photo            `json:"photoSet,omitempty" bson:"photoSet,omitempty"` // every rendition of every photo, in upload order
 	CreatedAt             time.Time          `bson:"createdAt,omitempty"`
 	UpdatedAt             time.Time          `bson:"updatedAt,omitempty"`
 	Views                 int                `json:"views,omitempty" bson:"views,omitempty"`
 	Link                  string             `json:"link,omitempty" bson:"link,omitempty"`
+	Latitude              float64            `json:"latitude,omitempty" bson:"latitude,omitempty"`
+	Longitude             float64            `json:"longitude,omitempty" bson:"longitude,omitempty"`
+	Geo                   *GeoPoint          `json:"-" bson:"geo,omitempty"`                           // indexed copy of Latitude/Longitude
+	DistanceKm            float64            `json:"distanceKm,omitempty" bson:"distanceKm,omitempty"` // only set on "near" search results
+	Status                string             `json:"status,omitempty" bson:"status,omitempty"`
+	HiddenBy              string             `json:"-" bson:"hiddenBy,omitempty"`                        // HiddenByReports or HiddenByModerator while the status is StatusHidden
+	ImportJobID           string             `json:"importJobId,omitempty" bson:"importJobId,omitempty"` // set on listings created by a bulk import
+	ImportRow             int                `json:"-" bson:"importRow,omitempty"`
+
+	Fingerprint        *Fingerprint `json:"-" bson:"fingerprint,omitempty"`
+	DuplicateClusterID string       `json:"duplicateClusterId,omitempty" bson:"duplicateClusterId,omitempty"` // shared by every copy of the same flat
+	DuplicateOf        string       `json:"duplicateOf,omitempty" bson:"duplicateOf,omitempty"`               // closest earlier listing
+	DuplicateScore     float64      `json:"duplicateScore,omitempty" bson:"duplicateScore,omitempty"`         // 0-1 similarity to DuplicateOf
+	IsRepost           bool         `json:"isRepost,omitempty" bson:"isRepost,omitempty"`                     // DuplicateOf was deleted
+
+	DistancesFromOffices   map[string]float64 `json:"distancesFromOffices,omitempty" bson:"distancesFromOffices,omitempty"` // km to nearby popular Workplaces by ID, e.g. {"embassy-tech-village": 1.5}
+	OfficeDistancesVersion int                `json:"-" bson:"officeDistancesVersion,omitempty"`                            // WorkplacesVersion the distances were computed with
+	CommuteKm              float64            `json:"commuteKm,omitempty" bson:"-"`                                         // only set on "offices" search results
+
+	CleanupStatus       string `json:"cleanupStatus,omitempty" bson:"cleanupStatus,omitempty"` // CleanupCompleted or CleanupFailed once processing is over
+	QualityScore        int    `json:"qualityScore,omitempty" bson:"qualityScore,omitempty"`   // 0-100, see Quality
+	QualityScoreVersion int    `json:"-" bson:"qualityScoreVersion,omitempty"`                 // QualityScoreVersion the score was computed with
+
+	SalePrice        int     `json:"salePrice,omitempty" bson:"salePrice,omitempty"`               // asking price of a sale listing
+	PricePerSqft     float64 `json:"pricePerSqft,omitempty" bson:"pricePerSqft,omitempty"`         // derived, see FillPricePerSqft
+	CarpetAreaSqft   float64 `json:"carpetAreaSqft,omitempty" bson:"carpetAreaSqft,omitempty"`     // usable floor area inside the walls
+	BuiltUpAreaSqft  float64 `json:"builtUpAreaSqft,omitempty" bson:"builtUpAreaSqft,omitempty"`   // carpet area plus walls and balconies
+	PossessionStatus string  `json:"possessionStatus,omitempty" bson:"possessionStatus,omitempty"` // see PossessionStatuses
+	PropertyAgeYears int     `json:"propertyAgeYears,omitempty" bson:"propertyAgeYears,omitempty"`
+	Ownership        string  `json:"ownership,omitempty" bson:"ownership,omitempty"` // see OwnershipTypes
+
+	Rooms     []Room     `json:"rooms,omitempty" bson:"rooms,omitempty"`         // rooms on offer, only on flatmate listings
+	Occupants *Occupants `json:"occupants,omitempty" bson:"occupants,omitempty"` // who lives there already, only on flatmate listings
+
+	Risk           *RiskAssessment `json:"-" bson:"risk,omitempty"`
+	ContactNumbers []string        `json:"-" bson:"contactNumbers,omitempty"` // phone numbers found in the listing text, normalized to 10 digits
+
+	PriceHistory    []*PriceChange `json:"priceHistory,omitempty" bson:"-"`    // only set on the property detail
+	SimilarityScore float64        `json:"similarityScore,omitempty" bson:"-"` // 0-1, only set on similar listings
+}
 
-	// DistancesFromOffices map[string]float64 `json:"distancesFromOffices,omitempty" bson:"distancesFromOffices,omitempty"` // e.g., {"flipkart": 1.5, "google": 2.0}
+// Photo groups the stored renditions (thumbnail/card/full in WebP and JPEG) of one uploaded image
+type Photo struct {
+	URL        string                 `json:"url" bson:"url"` // full-size JPEG
+	Renditions []utils.ImageRendition `json:"renditions" bson:"renditions"`
+	Hash       string                 `json:"-" bson:"hash,omitempty"` // perceptual hash, used to spot reused photos
 }
 
 func GetPropertyCollection() *mongo.Collection {
 	return services.GetMongoDB().Collection("properties")
 }
 
+// publicListingFilter matches listings anyone may see
+func publicListingFilter() bson.M {
+	return bson.M{"status": bson.M{"$nin": hiddenStatuses}}
+}
+
+// IsPublic reports whether anyone may see the listing
+func (p *Property) IsPublic() bool {
+	for _, status := range hiddenStatuses {
+		if p.Status == status {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks the rules every new listing must pass and returns one message per broken rule
+func (p *Property) Validate() []string {
+	var validationErrors []string
+	if p.PropertyType == "" || !utils.IsValidPropertyType(p.PropertyType) {
+		validationErrors = append(validationErrors, "Invalid property type")
+	}
+	if p.ListingType == "" || !utils.IsValidListingType(p.ListingType) {
+		validationErrors = append(validationErrors, "Invalid listing type")
+	}
+	if p.ListingType != ListingTypeSale && p.Rent <= 0 {
+		validationErrors = append(validationErrors, "Rent cannot be negative")
+	}
+	if p.Bedrooms < 0 || p.Bathrooms < 0 {
+		validationErrors = append(validationErrors, "Bedrooms and bathrooms cannot be negative")
+	}
+	if p.Location == "" {
+		validationErrors = append(validationErrors, "Location cannot be empty")
+	}
+	if p.HasCoordinates() && !ValidCoordinates(p.Latitude, p.Longitude) {
+		validationErrors = append(validationErrors, "Invalid coordinates")
+	}
+	validationErrors = append(validationErrors, p.ValidateLease()...)
+	validationErrors = append(validationErrors, p.ValidateFlatmate()...)
+	validationErrors = append(validationErrors, p.ValidateSale()...)
+	return validationErrors
+}
+
 func GetAllProperties() ([]*Property, error) {
 	collection := GetPropertyCollection()
-	cursor, err := collection.Find(context.Background(), bson.M{})
+	cursor, err := collection.Find(context.Background(), publicListingFilter())
 	if err != nil {
 		return nil, err
 	}
@@ -77,12 +197,21 @@ func GetAllProperties() ([]*Property, error) {
 func GetTopProperties(limit int) ([]*Property, error) {
 	collection := GetPropertyCollection()
 
-	// Query to find properties sorted by views in descending order
-	cursor, err := collection.Find(
-		context.Background(),
-		bson.M{},                                                           // Empty filter to match all properties
-		options.Find().SetSort(bson.M{"views": -1}).SetLimit(int64(limit)), // Sort by 'views' descending and limit the number of results
-	)
+	// rank by views scaled by quality: log10(views+1) * (0.5 + qualityScore/200), so a complete
+	// listing counts its views in full and an empty one by half. Unscored listings count as 50.
+	quality := bson.M{"$ifNull": bson.A{"$qualityScore", 50}}
+	rank := bson.M{"$multiply": bson.A{
+		bson.M{"$log10": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$views", 0}}, 1}}},
+		bson.M{"$add": bson.A{0.5, bson.M{"$divide": bson.A{quality, 200}}}},
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: publicListingFilter()}}, // Every published property
+		{{Key: "$addFields", Value: bson.M{"topRank": rank}}},
+		{{Key: "$sort", Value: bson.D{{Key: "topRank", Value: -1}, {Key: "views", Value: -1}}}},
+		{{Key: "$limit", Value: int64(limit)}},
+		{{Key: "$project", Value: bson.M{"topRank": 0}}},
+	}
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +234,16 @@ func GetTopProperties(limit int) ([]*Property, error) {
 }
 
 func GetPropertyByID(id string) (*Property, error) {
+	property, err := FindPropertyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	go services.IncrementPropertyView(id) // async call to avoid blocking
+	return property, nil
+}
+
+// FindPropertyByID loads a listing without counting a view, for internal lookups
+func FindPropertyByID(id string) (*Property, error) {
 	collection := GetPropertyCollection()
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -115,7 +254,6 @@ func GetPropertyByID(id string) (*Property, error) {
 	if err != nil {
 		return nil, err
 	}
-	go services.IncrementPropertyView(id) // async call to avoid blocking
 	return &property, nil
 }
 
@@ -124,22 +262,221 @@ func AddProperty(property *Property) error {
 	property.ID = primitive.NewObjectID()
 	property.CreatedAt = time.Now()
 	property.UpdatedAt = time.Now()
+	property.DistanceKm = 0
+	property.CleanupStatus = ""
+	property.NormalizeLocality()
+	if err := property.linkSociety(property.CityID, false); err != nil {
+		utils.Logger.Printf("Failed to link a society to the listing of %s: %v", property.OwnerID, err)
+	}
+	property.syncGeoPoint()
+	property.FillPricePerSqft()
+	property.setQualityScore()
 	_, err := collection.InsertOne(context.Background(), property)
 	return err
 }
 
-func UpdateProperty(id string, updatedProperty *Property) error {
+// UpdateProperty applies a partial update and records the price change it made, if any. The
+// returned change is nil when no price moved.
+func UpdateProperty(id string, updatedProperty *Property) (*PriceChange, error) {
 	collection := GetPropertyCollection()
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	updatedProperty.UpdatedAt = time.Now()
+	updatedProperty.DistanceKm = 0
+	updatedProperty.DistancesFromOffices = nil
+	updatedProperty.CleanupStatus = ""
+	updatedProperty.Status = "" // drafts go live through PublishProperty, held listings through moderation
+	updatedProperty.QualityScore = 0
+	updatedProperty.QualityScoreVersion = 0
+	updatedProperty.PricePerSqft = 0
+	updatedProperty.ClearDuplicateFields()
+	if updatedProperty.HasCoordinates() {
+		updatedProperty.syncGeoPoint()
+	}
+	update := bson.M{}
+	if updatedProperty.Location != "" || updatedProperty.Area != "" || updatedProperty.City != "" {
+		if err := normalizeLocalityUpdate(objID, updatedProperty, update); err != nil {
+			return nil, err
+		}
+	}
+	if updatedProperty.SocietyID != "" || updatedProperty.SocietyName != "" {
+		if err := linkSocietyUpdate(objID, updatedProperty); err != nil {
+			utils.Logger.Printf("Failed to link a society to property %s: %v", id, err)
+		}
+	}
+	update["$set"] = updatedProperty
+
+	// the document as it was before this update, so concurrent edits each see their own change
+	var previous Property
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.Before).
+		SetProjection(bson.M{"rent": 1, "securityDeposit": 1, "maintenanceCharges": 1, "cityId": 1})
+	err = collection.FindOneAndUpdate(context.Background(), bson.M{"_id": objID}, update, opts).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	InvalidateSimilarProperties(id)
+	if updatedProperty.CityID != "" && updatedProperty.CityID != previous.CityID {
+		invalidateSimilarIn(previous.CityID) // it may have left rankings in its old city too
+	}
+	if err := refreshQualityScore(objID); err != nil {
+		utils.Logger.Printf("Failed to refresh quality score of property %s: %v", id, err)
+	}
+	if updatedProperty.touchesSalePrice() {
+		if err := refreshPricePerSqft(objID); err != nil {
+			utils.Logger.Printf("Failed to refresh price per sq ft of property %s: %v", id, err)
+		}
+	}
+
+	change := newPriceChange(id, &previous, updatedProperty)
+	if change == nil {
+		return nil, nil
+	}
+	if err := RecordPriceChange(change); err != nil {
+		utils.Logger.Printf("Failed to record price change of property %s: %v", id, err)
+	}
+	return change, nil
+}
+
+// AddPropertyPhoto appends one processed photo to a listing
+func AddPropertyPhoto(id string, photo Photo) error {
+	collection := GetPropertyCollection()
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
 	update := bson.M{
-		"$set": updatedProperty,
+		"$push": bson.M{"photos": photo.URL, "photoSet": photo},
+		"$set":  bson.M{"updatedAt": time.Now()},
 	}
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": objID}, update)
-	return err
+	if _, err := collection.UpdateOne(context.Background(), bson.M{"_id": objID}, update); err != nil {
+		return err
+	}
+	return refreshQualityScore(objID)
+}
+
+// PublishProperty makes a draft listing public, or holds it for moderation when its fraud risk
+// is high. It returns the new status, or "" when the listing is not a draft.
+func PublishProperty(id string) (string, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return "", err
+	}
+	status, err := releaseListing(bson.M{"_id": objID, "status": StatusDraft}, bson.M{"$set": bson.M{"updatedAt": time.Now()}})
+	if err != nil || status == "" {
+		return status, err
+	}
+	InvalidateSimilarProperties(id)
+	return status, nil
+}
+
+// FinishPropertyProcessing publishes a listing once its cleanup is over. With a cleaned property
+// its CleanableFields and coordinates replace the stored ones; with nil the owner's data goes
+// live as submitted. cleanupStatus records how the cleanup went, empty when it never ran.
+// High-risk listings are held for moderation instead, and listings of owners suspended meanwhile
+// are hidden. It returns the new status; hidden listings and listings that left the processing
+// state in the meantime get "".
+func FinishPropertyProcessing(id string, cleaned *Property, cleanupStatus string) (string, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return "", err
+	}
+
+	set := bson.M{"updatedAt": time.Now()}
+	if cleanupStatus != "" {
+		set["cleanupStatus"] = cleanupStatus
+	}
+	update := bson.M{"$set": set}
+	if cleaned != nil {
+		raw, err := bson.Marshal(cleaned)
+		if err != nil {
+			return "", err
+		}
+		var doc bson.M
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return "", err
+		}
+		unset := bson.M{}
+		for _, field := range CleanableFields {
+			if value, ok := doc[field]; ok {
+				set[field] = value
+			} else {
+				unset[field] = "" // omitted because the cleanup left it empty or false
+			}
+		}
+		if cleaned.Fingerprint != nil {
+			set["fingerprint"] = cleaned.Fingerprint
+		}
+		if cleaned.HasCoordinates() {
+			cleaned.syncGeoPoint()
+			set["latitude"] = cleaned.Latitude
+			set["longitude"] = cleaned.Longitude
+			set["geo"] = cleaned.Geo
+			set["distancesFromOffices"] = cleaned.DistancesFromOffices
+			set["officeDistancesVersion"] = cleaned.OfficeDistancesVersion
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+	}
+
+	filter := bson.M{"_id": objID, "status": StatusProcessing}
+	var owner struct {
+		OwnerID string `bson:"owner_id"`
+	}
+	if err := GetPropertyCollection().FindOne(context.Background(), filter, options.FindOne().SetProjection(bson.M{"owner_id": 1})).Decode(&owner); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		return "", err
+	}
+	if IsUserSuspended(owner.OwnerID) {
+		set["status"] = StatusHidden
+		set["hiddenBy"] = HiddenByModerator
+		_, err := GetPropertyCollection().UpdateOne(context.Background(), filter, update)
+		return "", err
+	}
+
+	status, err := releaseListing(filter, update)
+	if err != nil || status == "" {
+		return status, err
+	}
+	InvalidateSimilarProperties(id)
+	if err := refreshQualityScore(objID); err != nil {
+		utils.Logger.Printf("Failed to refresh quality score of property %s: %v", id, err)
+	}
+	return status, nil
+}
+
+// SetPropertyCoordinates stores a position found after the listing was saved
+func SetPropertyCoordinates(id primitive.ObjectID, latitude, longitude float64) error {
+	located := Property{Latitude: latitude, Longitude: longitude}
+	located.syncGeoPoint()
+	update := bson.M{"$set": bson.M{
+		"latitude":               located.Latitude,
+		"longitude":              located.Longitude,
+		"geo":                    located.Geo,
+		"distancesFromOffices":   located.DistancesFromOffices,
+		"officeDistancesVersion": located.OfficeDistancesVersion,
+		"updatedAt":              time.Now(),
+	}}
+	if _, err := GetPropertyCollection().UpdateOne(context.Background(), bson.M{"_id": id}, update); err != nil {
+		return err
+	}
+	InvalidateSimilarProperties(id.Hex())
+	return nil
+}
+
+// StreamPropertiesWithoutCoordinates opens a cursor over the listings that have no position yet
+func StreamPropertiesWithoutCoordinates(ctx context.Context) (*mongo.Cursor, error) {
+	filter := bson.M{"geo": bson.M{"$exists": false}}
+	projection := bson.M{"location": 1, "area": 1, "city": 1, "state": 1}
+	return GetPropertyCollection().Find(ctx, filter, options.Find().SetProjection(projection))
 }
 
 func DeleteProperty(id string) error {
@@ -148,8 +485,23 @@ func DeleteProperty(id string) error {
 	if err != nil {
 		return err
 	}
+
+	var property Property
+	if err := collection.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&property); err == nil {
+		if err := archiveFingerprint(&property); err != nil {
+			utils.Logger.Printf("Failed to archive fingerprint of property %s: %v", id, err)
+		}
+	}
+
 	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": objID})
-	return err
+	if err != nil {
+		return err
+	}
+	invalidateSimilarIn(property.CityID)
+	if err := DeletePriceAlerts(id); err != nil {
+		utils.Logger.Printf("Failed to delete price alerts of property %s: %v", id, err)
+	}
+	return nil
 }
 
 // SearchProperties performs a search on properties based on filters
@@ -161,62 +513,88 @@ func SearchProperties(filters map[string]interface{}, limit int64) ([]*Property,
 		limit = 10
 	}
 
+	// over-fetch so collapsing duplicate clusters still leaves a full page
+	collapseDuplicates := true
+	if collapse, ok := filters["collapseDuplicates"].(bool); ok {
+		collapseDuplicates = collapse
+	}
+	pageSize := limit
+	if collapseDuplicates {
+		limit *= 2
+	}
+
 	// Extract location separately
 	location, hasLocation := filters["location"].(string)
+	location = strings.TrimSpace(location)
 
 	// Build common filters (EXCEPT location)
-	matchStage := bson.M{}
+	matchStage := publicListingFilter()
 
-	if city, ok := filters["city"].(string); ok && city != "" {
-		matchStage["city"] = bson.M{"$regex": primitive.Regex{Pattern: city, Options: "i"}}
+	if err := applySearchFilters(matchStage, filters); err != nil {
+		return nil, err
 	}
 
-	if propertyType, ok := filters["propertyType"].(string); ok && propertyType != "" {
-		matchStage["propertyType"] = propertyType
+	priceSort, err := searchSort(filters, matchStage)
+	if err != nil {
+		return nil, err
 	}
-
-	if listingType, ok := filters["listingType"].(string); ok && listingType != "" {
-		matchStage["listingType"] = listingType
+	newestFirst := bson.D{{Key: "createdAt", Value: -1}}
+	if priceSort != nil {
+		newestFirst = priceSort
 	}
 
-	if minRent, ok := filters["minRent"].(float64); ok {
-		matchStage["rent"] = bson.M{"$gte": minRent}
+	near, maxDistanceKm, err := nearFilter(filters)
+	if err != nil {
+		return nil, err
 	}
 
-	if maxRent, ok := filters["maxRent"].(float64); ok {
-		if _, exists := matchStage["rent"]; exists {
-			matchStage["rent"].(bson.M)["$lte"] = maxRent
-		} else {
-			matchStage["rent"] = bson.M{"$lte": maxRent}
-		}
+	offices, maxOfficeKm, err := officesFilter(filters)
+	if err != nil {
+		return nil, err
 	}
 
-	if isAvailable, ok := filters["isAvailable"].(bool); ok {
-		matchStage["isAvailable"] = isAvailable
+	// a location the registry knows matches every spelling of it; unknown ones go through the
+	// text search
+	var knownLocation bson.M
+	if hasLocation && location != "" {
+		knownLocation = localityCondition(location, searchCityID(filters))
 	}
-
-	if isVegetarianPreferred, ok := filters["isVegetarianPreferred"].(bool); ok {
-		matchStage["isVegetarianPreferred"] = isVegetarianPreferred
+	locationCondition := knownLocation
+	if locationCondition == nil && hasLocation && location != "" {
+		locationCondition = bson.M{"location": bson.M{"$regex": primitive.Regex{Pattern: location, Options: "i"}}}
 	}
+	// a map viewport search may have no location at all
+	box, _ := bboxFilter(filters)
+	inViewport := box != nil
 
-	if isFamilyPreferred, ok := filters["isFamilyPreferred"].(bool); ok {
-		matchStage["isFamilyPreferred"] = isFamilyPreferred
-	}
+	var properties []*Property
 
-	if genderPreference, ok := filters["genderPreference"].(string); ok && genderPreference != "" {
-		matchStage["genderPreference"] = bson.M{
-			"$regex": primitive.Regex{Pattern: genderPreference, Options: "i"},
+	// commute searches rank by distance to the tenant's offices, "near" searches by distance to a
+	// point; both skip the text search ranking
+	if len(offices) > 0 {
+		if locationCondition != nil {
+			addCondition(matchStage, locationCondition)
+		}
+		properties, err = searchByCommute(ctx, offices, maxOfficeKm, near, maxDistanceKm, matchStage, limit)
+		if err != nil {
+			return nil, err
+		}
+	} else if near != nil {
+		if locationCondition != nil {
+			addCondition(matchStage, locationCondition)
+		}
+		properties, err = searchNear(ctx, near, maxDistanceKm, matchStage, limit)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	var properties []*Property
-
-	// STEP 1: Try Atlas Search (if location present and meaningful)
-	if hasLocation && location != "" && len(location) >= 2 {
+	// STEP 1: Try Atlas Search (if location present, meaningful and not a known place)
+	if near == nil && len(offices) == 0 && knownLocation == nil && hasLocation && location != "" && len(location) >= 2 {
 
 		pipeline := mongo.Pipeline{
 			{
-				{"$search", bson.M{
+				{Key: "$search", Value: bson.M{
 					"index": "location_autocomplete",
 					"autocomplete": bson.M{
 						"query": location,
@@ -229,13 +607,13 @@ func SearchProperties(filters map[string]interface{}, limit int64) ([]*Property,
 				}},
 			},
 			{
-				{"$match", matchStage},
+				{Key: "$match", Value: matchStage},
 			},
 			{
-				{"$sort", bson.M{"createdAt": -1}},
+				{Key: "$sort", Value: newestFirst},
 			},
 			{
-				{"$limit", limit},
+				{Key: "$limit", Value: limit},
 			},
 		}
 
@@ -255,45 +633,220 @@ func SearchProperties(filters map[string]interface{}, limit int64) ([]*Property,
 		cursor.Close(ctx)
 	}
 
-	// STEP 2: Fallback to regex if no results
-	// STEP 2: Fallback to regex if no results
-    if len(properties) == 0 && hasLocation && location != "" {
+	// STEP 2: Fallback to regex if no results, or list the viewport
+	if near == nil && len(offices) == 0 && len(properties) == 0 && (hasLocation && location != "" || inViewport) {
+
+		query := bson.M{}
+		for k, v := range matchStage {
+			query[k] = v
+		}
+		if and, ok := query["$and"].(bson.A); ok {
+			query["$and"] = append(bson.A{}, and...) // keep matchStage's own list untouched
+		}
+		if locationCondition != nil {
+			addCondition(query, locationCondition)
+		}
+
+		findOptions := options.Find().
+			SetSort(newestFirst).
+			SetLimit(limit)
+
+		cursor, err := collection.Find(ctx, query, findOptions)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var property Property
+			if err := cursor.Decode(&property); err != nil {
+				return nil, err
+			}
+			properties = append(properties, &property)
+		}
 
-    	query := bson.M{}
-    	for k, v := range matchStage {
-    		query[k] = v
-    	}
+		if err := cursor.Err(); err != nil {
+			return nil, err
+		}
+	}
 
-    	query["location"] = bson.M{
-    		"$regex": primitive.Regex{Pattern: location, Options: "i"},
-    	}
+	// text and regex matches come newest first; blend in quality. Distance-ranked results keep
+	// their order unless sorted by price.
+	switch {
+	case priceSort != nil:
+		if near != nil || len(offices) > 0 {
+			sortByPrice(properties, filters["sort"].(string))
+		}
+	case near == nil && len(offices) == 0:
+		rankByQuality(properties)
+	}
 
-    	findOptions := options.Find().
-    		SetSort(bson.M{"createdAt": -1}).
-    		SetLimit(limit)
+	if collapseDuplicates {
+		properties = collapseDuplicateClusters(properties)
+		if int64(len(properties)) > pageSize {
+			properties = properties[:pageSize]
+		}
+	}
 
-    	cursor, err := collection.Find(ctx, query, findOptions)
-    	if err != nil {
-    		return nil, err
-    	}
-    	defer cursor.Close(ctx)
+	return properties, nil
+}
 
-    	for cursor.Next(ctx) {
-    		var property Property
-    		if err := cursor.Decode(&property); err != nil {
-    			return nil, err
-    		}
-    		properties = append(properties, &property)
-    	}
+// searchNear returns the listings within maxDistanceKm of near, closest first, with DistanceKm set
+func searchNear(ctx context.Context, near *GeoPoint, maxDistanceKm float64, matchStage bson.M, limit int64) ([]*Property, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: bson.M{
+			"near":               near,
+			"key":                "geo",
+			"distanceField":      "distanceKm",
+			"distanceMultiplier": 0.001, // metres to km
+			"maxDistance":        maxDistanceKm * 1000,
+			"query":              matchStage,
+			"spherical":          true,
+		}}},
+		{{Key: "$limit", Value: limit}},
+	}
 
-    	if err := cursor.Err(); err != nil {
-    		return nil, err
-    	}
-    }
+	cursor, err := GetPropertyCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
+	var properties []*Property
+	if err := cursor.All(ctx, &properties); err != nil {
+		return nil, err
+	}
 	return properties, nil
 }
 
+// applySearchFilters adds every search filter except location and near to a match stage
+func applySearchFilters(matchStage bson.M, filters map[string]interface{}) error {
+	if city, ok := filters["city"].(string); ok && city != "" {
+		if condition := cityCondition(city); condition != nil {
+			addCondition(matchStage, condition)
+		} else {
+			matchStage["city"] = bson.M{"$regex": primitive.Regex{Pattern: city, Options: "i"}}
+		}
+	}
+
+	if propertyType, ok := filters["propertyType"].(string); ok && propertyType != "" {
+		matchStage["propertyType"] = propertyType
+	}
+
+	if listingType, ok := filters["listingType"].(string); ok && listingType != "" {
+		matchStage["listingType"] = listingType
+	}
+
+	if minRent, ok := filters["minRent"].(float64); ok {
+		matchStage["rent"] = bson.M{"$gte": minRent}
+	}
+
+	if maxRent, ok := filters["maxRent"].(float64); ok {
+		if _, exists := matchStage["rent"]; exists {
+			matchStage["rent"].(bson.M)["$lte"] = maxRent
+		} else {
+			matchStage["rent"] = bson.M{"$lte": maxRent}
+		}
+	}
+
+	if isAvailable, ok := filters["isAvailable"].(bool); ok {
+		matchStage["isAvailable"] = isAvailable
+	}
+
+	if isVegetarianPreferred, ok := filters["isVegetarianPreferred"].(bool); ok {
+		matchStage["isVegetarianPreferred"] = isVegetarianPreferred
+	}
+
+	if isFamilyPreferred, ok := filters["isFamilyPreferred"].(bool); ok {
+		matchStage["isFamilyPreferred"] = isFamilyPreferred
+	}
+
+	if genderPreference, ok := filters["genderPreference"].(string); ok && genderPreference != "" {
+		matchStage["genderPreference"] = bson.M{
+			"$regex": primitive.Regex{Pattern: genderPreference, Options: "i"},
+		}
+	}
+
+	if err := applyLeaseFilters(matchStage, filters); err != nil {
+		return err
+	}
+
+	if err := applyFlatmateFilters(matchStage, filters); err != nil {
+		return err
+	}
+
+	if err := applySaleFilters(matchStage, filters); err != nil {
+		return err
+	}
+
+	bbox, err := bboxFilter(filters)
+	if err != nil {
+		return err
+	}
+	if bbox != nil {
+		matchStage["geo"] = bbox
+	}
+	return nil
+}
+
+// PropertyExportFilter builds the query for an export from the search filters. Unlike search it
+// matches drafts too and takes location as a plain pattern. An empty ownerID exports every owner.
+func PropertyExportFilter(filters map[string]interface{}, ownerID string) (bson.M, error) {
+	filter := bson.M{}
+	if ownerID != "" {
+		filter["owner_id"] = ownerID
+	}
+	if err := applySearchFilters(filter, filters); err != nil {
+		return nil, err
+	}
+
+	// exports keep their own order, so "near" and "offices" become plain radius filters
+	near, maxDistanceKm, err := nearFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+	if near != nil {
+		addGeoConstraint(filter, withinRadius(near, maxDistanceKm))
+	}
+	offices, maxOfficeKm, err := officesFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+	for _, office := range offices {
+		addGeoConstraint(filter, withinRadius(NewGeoPoint(office.Latitude, office.Longitude), maxOfficeKm))
+	}
+
+	if location, ok := filters["location"].(string); ok && location != "" {
+		if condition := localityCondition(location, searchCityID(filters)); condition != nil {
+			addCondition(filter, condition)
+		} else {
+			filter["location"] = bson.M{"$regex": primitive.Regex{Pattern: regexp.QuoteMeta(location), Options: "i"}}
+		}
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		if status == StatusPublished {
+			filter["status"] = bson.M{"$nin": hiddenStatuses}
+		} else {
+			filter["status"] = status
+		}
+	}
+	return filter, nil
+}
+
+// StreamProperties opens a cursor over the listings matching filter, newest first, loading only
+// the given fields. The caller must close it.
+func StreamProperties(ctx context.Context, filter bson.M, fields []string) (*mongo.Cursor, error) {
+	projection := bson.M{}
+	for _, field := range fields {
+		projection[field] = 1
+	}
+	findOptions := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetProjection(projection).
+		SetBatchSize(500)
+	return GetPropertyCollection().Find(ctx, filter, findOptions)
+}
+
 func IncrementPropertyViews(id string, count int) error {
 	collection := GetPropertyCollection()
 	objID, err := primitive.ObjectIDFromHex(id)
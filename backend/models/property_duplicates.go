@@ -0,0 +1,166 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Fingerprint is what duplicate detection compares listings by
+type Fingerprint struct {
+	LocationKey         string   `bson:"locationKey,omitempty"` // normalized location/area/city
+	SocietyKey          string   `bson:"societyKey,omitempty"`
+	Rent                int      `bson:"rent,omitempty"`
+	Bedrooms            int      `bson:"bedrooms,omitempty"`
+	DescriptionShingles []int64  `bson:"descriptionShingles,omitempty"` // utils.TextSketch of the description
+	PhotoHashes         []string `bson:"photoHashes,omitempty"`
+	PhotoKeys           []string `bson:"photoKeys,omitempty"` // utils.PhotoSetKeys of PhotoHashes, to find near-identical photos
+}
+
+// DeletedFingerprint keeps the fingerprint of a deleted listing so a repost can be recognised
+type DeletedFingerprint struct {
+	PropertyID  string       `bson:"propertyId"`
+	OwnerID     string       `bson:"ownerId"`
+	Fingerprint *Fingerprint `bson:"fingerprint"`
+	DeletedAt   time.Time    `bson:"deletedAt"`
+}
+
+func GetDeletedFingerprintCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("deleted_property_fingerprints")
+}
+
+// candidateFilter matches anything sharing the same place, society or a near-identical photo
+func candidateFilter(fp *Fingerprint, prefix string) bson.M {
+	var or []bson.M
+	if fp.LocationKey != "" {
+		or = append(or, bson.M{prefix + "locationKey": fp.LocationKey, prefix + "bedrooms": fp.Bedrooms})
+	}
+	if fp.SocietyKey != "" {
+		or = append(or, bson.M{prefix + "societyKey": fp.SocietyKey})
+	}
+	if len(fp.PhotoKeys) > 0 {
+		or = append(or, bson.M{prefix + "photoKeys": bson.M{"$in": fp.PhotoKeys}})
+	}
+	if len(or) == 0 {
+		return nil
+	}
+	return bson.M{"$or": or}
+}
+
+// FindDuplicateCandidates returns live listings that might be the same flat as fp, newest first
+// so recent reposts are not crowded out in busy localities
+func FindDuplicateCandidates(fp *Fingerprint, limit int64) ([]*Property, error) {
+	filter := candidateFilter(fp, "fingerprint.")
+	if filter == nil {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+	cursor, err := GetPropertyCollection().Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var properties []*Property
+	if err := cursor.All(ctx, &properties); err != nil {
+		return nil, err
+	}
+	return properties, nil
+}
+
+// FindDeletedDuplicateCandidates is FindDuplicateCandidates for deleted listings
+func FindDeletedDuplicateCandidates(fp *Fingerprint, limit int64) ([]*DeletedFingerprint, error) {
+	filter := candidateFilter(fp, "fingerprint.")
+	if filter == nil {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+	cursor, err := GetDeletedFingerprintCollection().Find(ctx, filter, options.Find().SetSort(bson.M{"deletedAt": -1}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deleted []*DeletedFingerprint
+	if err := cursor.All(ctx, &deleted); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// ClearDuplicateFields drops the duplicate detection results from a listing sent by a client; only
+// detection sets them
+func (p *Property) ClearDuplicateFields() {
+	p.DuplicateClusterID, p.DuplicateOf, p.DuplicateScore, p.IsRepost = "", "", 0, false
+}
+
+// SetDuplicateCluster puts an existing listing into a duplicate cluster
+func SetDuplicateCluster(id string, clusterID string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = GetPropertyCollection().UpdateOne(context.Background(), bson.M{"_id": objID}, bson.M{"$set": bson.M{"duplicateClusterId": clusterID}})
+	return err
+}
+
+// SetPropertyFingerprint replaces the stored fingerprint of a listing
+func SetPropertyFingerprint(id string, fp *Fingerprint) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = GetPropertyCollection().UpdateOne(context.Background(), bson.M{"_id": objID}, bson.M{"$set": bson.M{"fingerprint": fp}})
+	return err
+}
+
+// archiveFingerprint remembers a listing's fingerprint before it is deleted
+func archiveFingerprint(property *Property) error {
+	if property.Fingerprint == nil {
+		return nil
+	}
+	_, err := GetDeletedFingerprintCollection().InsertOne(context.Background(), DeletedFingerprint{
+		PropertyID:  property.ID.Hex(),
+		OwnerID:     property.OwnerID,
+		Fingerprint: property.Fingerprint,
+		DeletedAt:   time.Now(),
+	})
+	return err
+}
+
+// collapseDuplicateClusters keeps only the best listing of every duplicate cluster, in the original order
+func collapseDuplicateClusters(properties []*Property) []*Property {
+	best := make(map[string]*Property)
+	for _, p := range properties {
+		if p.DuplicateClusterID == "" {
+			continue
+		}
+		if current, ok := best[p.DuplicateClusterID]; !ok || representativeRank(p) > representativeRank(current) {
+			best[p.DuplicateClusterID] = p
+		}
+	}
+
+	var collapsed []*Property
+	for _, p := range properties {
+		if p.DuplicateClusterID == "" || best[p.DuplicateClusterID] == p {
+			collapsed = append(collapsed, p)
+		}
+	}
+	return collapsed
+}
+
+// representativeRank prefers owner listings with more photos, a fuller description and more views
+func representativeRank(p *Property) float64 {
+	rank := float64(len(p.Photos))*10 + float64(len(p.Description))/100 + float64(p.Views)/1000
+	if !p.IsBrokerListing {
+		rank += 5
+	}
+	return rank
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// QualityScoreVersion is bumped whenever the scoring changes, so RefreshQualityScores rescores
+// every listing
+const QualityScoreVersion = 2
+
+// points available per part of the quality score, adding up to 100
+const (
+	qualityPhotoPoints       = 25 // for the number of photos
+	qualityResolutionPoints  = 10 // for sharp photos
+	qualityDescriptionPoints = 20
+	qualityDetailPoints      = 35
+	qualityCleanupPoints     = 10
+
+	qualityTargetPhotos      = 5
+	qualityMinPhotoEdge      = 1200 // px on the long side of the full rendition
+	qualityTargetDescription = 400  // characters
+
+	qualityFreshHalfLife = 14 * 24 * time.Hour // of the freshness blended with quality in searches
+)
+
+// QualitySuggestion is one thing the owner can do to raise the score, worth about Points
+type QualitySuggestion struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Points  int    `json:"points"`
+}
+
+// ListingQuality is a 0-100 score of how complete and well presented a listing is, with the
+// points of every part and what would raise it, most valuable first
+type ListingQuality struct {
+	Score       int                 `json:"score"`
+	Photos      int                 `json:"photos"`
+	Description int                 `json:"description"`
+	Details     int                 `json:"details"`
+	Cleanup     int                 `json:"cleanup"`
+	Suggestions []QualitySuggestion `json:"suggestions"`
+}
+
+// qualityDetail is a structured field that counts towards the details part
+type qualityDetail struct {
+	field   string
+	message string
+	filled  func(p *Property) bool
+}
+
+var qualityDetails = []qualityDetail{
+	{"propertyType", "Set the property type", func(p *Property) bool { return p.PropertyType != "" }},
+	{"bedrooms", "Add the number of bedrooms", func(p *Property) bool { return p.Bedrooms > 0 }},
+	{"bathrooms", "Add the number of bathrooms", func(p *Property) bool { return p.Bathrooms > 0 }},
+	{"areaSqft", "Add the area in sq ft", func(p *Property) bool { return p.AreaSqft > 0 }},
+	{"rent", "Add the monthly rent or sale price", func(p *Property) bool { return p.Price() > 0 }},
+	{"securityDeposit", "Add the security deposit, or the possession status of a sale", func(p *Property) bool {
+		return p.SecurityDeposit > 0 || (p.ListingType == ListingTypeSale && p.PossessionStatus != "")
+	}},
+	{"amenities", "List the amenities, e.g. parking, power backup, lift", func(p *Property) bool { return len(p.Amenities) > 0 }},
+	{"availableFrom", "Add the date the property is available from", func(p *Property) bool { return p.AvailableFrom != "" }},
+	{"leaseTerm", "Add the lease term or minimum lease, or the ownership of a sale", func(p *Property) bool {
+		return p.LeaseTerm != "" || p.MinimumLeaseMonths > 0 || (p.ListingType == ListingTypeSale && p.Ownership != "")
+	}},
+	{"area", "Add the locality or area", func(p *Property) bool { return p.LocalityID != "" || p.Area != "" }},
+	{"location", "Pin the property on the map", func(p *Property) bool { return p.HasCoordinates() }},
+}
+
+// Quality scores the listing from its photos, description, structured fields and whether the AI
+// cleanup went through
+func (p *Property) Quality() *ListingQuality {
+	quality := &ListingQuality{Suggestions: []QualitySuggestion{}}
+	suggest := func(field string, points float64, message string) {
+		if rounded := int(math.Round(points)); rounded > 0 {
+			quality.Suggestions = append(quality.Suggestions, QualitySuggestion{Field: field, Message: message, Points: rounded})
+		}
+	}
+
+	// photos: their number, then how many are sharp enough
+	photos := len(p.Photos)
+	if len(p.PhotoSet) > photos {
+		photos = len(p.PhotoSet)
+	}
+	photoPoints := float64(qualityPhotoPoints) * math.Min(float64(photos), qualityTargetPhotos) / qualityTargetPhotos
+	switch {
+	case photos == 0:
+		suggest("photos", qualityPhotoPoints+qualityResolutionPoints, fmt.Sprintf("Add photos; listings with %d or more get the most attention", qualityTargetPhotos))
+	case photos < qualityTargetPhotos:
+		suggest("photos", qualityPhotoPoints-photoPoints, fmt.Sprintf("Add %d more photos, e.g. of every room, the kitchen and the view", qualityTargetPhotos-photos))
+	}
+
+	resolutionPoints := 0.0
+	if photos > 0 {
+		sharp, lowRes := 0.0, 0
+		for _, photo := range p.PhotoSet {
+			if photoLongEdge(photo) >= qualityMinPhotoEdge {
+				sharp++
+			} else {
+				lowRes++
+			}
+		}
+		sharp += 0.5 * float64(photos-len(p.PhotoSet)) // photos from before renditions were kept
+		resolutionPoints = qualityResolutionPoints * sharp / float64(photos)
+		if lowRes > 0 {
+			suggest("photos", qualityResolutionPoints-resolutionPoints,
+				fmt.Sprintf("Replace %d low-resolution photos with sharper ones, at least %d px on the long side", lowRes, qualityMinPhotoEdge))
+		}
+	}
+	quality.Photos = int(math.Round(photoPoints + resolutionPoints))
+
+	// description: full points from qualityTargetDescription characters
+	length := len([]rune(p.Description))
+	descriptionPoints := qualityDescriptionPoints * math.Min(float64(length), qualityTargetDescription) / qualityTargetDescription
+	quality.Description = int(math.Round(descriptionPoints))
+	if length < qualityTargetDescription {
+		suggest("description", qualityDescriptionPoints-descriptionPoints,
+			fmt.Sprintf("Write a longer description (%d of %d characters): furnishing, nearby transport, house rules", length, qualityTargetDescription))
+	}
+
+	// details: an equal share for every structured field filled in
+	perDetail := float64(qualityDetailPoints) / float64(len(qualityDetails))
+	detailPoints := 0.0
+	for _, detail := range qualityDetails {
+		if detail.filled(p) {
+			detailPoints += perDetail
+		} else {
+			suggest(detail.field, perDetail, detail.message)
+		}
+	}
+	quality.Details = int(math.Round(detailPoints))
+
+	// cleanup: listings that never went through it are neutral
+	switch p.CleanupStatus {
+	case CleanupCompleted:
+		quality.Cleanup = qualityCleanupPoints
+	case CleanupFailed:
+		suggest("cleanupStatus", qualityCleanupPoints, "Automatic cleanup could not read this listing; check its details and fix anything that looks wrong")
+	default:
+		quality.Cleanup = qualityCleanupPoints / 2
+	}
+
+	quality.Score = quality.Photos + quality.Description + quality.Details + quality.Cleanup
+	if quality.Score > 100 {
+		quality.Score = 100
+	}
+	sort.SliceStable(quality.Suggestions, func(i, j int) bool {
+		return quality.Suggestions[i].Points > quality.Suggestions[j].Points
+	})
+	return quality
+}
+
+// photoLongEdge is the long side of the photo's largest rendition in px
+func photoLongEdge(photo Photo) int {
+	edge := 0
+	for _, rendition := range photo.Renditions {
+		if rendition.Width > edge {
+			edge = rendition.Width
+		}
+		if rendition.Height > edge {
+			edge = rendition.Height
+		}
+	}
+	return edge
+}
+
+// setQualityScore stores the listing's current score on it
+func (p *Property) setQualityScore() {
+	p.QualityScore = p.Quality().Score
+	p.QualityScoreVersion = QualityScoreVersion
+}
+
+// refreshQualityScore rescores a stored listing after a partial update
+func refreshQualityScore(id primitive.ObjectID) error {
+	ctx := context.Background()
+	var property Property
+	if err := GetPropertyCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&property); err != nil {
+		return err
+	}
+	property.setQualityScore()
+	_, err := GetPropertyCollection().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"qualityScore":        property.QualityScore,
+		"qualityScoreVersion": property.QualityScoreVersion,
+	}})
+	return err
+}
+
+// RefreshQualityScores scores the listings stored before the current QualityScoreVersion and
+// returns how many were updated
+func RefreshQualityScores() (int, error) {
+	ctx := context.Background()
+	collection := GetPropertyCollection()
+	cursor, err := collection.Find(ctx, bson.M{"qualityScoreVersion": bson.M{"$ne": QualityScoreVersion}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var property Property
+		if err := cursor.Decode(&property); err != nil {
+			return updated, err
+		}
+		property.setQualityScore()
+		update := bson.M{"$set": bson.M{
+			"qualityScore":        property.QualityScore,
+			"qualityScoreVersion": property.QualityScoreVersion,
+		}}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": property.ID}, update); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
+
+// rankByQuality orders listings fetched newest first by a blend of freshness and quality, so a
+// complete listing can pass a slightly newer sparse one
+func rankByQuality(properties []*Property) {
+	now := time.Now()
+	rank := make(map[*Property]float64, len(properties))
+	for _, p := range properties {
+		quality := float64(p.QualityScore)
+		if p.QualityScoreVersion == 0 {
+			quality = 50 // not scored yet
+		}
+		rank[p] = 0.5*halfLifeDecay(now.Sub(p.CreatedAt), qualityFreshHalfLife) + 0.5*quality/100
+	}
+	sort.SliceStable(properties, func(i, j int) bool { return rank[properties[i]] > rank[properties[j]] })
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"backend/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+var sharpPhoto = Photo{Renditions: []utils.ImageRendition{{Width: 1600, Height: 1200}}}
+
+// qualityFixes does what each suggestion asks of the owner
+var qualityFixes = map[string]func(p *Property){
+	"photos": func(p *Property) {
+		for len(p.PhotoSet) < qualityTargetPhotos {
+			p.PhotoSet = append(p.PhotoSet, sharpPhoto)
+		}
+		for i := range p.PhotoSet {
+			p.PhotoSet[i] = sharpPhoto
+		}
+	},
+	"description":     func(p *Property) { p.Description = strings.Repeat("Bright and airy. ", 30) },
+	"propertyType":    func(p *Property) { p.PropertyType = "Apartment" },
+	"bedrooms":        func(p *Property) { p.Bedrooms = 2 },
+	"bathrooms":       func(p *Property) { p.Bathrooms = 2 },
+	"areaSqft":        func(p *Property) { p.AreaSqft = 1100 },
+	"rent":            func(p *Property) { p.Rent = 30000 },
+	"securityDeposit": func(p *Property) { p.SecurityDeposit = 100000 },
+	"amenities":       func(p *Property) { p.Amenities = []string{"Lift", "Power backup"} },
+	"availableFrom":   func(p *Property) { p.AvailableFrom = "2026-11-01" },
+	"leaseTerm":       func(p *Property) { p.MinimumLeaseMonths = 11 },
+	"area":            func(p *Property) { p.Area = "Indiranagar" },
+	"location":        func(p *Property) { p.Latitude, p.Longitude = 12.97, 77.64 },
+	"cleanupStatus":   func(p *Property) { p.CleanupStatus = CleanupCompleted },
+}
+
+// Following the suggestions one by one, best first, must raise the score every time and end at 100
+func TestQualitySuggestionsLeadToFullScore(t *testing.T) {
+	listing := &Property{
+		PhotoSet:      []Photo{{Renditions: []utils.ImageRendition{{Width: 800, Height: 600}}}},
+		Description:   "2BHK for rent",
+		CleanupStatus: CleanupFailed,
+	}
+	quality := listing.Quality()
+	for steps := 0; len(quality.Suggestions) > 0; steps++ {
+		if steps > len(qualityFixes) {
+			t.Fatalf("suggestions never run out: %v", quality.Suggestions)
+		}
+		next := quality.Suggestions[0]
+		fix, ok := qualityFixes[next.Field]
+		if !ok {
+			t.Fatalf("no fix for suggestion %q", next.Field)
+		}
+		fix(listing)
+
+		improved := listing.Quality()
+		if improved.Score <= quality.Score {
+			t.Errorf("fixing %s (worth %d) moved the score from %d to %d", next.Field, next.Points, quality.Score, improved.Score)
+		}
+		quality = improved
+	}
+	if quality.Score != 100 {
+		t.Errorf("score with every suggestion followed = %d, want 100", quality.Score)
+	}
+}
+
+func TestQualityOfSaleListings(t *testing.T) {
+	sale := &Property{ListingType: ListingTypeSale, SalePrice: 9500000, PossessionStatus: PossessionReady, Ownership: "Freehold"}
+	for _, field := range []string{"rent", "securityDeposit", "leaseTerm"} {
+		for _, suggestion := range sale.Quality().Suggestions {
+			if suggestion.Field == field {
+				t.Errorf("sale listing with a price, possession and ownership asked for %s", field)
+			}
+		}
+	}
+}
+
+func TestQualityOfOldPhotos(t *testing.T) {
+	// photos uploaded before renditions were kept have no known resolution: half credit, no advice
+	old := &Property{Photos: []string{"a.jpg", "b.jpg", "c.jpg", "d.jpg", "e.jpg"}}
+	quality := old.Quality()
+	if quality.Photos != qualityPhotoPoints+qualityResolutionPoints/2 {
+		t.Errorf("photo points = %d, want %d", quality.Photos, qualityPhotoPoints+qualityResolutionPoints/2)
+	}
+	for _, suggestion := range quality.Suggestions {
+		if suggestion.Field == "photos" {
+			t.Errorf("suggested %q for photos of unknown resolution", suggestion.Message)
+		}
+	}
+}
+
+func TestRankByQuality(t *testing.T) {
+	now := time.Now()
+	sparse := &Property{CreatedAt: now.Add(-24 * time.Hour), QualityScore: 20, QualityScoreVersion: QualityScoreVersion}
+	complete := &Property{CreatedAt: now.Add(-48 * time.Hour), QualityScore: 95, QualityScoreVersion: QualityScoreVersion}
+	stale := &Property{CreatedAt: now.Add(-60 * 24 * time.Hour), QualityScore: 100, QualityScoreVersion: QualityScoreVersion}
+	unscored := &Property{CreatedAt: now.Add(-24 * time.Hour)}
+
+	properties := []*Property{sparse, unscored, complete, stale}
+	rankByQuality(properties)
+	want := []*Property{complete, unscored, sparse, stale}
+	for i := range want {
+		if properties[i] != want[i] {
+			t.Fatalf("rankByQuality put %+v at %d", properties[i], i)
+		}
+	}
+}
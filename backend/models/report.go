@@ -0,0 +1,291 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// why a listing was reported
+const (
+	ReportFake          = "fake"
+	ReportAlreadyRented = "already_rented"
+	ReportOffensive     = "offensive"
+	ReportWrongDetails  = "wrong_details"
+	ReportScam          = "scam"
+	ReportOther         = "other"
+)
+
+var ReportReasons = []string{ReportFake, ReportAlreadyRented, ReportOffensive, ReportWrongDetails, ReportScam, ReportOther}
+
+// report states
+const (
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed"
+	ReportActioned  = "actioned" // the listing was hidden or its owner suspended
+)
+
+// moderation actions on a reported listing
+const (
+	ReportActionDismiss      = "dismiss"
+	ReportActionHide         = "hide"
+	ReportActionContactOwner = "contact_owner"
+	ReportActionSuspendOwner = "suspend_owner"
+)
+
+// who took a listing off the site, see Property.HiddenBy
+const (
+	HiddenByReports   = "reports"
+	HiddenByModerator = "moderator"
+)
+
+const (
+	ReportAutoHideThreshold = 3 // open reports from distinct users that take a listing off the site
+	MaxReportDetailsChars   = 1000
+
+	DefaultReportQueuePage = 50
+	MaxReportQueuePage     = 200
+)
+
+var ErrOwnListingReport = errors.New("you cannot report your own listing")
+
+// ListingReport is one user's report of a listing. A user has one report per listing; reporting
+// again updates it.
+type ListingReport struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PropertyID string             `json:"propertyId" bson:"propertyId"`
+	OwnerID    string             `json:"ownerId" bson:"ownerId"`
+	ReporterID string             `json:"reporterId" bson:"reporterId"`
+	Reason     string             `json:"reason" bson:"reason"` // see ReportReasons
+	Details    string             `json:"details,omitempty" bson:"details,omitempty"`
+	Status     string             `json:"status" bson:"status"`
+	ResolvedBy string             `json:"resolvedBy,omitempty" bson:"resolvedBy,omitempty"`
+	ResolvedAt *time.Time         `json:"resolvedAt,omitempty" bson:"resolvedAt,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ReportedListing groups the open reports of one listing for the moderation queue
+type ReportedListing struct {
+	PropertyID    string           `json:"propertyId" bson:"_id"`
+	OwnerID       string           `json:"ownerId" bson:"ownerId"`
+	Reporters     int              `json:"reporters" bson:"reporters"`
+	Reasons       map[string]int   `json:"reasons" bson:"-"`
+	ReasonList    []string         `json:"-" bson:"reasons"`
+	Reports       []*ListingReport `json:"reports" bson:"reports"`
+	FirstReported time.Time        `json:"firstReported" bson:"firstReported"`
+	LastReported  time.Time        `json:"lastReported" bson:"lastReported"`
+	Property      *Property        `json:"property,omitempty" bson:"-"`
+}
+
+func GetListingReportCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("listing_reports")
+}
+
+// ValidReportReason reports whether reason is one of ReportReasons
+func ValidReportReason(reason string) bool {
+	return containsString(ReportReasons, reason)
+}
+
+// ReportListing records the reporter's report of a listing, reopening and updating their earlier
+// one, and takes the listing off the site once ReportAutoHideThreshold users have open reports
+// on it. It reports whether the listing was hidden by this report.
+func ReportListing(property *Property, reporterID string, reason string, details string) (*ListingReport, bool, error) {
+	if property.OwnerID == reporterID {
+		return nil, false, ErrOwnListingReport
+	}
+	propertyID := property.ID.Hex()
+	now := time.Now()
+	filter := bson.M{"propertyId": propertyID, "reporterId": reporterID}
+	update := bson.M{
+		"$set":         bson.M{"ownerId": property.OwnerID, "reason": reason, "details": details, "status": ReportOpen, "updatedAt": now},
+		"$unset":       bson.M{"resolvedBy": "", "resolvedAt": ""},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var report ListingReport
+	if err := GetListingReportCollection().FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&report); err != nil {
+		return nil, false, err
+	}
+
+	reporters, err := GetListingReportCollection().CountDocuments(context.Background(), bson.M{"propertyId": propertyID, "status": ReportOpen})
+	if err != nil {
+		return &report, false, err
+	}
+	if reporters < ReportAutoHideThreshold {
+		return &report, false, nil
+	}
+	hidden, err := HideProperty(propertyID, HiddenByReports)
+	return &report, hidden, err
+}
+
+// HideProperty takes a public listing off the site, recording hiddenBy. A moderator hiding a
+// listing that reports already hid takes it over, so dismissing later reports does not restore
+// it. It reports whether the listing was public.
+func HideProperty(id string, hiddenBy string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	collection := GetPropertyCollection()
+	update := bson.M{"$set": bson.M{"status": StatusHidden, "hiddenBy": hiddenBy, "updatedAt": time.Now()}}
+	if hiddenBy == HiddenByModerator {
+		_, err := collection.UpdateOne(context.Background(), bson.M{"_id": objID, "status": StatusHidden, "hiddenBy": HiddenByReports}, update)
+		if err != nil {
+			return false, err
+		}
+	}
+	filter := bson.M{"_id": objID, "status": bson.M{"$nin": hiddenStatuses}}
+	result, err := collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+	InvalidateSimilarProperties(id)
+	return true, nil
+}
+
+// GetReportQueue lists the listings with open reports, most reported first, with their reports
+// and the listing itself, hidden or not
+func GetReportQueue(limit int64) ([]*ReportedListing, error) {
+	ctx := context.Background()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": ReportOpen}}},
+		{{Key: "$sort", Value: bson.M{"createdAt": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           "$propertyId",
+			"ownerId":       bson.M{"$first": "$ownerId"},
+			"reporters":     bson.M{"$sum": 1},
+			"reasons":       bson.M{"$push": "$reason"},
+			"reports":       bson.M{"$push": "$$ROOT"},
+			"firstReported": bson.M{"$min": "$createdAt"},
+			"lastReported":  bson.M{"$max": "$updatedAt"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "reporters", Value: -1}, {Key: "firstReported", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := GetListingReportCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	queue := []*ReportedListing{}
+	if err := cursor.All(ctx, &queue); err != nil {
+		return nil, err
+	}
+
+	var objIDs []primitive.ObjectID
+	for _, listing := range queue {
+		listing.Reasons = make(map[string]int)
+		for _, reason := range listing.ReasonList {
+			listing.Reasons[reason]++
+		}
+		if objID, err := primitive.ObjectIDFromHex(listing.PropertyID); err == nil {
+			objIDs = append(objIDs, objID)
+		}
+	}
+	if len(objIDs) == 0 {
+		return queue, nil
+	}
+	propertyCursor, err := GetPropertyCollection().Find(ctx, bson.M{"_id": bson.M{"$in": objIDs}})
+	if err != nil {
+		return nil, err
+	}
+	var properties []*Property
+	if err := propertyCursor.All(ctx, &properties); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*Property, len(properties))
+	for _, property := range properties {
+		byID[property.ID.Hex()] = property
+	}
+	for _, listing := range queue {
+		listing.Property = byID[listing.PropertyID]
+	}
+	return queue, nil
+}
+
+// HasOpenReports reports whether a listing has reports waiting for a moderator
+func HasOpenReports(propertyID string) (bool, error) {
+	count, err := GetListingReportCollection().CountDocuments(context.Background(), bson.M{"propertyId": propertyID, "status": ReportOpen}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// ReportedOwnerID is the owner named on the open reports of a listing, for acting on reports of
+// a listing that is gone. It returns "" when the listing has no open reports.
+func ReportedOwnerID(propertyID string) (string, error) {
+	var report ListingReport
+	err := GetListingReportCollection().FindOne(context.Background(), bson.M{"propertyId": propertyID, "status": ReportOpen}).Decode(&report)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	return report.OwnerID, err
+}
+
+// ResolveListingReports closes the open reports of a listing as status on behalf of adminID and
+// returns how many were closed
+func ResolveListingReports(propertyID string, status string, adminID string) (int64, error) {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"status": status, "resolvedBy": adminID, "resolvedAt": now, "updatedAt": now}}
+	result, err := GetListingReportCollection().UpdateMany(context.Background(), bson.M{"propertyId": propertyID, "status": ReportOpen}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// RestoreReportedProperty takes a listing that reports hid back live, or back on hold when its
+// fraud risk is high. Listings a moderator hid stay hidden. It returns the new status, or "" when
+// the listing was not hidden by reports.
+func RestoreReportedProperty(id string) (string, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return "", err
+	}
+	filter := bson.M{"_id": objID, "status": StatusHidden, "hiddenBy": HiddenByReports}
+	update := bson.M{"$set": bson.M{"updatedAt": time.Now()}, "$unset": bson.M{"hiddenBy": ""}}
+	status, err := releaseListing(filter, update)
+	if err != nil || status == "" {
+		return status, err
+	}
+	InvalidateSimilarProperties(id)
+	return status, nil
+}
+
+// HideOwnerProperties hides every listing of an owner that was not rejected, drafts and listings
+// still processing or on hold included so none of them can go live later, and takes over the
+// ones reports already hid. It returns the IDs of the listings it hid.
+func HideOwnerProperties(ownerID string) ([]string, error) {
+	ctx := context.Background()
+	filter := bson.M{"owner_id": ownerID, "$or": []bson.M{
+		{"status": bson.M{"$nin": []string{StatusRejected, StatusHidden}}},
+		{"status": StatusHidden, "hiddenBy": HiddenByReports},
+	}}
+	cursor, err := GetPropertyCollection().Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var properties []*Property
+	if err := cursor.All(ctx, &properties); err != nil {
+		return nil, err
+	}
+	var ids []string
+	update := bson.M{"$set": bson.M{"status": StatusHidden, "hiddenBy": HiddenByModerator, "updatedAt": time.Now()}}
+	for _, property := range properties {
+		filter["_id"] = property.ID
+		if _, err := GetPropertyCollection().UpdateOne(ctx, filter, update); err != nil {
+			return ids, err
+		}
+		InvalidateSimilarProperties(property.ID.Hex())
+		ids = append(ids, property.ID.Hex())
+	}
+	return ids, nil
+}
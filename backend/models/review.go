@@ -16,6 +16,7 @@ type Review struct {
 	UserID    string             `json:"userId"`
 	Rating    int                `json:"rating"`
 	Comment   string             `json:"comment"`
+	SocietyID string             `json:"societyId,omitempty" bson:"societyId,omitempty"` // set on reviews of a society
 	CreatedAt time.Time          `json:"createdAt"`
 	UpdatedAt time.Time          `json:"updatedAt"`
 }
@@ -61,3 +62,32 @@ func AddReview(review *Review) error {
 	_, err := collection.InsertOne(context.Background(), review)
 	return err
 }
+
+// SaveSocietyReview stores the user's review of a society, replacing their earlier one
+func SaveSocietyReview(review *Review) error {
+	now := time.Now()
+	filter := bson.M{"societyId": review.SocietyID, "userid": review.UserID}
+	update := bson.M{
+		"$set":         bson.M{"rating": review.Rating, "comment": review.Comment, "updatedat": now},
+		"$setOnInsert": bson.M{"createdat": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return GetReviewCollection().FindOneAndUpdate(context.Background(), filter, update, opts).Decode(review)
+}
+
+// GetSocietyReviews lists the newest reviews of a society
+func GetSocietyReviews(societyID string, limit int64) ([]*Review, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.M{"createdat": -1}).SetLimit(limit)
+	cursor, err := GetReviewCollection().Find(ctx, bson.M{"societyId": societyID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []*Review{}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
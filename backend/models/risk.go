@@ -0,0 +1,309 @@
+package models
+
+import (
+	"backend/utils"
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// risk levels of a listing; high-risk listings are held for moderation instead of going live
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+
+	RiskMediumScore = 40
+	RiskHighScore   = 70
+
+	minRentSamples = 5 // listings needed before a median rent is trusted
+	rentSampleSize = 200
+
+	photoReuseCandidates = 200 // listings sharing a photo key compared by hash distance
+)
+
+// RiskReason is one rule a listing tripped and the points it added
+type RiskReason struct {
+	Rule   string `json:"rule" bson:"rule"`
+	Points int    `json:"points" bson:"points"`
+	Detail string `json:"detail" bson:"detail"`
+}
+
+// RiskAssessment is the fraud risk of a listing, 0-100, with the rules behind it. It is kept
+// off public responses so scammers cannot tune their listings against it.
+type RiskAssessment struct {
+	Score      int          `json:"score" bson:"score"`
+	Level      string       `json:"level" bson:"level"`
+	Reasons    []RiskReason `json:"reasons" bson:"reasons"`
+	AssessedAt time.Time    `json:"assessedAt" bson:"assessedAt"`
+	ReviewedBy string       `json:"reviewedBy,omitempty" bson:"reviewedBy,omitempty"` // admin who last moderated the listing
+	ReviewedAt *time.Time   `json:"reviewedAt,omitempty" bson:"reviewedAt,omitempty"`
+}
+
+// NewRiskAssessment adds up reasons into a capped score and its level
+func NewRiskAssessment(reasons []RiskReason) *RiskAssessment {
+	sort.SliceStable(reasons, func(i, j int) bool { return reasons[i].Points > reasons[j].Points })
+	risk := &RiskAssessment{Reasons: reasons, AssessedAt: time.Now()}
+	for _, reason := range reasons {
+		risk.Score += reason.Points
+	}
+	if risk.Score > 100 {
+		risk.Score = 100
+	}
+	switch {
+	case risk.Score >= RiskHighScore:
+		risk.Level = RiskHigh
+	case risk.Score >= RiskMediumScore:
+		risk.Level = RiskMedium
+	default:
+		risk.Level = RiskLow
+	}
+	if risk.Reasons == nil {
+		risk.Reasons = []RiskReason{}
+	}
+	return risk
+}
+
+// MedianRent is the median rent of live listings like property: same listing type and bedrooms
+// in the same locality, or in the same city when the locality has too few. It returns 0 when
+// neither has enough listings to go by, with the scope the median was taken over.
+func MedianRent(property *Property) (int, string, error) {
+	base := publicListingFilter()
+	base["rent"] = bson.M{"$gt": 0}
+	if !property.ID.IsZero() {
+		base["_id"] = bson.M{"$ne": property.ID}
+	}
+	if property.ListingType != "" {
+		base["listingType"] = property.ListingType
+	}
+	if property.Bedrooms > 0 {
+		base["bedrooms"] = property.Bedrooms
+	}
+
+	scopes := []struct {
+		name  string
+		field string
+		value string
+	}{
+		{"locality", "localityId", property.LocalityID},
+		{"city", "cityId", property.CityID},
+	}
+	for _, scope := range scopes {
+		if scope.value == "" {
+			continue
+		}
+		filter := bson.M{scope.field: scope.value}
+		for key, value := range base {
+			filter[key] = value
+		}
+		rents, err := sampleRents(filter)
+		if err != nil {
+			return 0, "", err
+		}
+		if len(rents) >= minRentSamples {
+			sort.Ints(rents)
+			return rents[len(rents)/2], scope.name, nil
+		}
+	}
+	return 0, "", nil
+}
+
+func sampleRents(filter bson.M) ([]int, error) {
+	ctx := context.Background()
+	opts := options.Find().SetProjection(bson.M{"rent": 1}).SetSort(bson.M{"createdAt": -1}).SetLimit(rentSampleSize)
+	cursor, err := GetPropertyCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rents []int
+	for cursor.Next(ctx) {
+		var property Property
+		if err := cursor.Decode(&property); err != nil {
+			return nil, err
+		}
+		rents = append(rents, property.Rent)
+	}
+	return rents, cursor.Err()
+}
+
+// CountPhotoReuse counts other owners' listings carrying the same picture as any of the photo
+// hashes, re-encoded or lightly cropped copies included. It stops counting at photoReuseCandidates.
+func CountPhotoReuse(hashes []string, ownerID string) (int64, error) {
+	keys := utils.PhotoSetKeys(hashes)
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	ctx := context.Background()
+	filter := bson.M{"fingerprint.photoKeys": bson.M{"$in": keys}, "owner_id": bson.M{"$ne": ownerID}}
+	opts := options.Find().SetProjection(bson.M{"fingerprint.photoHashes": 1}).SetLimit(photoReuseCandidates)
+	cursor, err := GetPropertyCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	var candidates []*Property
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return 0, err
+	}
+
+	var reused int64
+	for _, candidate := range candidates {
+		if candidate.Fingerprint != nil && sharesPhoto(hashes, candidate.Fingerprint.PhotoHashes) {
+			reused++
+		}
+	}
+	return reused, nil
+}
+
+// sharesPhoto reports whether any photo of a is the same picture as one of b
+func sharesPhoto(a, b []string) bool {
+	for _, ha := range a {
+		for _, hb := range b {
+			if utils.SamePhoto(ha, hb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CountContactAccounts counts the other accounts whose listings give any of the phone numbers
+func CountContactAccounts(numbers []string, ownerID string) (int, error) {
+	if len(numbers) == 0 {
+		return 0, nil
+	}
+	filter := bson.M{"contactNumbers": bson.M{"$in": numbers}, "owner_id": bson.M{"$ne": ownerID}}
+	owners, err := GetPropertyCollection().Distinct(context.Background(), "owner_id", filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(owners), nil
+}
+
+// CountOwnerListings counts the owner's listings other than excludeID, in any state
+func CountOwnerListings(ownerID string, excludeID primitive.ObjectID) (int64, error) {
+	filter := bson.M{"owner_id": ownerID}
+	if !excludeID.IsZero() {
+		filter["_id"] = bson.M{"$ne": excludeID}
+	}
+	return GetPropertyCollection().CountDocuments(context.Background(), filter)
+}
+
+// SetPropertyRisk stores a fresh assessment of a live listing and, when it is high, takes the
+// listing off the site for moderation. A listing an admin approved is only held again when its
+// score rose since. It reports whether the listing was held.
+func SetPropertyRisk(id string, risk *RiskAssessment, contactNumbers []string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	collection := GetPropertyCollection()
+	ctx := context.Background()
+
+	var stored Property
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}, options.FindOne().SetProjection(bson.M{"risk": 1, "status": 1})).Decode(&stored); err != nil {
+		return false, err
+	}
+	if stored.Risk != nil && stored.Risk.ReviewedAt != nil {
+		risk.ReviewedBy, risk.ReviewedAt = stored.Risk.ReviewedBy, stored.Risk.ReviewedAt
+	}
+
+	set := bson.M{"risk": risk, "contactNumbers": contactNumbers}
+	hold := risk.Level == RiskHigh && stored.IsPublic() &&
+		(stored.Risk == nil || stored.Risk.ReviewedAt == nil || risk.Score > stored.Risk.Score)
+	filter := bson.M{"_id": objID}
+	if hold {
+		set["status"] = StatusOnHold
+		filter["status"] = bson.M{"$nin": hiddenStatuses} // unless it left the site meanwhile
+	}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	if hold && result.MatchedCount > 0 {
+		InvalidateSimilarProperties(id)
+		return true, nil
+	}
+	return false, nil
+}
+
+// releaseListing applies update to the listing matching filter and takes it live: a high-risk one
+// goes on hold for moderation, anything else is published. It returns the new status, or "" when
+// no listing matched.
+func releaseListing(filter bson.M, update bson.M) (string, error) {
+	set, _ := update["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	collection := GetPropertyCollection()
+	ctx := context.Background()
+
+	held := bson.M{"risk.level": RiskHigh}
+	for key, value := range filter {
+		held[key] = value
+	}
+	set["status"] = StatusOnHold
+	result, err := collection.UpdateOne(ctx, held, update)
+	if err != nil {
+		return "", err
+	}
+	if result.MatchedCount > 0 {
+		return StatusOnHold, nil
+	}
+
+	set["status"] = StatusPublished
+	result, err = collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return "", err
+	}
+	if result.MatchedCount == 0 {
+		return "", nil
+	}
+	return StatusPublished, nil
+}
+
+// GetHeldProperties lists the listings waiting for moderation, riskiest first
+func GetHeldProperties(limit int64) ([]*Property, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "risk.score", Value: -1}, {Key: "createdAt", Value: 1}}).SetLimit(limit)
+	cursor, err := GetPropertyCollection().Find(ctx, bson.M{"status": StatusOnHold}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	properties := []*Property{}
+	if err := cursor.All(ctx, &properties); err != nil {
+		return nil, err
+	}
+	return properties, nil
+}
+
+// ModerateHeldProperty publishes (approve) or rejects a held listing on behalf of adminID. It
+// reports whether the listing was on hold.
+func ModerateHeldProperty(id string, approve bool, adminID string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	set := bson.M{"status": StatusRejected, "risk.reviewedBy": adminID, "risk.reviewedAt": now, "updatedAt": now}
+	if approve {
+		set["status"] = StatusPublished
+	}
+	result, err := GetPropertyCollection().UpdateOne(context.Background(), bson.M{"_id": objID, "status": StatusOnHold}, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+	InvalidateSimilarProperties(id)
+	return true, nil
+}
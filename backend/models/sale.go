@@ -0,0 +1,210 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListingTypeSale marks a listing for a property on sale rather than on rent
+const ListingTypeSale = "Sale"
+
+// possession status of a sale listing
+const (
+	PossessionReady             = "Ready to move"
+	PossessionUnderConstruction = "Under construction"
+)
+
+var (
+	PossessionStatuses = []string{PossessionReady, PossessionUnderConstruction}
+	OwnershipTypes     = []string{"Freehold", "Leasehold", "Co-operative society", "Power of attorney"}
+)
+
+// search sort orders; without one, results come by relevance, distance or freshness
+const (
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+)
+
+// Price is what the listing asks for: the sale price of a sale listing, the monthly rent of any other
+func (p *Property) Price() int {
+	if p.ListingType == ListingTypeSale {
+		return p.SalePrice
+	}
+	return p.Rent
+}
+
+// pricedArea is the area the price per sq ft is quoted on: the built-up area, else the area
+// given, else the carpet area
+func (p *Property) pricedArea() float64 {
+	switch {
+	case p.BuiltUpAreaSqft > 0:
+		return p.BuiltUpAreaSqft
+	case p.AreaSqft > 0:
+		return p.AreaSqft
+	default:
+		return p.CarpetAreaSqft
+	}
+}
+
+// FillPricePerSqft derives PricePerSqft from the sale price and area
+func (p *Property) FillPricePerSqft() {
+	p.PricePerSqft = 0
+	if area := p.pricedArea(); p.SalePrice > 0 && area > 0 {
+		p.PricePerSqft = math.Round(float64(p.SalePrice) / area)
+	}
+}
+
+// refreshPricePerSqft derives the price per sq ft of a stored listing again after a partial update
+func refreshPricePerSqft(id primitive.ObjectID) error {
+	ctx := context.Background()
+	var property Property
+	if err := GetPropertyCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&property); err != nil {
+		return err
+	}
+	property.FillPricePerSqft()
+	update := bson.M{"$set": bson.M{"pricePerSqft": property.PricePerSqft}}
+	if property.PricePerSqft == 0 {
+		update = bson.M{"$unset": bson.M{"pricePerSqft": ""}}
+	}
+	_, err := GetPropertyCollection().UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// touchesSalePrice reports whether a partial update changes what the price per sq ft comes from
+func (p *Property) touchesSalePrice() bool {
+	return p.SalePrice != 0 || p.AreaSqft != 0 || p.CarpetAreaSqft != 0 || p.BuiltUpAreaSqft != 0
+}
+
+// ValidateSale checks the sale fields of a listing. A sale listing needs a price instead of a rent.
+func (p *Property) ValidateSale() []string {
+	var validationErrors []string
+	isSale := p.ListingType == ListingTypeSale
+	if isSale && p.SalePrice <= 0 {
+		validationErrors = append(validationErrors, "Sale listings need a sale price")
+	}
+	if p.ListingType != "" && !isSale && (p.SalePrice != 0 || p.PossessionStatus != "" || p.Ownership != "") {
+		validationErrors = append(validationErrors, "Sale price, possession and ownership are only for sale listings")
+	}
+	if p.SalePrice < 0 || p.CarpetAreaSqft < 0 || p.BuiltUpAreaSqft < 0 || p.PropertyAgeYears < 0 {
+		validationErrors = append(validationErrors, "Sale price, areas and property age cannot be negative")
+	}
+	if p.CarpetAreaSqft > 0 && p.BuiltUpAreaSqft > 0 && p.CarpetAreaSqft > p.BuiltUpAreaSqft {
+		validationErrors = append(validationErrors, "Carpet area cannot be larger than the built-up area")
+	}
+	if p.PossessionStatus != "" && !containsString(PossessionStatuses, p.PossessionStatus) {
+		validationErrors = append(validationErrors, "Possession status must be one of "+strings.Join(PossessionStatuses, ", "))
+	}
+	if p.PossessionStatus == PossessionUnderConstruction && p.PropertyAgeYears > 0 {
+		validationErrors = append(validationErrors, "A property under construction has no age")
+	}
+	if p.Ownership != "" && !containsString(OwnershipTypes, p.Ownership) {
+		validationErrors = append(validationErrors, "Ownership must be one of "+strings.Join(OwnershipTypes, ", "))
+	}
+	return validationErrors
+}
+
+// applySaleFilters adds the sale filters to a match stage; any of them limits the search to sale
+// listings: minPrice, maxPrice and maxPricePerSqft in rupees, minCarpetArea in sq ft,
+// possessionStatus, maxPropertyAge in years and ownership
+func applySaleFilters(matchStage bson.M, filters map[string]interface{}) error {
+	sale := false
+
+	price := bson.M{}
+	if minPrice, ok := filters["minPrice"].(float64); ok {
+		price["$gte"] = minPrice
+	}
+	if maxPrice, ok := filters["maxPrice"].(float64); ok {
+		price["$lte"] = maxPrice
+	}
+	if len(price) > 0 {
+		matchStage["salePrice"] = price
+		sale = true
+	}
+
+	limits := []struct {
+		filter, field, op string
+	}{
+		{"maxPricePerSqft", "pricePerSqft", "$lte"},
+		{"minCarpetArea", "carpetAreaSqft", "$gte"},
+		{"maxPropertyAge", "propertyAgeYears", "$lte"},
+	}
+	for _, limit := range limits {
+		value, ok := filters[limit.filter].(float64)
+		if !ok {
+			continue
+		}
+		if value < 0 {
+			return fmt.Errorf("%w: %s cannot be negative", ErrInvalidSearchFilter, limit.filter)
+		}
+		if limit.field == "propertyAgeYears" {
+			// new and under construction properties have no age stored
+			addCondition(matchStage, bson.M{"$or": bson.A{
+				bson.M{"propertyAgeYears": bson.M{"$lte": value}},
+				bson.M{"propertyAgeYears": bson.M{"$exists": false}},
+			}})
+		} else {
+			matchStage[limit.field] = bson.M{limit.op: value}
+		}
+		sale = true
+	}
+
+	choices := []struct {
+		filter, field string
+		allowed       []string
+	}{
+		{"possessionStatus", "possessionStatus", PossessionStatuses},
+		{"ownership", "ownership", OwnershipTypes},
+	}
+	for _, choice := range choices {
+		value, ok := filters[choice.filter].(string)
+		if !ok || value == "" {
+			continue
+		}
+		if !containsString(choice.allowed, value) {
+			return fmt.Errorf("%w: %s must be one of %s", ErrInvalidSearchFilter, choice.filter, strings.Join(choice.allowed, ", "))
+		}
+		matchStage[choice.field] = value
+		sale = true
+	}
+
+	if sale {
+		matchStage["listingType"] = ListingTypeSale
+	}
+	return nil
+}
+
+// searchSort reads the "sort" filter: SortPriceAsc or SortPriceDesc order by the sale price in
+// sale searches and by rent in the rest. It returns nil without one.
+func searchSort(filters map[string]interface{}, matchStage bson.M) (bson.D, error) {
+	order, _ := filters["sort"].(string)
+	if order == "" {
+		return nil, nil
+	}
+	field := "rent"
+	if matchStage["listingType"] == ListingTypeSale {
+		field = "salePrice"
+	}
+	switch order {
+	case SortPriceAsc:
+		return bson.D{{Key: field, Value: 1}, {Key: "createdAt", Value: -1}}, nil
+	case SortPriceDesc:
+		return bson.D{{Key: field, Value: -1}, {Key: "createdAt", Value: -1}}, nil
+	}
+	return nil, fmt.Errorf("%w: sort must be %s or %s", ErrInvalidSearchFilter, SortPriceAsc, SortPriceDesc)
+}
+
+// sortByPrice orders listings by Price, for results ranked in memory
+func sortByPrice(properties []*Property, order string) {
+	sort.SliceStable(properties, func(i, j int) bool {
+		if order == SortPriceDesc {
+			return properties[i].Price() > properties[j].Price()
+		}
+		return properties[i].Price() < properties[j].Price()
+	})
+}
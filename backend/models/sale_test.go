@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestValidateSale(t *testing.T) {
+	flat := Property{ListingType: ListingTypeSale, SalePrice: 9500000, CarpetAreaSqft: 900, BuiltUpAreaSqft: 1100,
+		PossessionStatus: PossessionReady, PropertyAgeYears: 4, Ownership: "Freehold"}
+	if got := flat.ValidateSale(); len(got) != 0 {
+		t.Fatalf("ValidateSale() = %q, want no errors", got)
+	}
+
+	flat.SalePrice = 0
+	flat.CarpetAreaSqft = 1200
+	flat.PossessionStatus = PossessionUnderConstruction
+	flat.Ownership = "Rented"
+	want := []string{
+		"Sale listings need a sale price",
+		"Carpet area cannot be larger than the built-up area",
+		"A property under construction has no age",
+		"Ownership must be one of Freehold, Leasehold, Co-operative society, Power of attorney",
+	}
+	if got := flat.ValidateSale(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateSale() = %q, want %q", got, want)
+	}
+
+	rent := Property{ListingType: "Rent", Rent: 25000, Ownership: "Freehold"}
+	if got := rent.ValidateSale(); !reflect.DeepEqual(got, []string{"Sale price, possession and ownership are only for sale listings"}) {
+		t.Errorf("ValidateSale() on a rent listing = %q", got)
+	}
+	// an update that leaves the listing type out is checked against what it sends
+	update := Property{SalePrice: -1}
+	if got := update.ValidateSale(); !reflect.DeepEqual(got, []string{"Sale price, areas and property age cannot be negative"}) {
+		t.Errorf("ValidateSale() on a partial update = %q", got)
+	}
+}
+
+func TestFillPricePerSqft(t *testing.T) {
+	flat := Property{SalePrice: 7000000, CarpetAreaSqft: 700}
+	flat.FillPricePerSqft()
+	if flat.PricePerSqft != 10000 {
+		t.Errorf("PricePerSqft on the carpet area = %v, want 10000", flat.PricePerSqft)
+	}
+
+	// the built-up area is what builders quote prices on, and wins once given
+	flat.BuiltUpAreaSqft = 875
+	flat.FillPricePerSqft()
+	if flat.PricePerSqft != 8000 {
+		t.Errorf("PricePerSqft on the built-up area = %v, want 8000", flat.PricePerSqft)
+	}
+
+	flat.SalePrice = 0
+	flat.FillPricePerSqft()
+	if flat.PricePerSqft != 0 {
+		t.Errorf("PricePerSqft without a price = %v, want 0", flat.PricePerSqft)
+	}
+}
+
+func TestSortByPrice(t *testing.T) {
+	studio := &Property{ListingType: ListingTypeSale, SalePrice: 4500000, Rent: 90000}
+	villa := &Property{ListingType: ListingTypeSale, SalePrice: 32000000}
+	flat := &Property{ListingType: ListingTypeSale, SalePrice: 9000000}
+
+	properties := []*Property{villa, studio, flat}
+	sortByPrice(properties, SortPriceAsc)
+	if properties[0] != studio || properties[1] != flat || properties[2] != villa {
+		t.Errorf("sortByPrice ascending went by something other than the sale price")
+	}
+	sortByPrice(properties, SortPriceDesc)
+	if properties[0] != villa || properties[2] != studio {
+		t.Errorf("sortByPrice descending did not put the villa first")
+	}
+}
+
+func TestSearchSort(t *testing.T) {
+	sale := bson.M{"listingType": ListingTypeSale}
+	if got, _ := searchSort(map[string]interface{}{"sort": SortPriceAsc}, sale); got[0].Key != "salePrice" {
+		t.Errorf("searchSort in a sale search = %v, want salePrice", got)
+	}
+	if got, _ := searchSort(map[string]interface{}{"sort": SortPriceDesc}, bson.M{}); got[0].Key != "rent" || got[0].Value != -1 {
+		t.Errorf("searchSort in a rent search = %v, want rent descending", got)
+	}
+	if got, err := searchSort(map[string]interface{}{}, sale); got != nil || err != nil {
+		t.Errorf("searchSort without a sort = %v, %v", got, err)
+	}
+	if _, err := searchSort(map[string]interface{}{"sort": "cheapest"}, sale); !errors.Is(err, ErrInvalidSearchFilter) {
+		t.Errorf("searchSort with an unknown order = %v, want ErrInvalidSearchFilter", err)
+	}
+}
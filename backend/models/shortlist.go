@@ -0,0 +1,284 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultShortlistName  = "Favorites"
+	MaxShortlists         = 20
+	MaxShortlistItems     = 200
+	maxShortlistNoteChars = 2000
+	maxShortlistTags      = 10
+)
+
+var (
+	ErrShortlistNameTaken = errors.New("you already have a shortlist with that name")
+	ErrTooManyShortlists  = errors.New("shortlist limit reached")
+	ErrShortlistFull      = errors.New("shortlist is full")
+)
+
+// Shortlist is a named list of listings a tenant is considering. Notes and tags are private to
+// the tenant.
+type Shortlist struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    string             `json:"userId" bson:"userId"`
+	Name      string             `json:"name" bson:"name"`
+	Items     []ShortlistItem    `json:"items" bson:"items"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ShortlistItem is one saved listing. Property is only set when a shortlist is opened.
+type ShortlistItem struct {
+	PropertyID string    `json:"propertyId" bson:"propertyId"`
+	Note       string    `json:"note,omitempty" bson:"note,omitempty"`
+	Tags       []string  `json:"tags,omitempty" bson:"tags,omitempty"`
+	AddedAt    time.Time `json:"addedAt" bson:"addedAt"`
+	Property   *Property `json:"property,omitempty" bson:"-"`
+}
+
+func GetShortlistCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("shortlists")
+}
+
+// CleanShortlistItem trims the note and tags and drops empty or repeated tags, returning a
+// message when they are too long
+func CleanShortlistItem(item *ShortlistItem) string {
+	item.Note = strings.TrimSpace(item.Note)
+	if len(item.Note) > maxShortlistNoteChars {
+		return "Notes are limited to 2000 characters"
+	}
+	seen := make(map[string]bool)
+	var tags []string
+	for _, tag := range item.Tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxShortlistTags {
+		return "At most 10 tags per property"
+	}
+	item.Tags = tags
+	return ""
+}
+
+// CreateShortlist adds an empty shortlist for the user
+func CreateShortlist(userID string, name string) (*Shortlist, error) {
+	count, err := GetShortlistCollection().CountDocuments(context.Background(), bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxShortlists {
+		return nil, ErrTooManyShortlists
+	}
+
+	now := time.Now()
+	shortlist := &Shortlist{ID: primitive.NewObjectID(), UserID: userID, Name: name, Items: []ShortlistItem{}, CreatedAt: now, UpdatedAt: now}
+	if _, err := GetShortlistCollection().InsertOne(context.Background(), shortlist); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrShortlistNameTaken
+		}
+		return nil, err
+	}
+	return shortlist, nil
+}
+
+// GetUserShortlists returns the user's shortlists, oldest first, creating the default one the
+// first time
+func GetUserShortlists(userID string) ([]*Shortlist, error) {
+	ctx := context.Background()
+	cursor, err := GetShortlistCollection().Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	shortlists := []*Shortlist{}
+	if err := cursor.All(ctx, &shortlists); err != nil {
+		return nil, err
+	}
+	if len(shortlists) == 0 {
+		favorites, err := CreateShortlist(userID, DefaultShortlistName)
+		if err != nil && !errors.Is(err, ErrShortlistNameTaken) {
+			return nil, err
+		}
+		if favorites != nil {
+			shortlists = append(shortlists, favorites)
+		}
+	}
+	return shortlists, nil
+}
+
+// GetShortlist returns one of the user's shortlists
+func GetShortlist(userID string, id string) (*Shortlist, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var shortlist Shortlist
+	if err := GetShortlistCollection().FindOne(context.Background(), bson.M{"_id": objID, "userId": userID}).Decode(&shortlist); err != nil {
+		return nil, err
+	}
+	return &shortlist, nil
+}
+
+// RenameShortlist reports whether the user has the shortlist
+func RenameShortlist(userID string, id string, name string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	result, err := GetShortlistCollection().UpdateOne(context.Background(),
+		bson.M{"_id": objID, "userId": userID},
+		bson.M{"$set": bson.M{"name": name, "updatedAt": time.Now()}},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, ErrShortlistNameTaken
+	}
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// DeleteShortlist reports whether the user had the shortlist
+func DeleteShortlist(userID string, id string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	result, err := GetShortlistCollection().DeleteOne(context.Background(), bson.M{"_id": objID, "userId": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// SaveShortlistItem adds a listing to a shortlist, or replaces its note and tags when it is
+// already there. It reports whether the user has the shortlist.
+func SaveShortlistItem(userID string, id string, item ShortlistItem) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	collection := GetShortlistCollection()
+	ctx := context.Background()
+	now := time.Now()
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": objID, "userId": userID, "items.propertyId": item.PropertyID},
+		bson.M{"$set": bson.M{"items.$.note": item.Note, "items.$.tags": item.Tags, "updatedAt": now}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount > 0 {
+		return true, nil
+	}
+
+	item.AddedAt = now
+	result, err = collection.UpdateOne(ctx,
+		bson.M{
+			"_id": objID, "userId": userID,
+			"items.propertyId":                           bson.M{"$ne": item.PropertyID},
+			"items." + strconv.Itoa(MaxShortlistItems-1): bson.M{"$exists": false},
+		},
+		bson.M{"$push": bson.M{"items": item}, "$set": bson.M{"updatedAt": now}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount > 0 {
+		return true, nil
+	}
+
+	// the shortlist is missing, full, or the item was added by a concurrent request
+	shortlist, err := GetShortlist(userID, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, existing := range shortlist.Items {
+		if existing.PropertyID == item.PropertyID {
+			return SaveShortlistItem(userID, id, item)
+		}
+	}
+	return true, ErrShortlistFull
+}
+
+// RemoveShortlistItem reports whether the listing was on the user's shortlist
+func RemoveShortlistItem(userID string, id string, propertyID string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	result, err := GetShortlistCollection().UpdateOne(context.Background(),
+		bson.M{"_id": objID, "userId": userID, "items.propertyId": propertyID},
+		bson.M{"$pull": bson.M{"items": bson.M{"propertyId": propertyID}}, "$set": bson.M{"updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// FindPropertiesByIDs loads public listings in the order of ids, skipping unknown, hidden and
+// malformed ones
+func FindPropertiesByIDs(ids []string) ([]*Property, error) {
+	var objIDs []primitive.ObjectID
+	for _, id := range ids {
+		if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objIDs = append(objIDs, objID)
+		}
+	}
+	if len(objIDs) == 0 {
+		return []*Property{}, nil
+	}
+
+	filter := publicListingFilter()
+	filter["_id"] = bson.M{"$in": objIDs}
+	ctx := context.Background()
+	cursor, err := GetPropertyCollection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	byID := make(map[string]*Property)
+	for cursor.Next(ctx) {
+		var property Property
+		if err := cursor.Decode(&property); err != nil {
+			return nil, err
+		}
+		byID[property.ID.Hex()] = &property
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	properties := []*Property{}
+	for _, id := range ids {
+		if property, ok := byID[id]; ok {
+			properties = append(properties, property)
+			delete(byID, id) // a repeated id is listed once
+		}
+	}
+	return properties, nil
+}
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"backend/services"
+	"backend/utils"
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultSimilarProperties = 6
+	MaxSimilarProperties     = 20
+
+	similarCacheKeyPrefix = "similar_properties:"
+	similarGenerationKey  = "similar_properties_generation:" // + city ID, bumped whenever a listing there changes
+	similarCacheTTL       = 6 * time.Hour
+	similarCandidatePool  = 300 // listings scored in memory per lookup
+	similarNearbyKm       = 10  // beyond this a listing gets no proximity credit
+	similarRentBand       = 0.5 // rents further apart than this fraction get no rent credit
+)
+
+// weights of the similarity signals, adding up to 1
+const (
+	similarLocalityWeight    = 0.35
+	similarRentWeight        = 0.25
+	similarBedroomsWeight    = 0.15
+	similarTypeWeight        = 0.10
+	similarPreferencesWeight = 0.15
+)
+
+// similarMatch is one ranked listing as kept in the cache. Only IDs are cached, so edits to
+// the listings show up at once and hidden or deleted ones drop out.
+type similarMatch struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// similarScope is the city whose generation versions a listing's cached recommendations.
+// Listings the registry could not place share one scope.
+func similarScope(cityID string) string {
+	if cityID == "" {
+		return "unplaced"
+	}
+	return cityID
+}
+
+// similarCacheKey is the cache key of a listing's recommendations under the current generation
+// of its city, so a change to any listing in the city retires every cached ranking there
+func similarCacheKey(ctx context.Context, property *Property) string {
+	scope := similarScope(property.CityID)
+	generation, err := services.GetFromRedis(ctx, similarGenerationKey+scope)
+	if err != nil {
+		generation = "0"
+	}
+	return similarCacheKeyPrefix + scope + ":" + generation + ":" + property.ID.Hex()
+}
+
+// invalidateSimilarIn retires the cached recommendations of every listing in a city
+func invalidateSimilarIn(cityID string) {
+	scope := similarScope(cityID)
+	if err := services.IncrementInRedis(context.Background(), similarGenerationKey+scope); err != nil {
+		utils.Logger.Printf("Failed to invalidate similar properties in %s: %v", scope, err)
+	}
+}
+
+// InvalidateSimilarProperties retires the cached recommendations of a listing that changed and
+// of the other listings in its city, which may rank it. Rankings that reach it across a city
+// border only catch up when they expire.
+func InvalidateSimilarProperties(id string) {
+	property, err := FindPropertyByID(id)
+	if err != nil {
+		utils.Logger.Printf("Failed to load property %s to invalidate similar properties: %v", id, err)
+		return
+	}
+	invalidateSimilarIn(property.CityID)
+}
+
+// FindSimilarProperties returns up to limit other public listings most like property, best
+// first, with SimilarityScore set. Other copies of the same flat are left out.
+func FindSimilarProperties(property *Property, limit int) ([]*Property, error) {
+	ctx := context.Background()
+	id := property.ID.Hex()
+	cacheKey := similarCacheKey(ctx, property)
+
+	var matches []similarMatch
+	if cached, err := services.GetFromRedis(ctx, cacheKey); err == nil {
+		if err := json.Unmarshal([]byte(cached), &matches); err != nil {
+			matches = nil
+		}
+	}
+	if matches == nil {
+		var err error
+		if matches, err = rankSimilarProperties(ctx, property); err != nil {
+			return nil, err
+		}
+		if data, err := json.Marshal(matches); err == nil {
+			if err := services.SetToRedis(ctx, cacheKey, string(data), similarCacheTTL); err != nil {
+				utils.Logger.Printf("Failed to cache similar properties of %s: %v", id, err)
+			}
+		}
+	}
+
+	// a few spares in case some of the cached listings are gone
+	ids := make([]string, 0, limit+5)
+	scores := make(map[string]float64, len(matches))
+	for _, match := range matches {
+		if len(ids) == cap(ids) {
+			break
+		}
+		ids = append(ids, match.ID)
+		scores[match.ID] = match.Score
+	}
+	properties, err := FindPropertiesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(properties) > limit {
+		properties = properties[:limit]
+	}
+	for _, p := range properties {
+		p.SimilarityScore = scores[p.ID.Hex()]
+	}
+	return properties, nil
+}
+
+// rankSimilarProperties scores the nearby listings of the same kind against property and keeps
+// the best MaxSimilarProperties
+func rankSimilarProperties(ctx context.Context, property *Property) ([]similarMatch, error) {
+	filter := publicListingFilter()
+	filter["_id"] = bson.M{"$ne": property.ID}
+	if property.ListingType != "" {
+		filter["listingType"] = property.ListingType
+	}
+	if property.DuplicateClusterID != "" {
+		filter["duplicateClusterId"] = bson.M{"$ne": property.DuplicateClusterID}
+	}
+	if nearby := similarAreaCondition(property); nearby != nil {
+		addCondition(filter, nearby)
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(similarCandidatePool)
+	cursor, err := GetPropertyCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []*Property
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+
+	var kept []*Property
+	for _, candidate := range candidates {
+		if !isOwnerDuplicate(property, candidate) {
+			candidate.SimilarityScore = similarityScore(property, candidate)
+			kept = append(kept, candidate)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].SimilarityScore > kept[j].SimilarityScore })
+	kept = collapseDuplicateClusters(kept)
+
+	matches := []similarMatch{}
+	for _, candidate := range kept {
+		if len(matches) == MaxSimilarProperties {
+			break
+		}
+		matches = append(matches, similarMatch{ID: candidate.ID.Hex(), Score: candidate.SimilarityScore})
+	}
+	return matches, nil
+}
+
+// similarAreaCondition limits candidates to the listing's city or surroundings, or nil when
+// nothing is known about where it is
+func similarAreaCondition(property *Property) bson.M {
+	var or bson.A
+	if property.Geo != nil {
+		or = append(or, bson.M{"geo": withinRadius(property.Geo, similarNearbyKm)})
+	}
+	if property.CityID != "" {
+		or = append(or, bson.M{"cityId": property.CityID})
+	} else if city := cityCondition(property.City); city != nil {
+		or = append(or, city)
+	}
+	if property.LocalityID != "" {
+		or = append(or, bson.M{"localityId": property.LocalityID})
+	} else if locality := localityCondition(property.Location, property.CityID); locality != nil {
+		or = append(or, locality)
+	}
+	if len(or) == 0 {
+		return nil
+	}
+	return bson.M{"$or": or}
+}
+
+// isOwnerDuplicate reports whether candidate is the same owner listing the same flat again
+func isOwnerDuplicate(property, candidate *Property) bool {
+	if property.OwnerID == "" || candidate.OwnerID != property.OwnerID {
+		return false
+	}
+	if candidate.Bedrooms != property.Bedrooms {
+		return false
+	}
+	if property.LocalityID != "" && candidate.LocalityID == property.LocalityID {
+		return true
+	}
+	if property.Fingerprint != nil && candidate.Fingerprint != nil && property.Fingerprint.LocationKey != "" {
+		return candidate.Fingerprint.LocationKey == property.Fingerprint.LocationKey
+	}
+	return strings.EqualFold(strings.TrimSpace(candidate.Location), strings.TrimSpace(property.Location))
+}
+
+// similarityScore is a 0-1 score of how much candidate resembles property
+func similarityScore(property, candidate *Property) float64 {
+	score := similarLocalityWeight*localityProximity(property, candidate) +
+		similarRentWeight*rentCloseness(property.Price(), candidate.Price()) +
+		similarBedroomsWeight*bedroomsCloseness(property.Bedrooms, candidate.Bedrooms) +
+		similarPreferencesWeight*preferencesMatch(property, candidate)
+	if property.PropertyType != "" && strings.EqualFold(property.PropertyType, candidate.PropertyType) {
+		score += similarTypeWeight
+	}
+	return math.Round(score*1000) / 1000
+}
+
+// localityProximity is 1 for the same locality or the same spot, falling to 0 at
+// similarNearbyKm, and 0.2 for anywhere else in the same city
+func localityProximity(property, candidate *Property) float64 {
+	proximity := 0.0
+	if property.LocalityID != "" && property.LocalityID == candidate.LocalityID {
+		proximity = 1
+	}
+	if property.HasCoordinates() && candidate.HasCoordinates() {
+		km := utils.HaversineKm(property.Latitude, property.Longitude, candidate.Latitude, candidate.Longitude)
+		proximity = math.Max(proximity, 1-km/similarNearbyKm)
+	}
+	if property.CityID != "" && property.CityID == candidate.CityID {
+		proximity = math.Max(proximity, 0.2)
+	}
+	return proximity
+}
+
+// rentCloseness is 1 for the same rent, falling to 0 once the rents differ by similarRentBand.
+// Listings without a rent are neutral.
+func rentCloseness(rent, other int) float64 {
+	if rent <= 0 || other <= 0 {
+		return 0.5
+	}
+	diff := math.Abs(float64(other-rent)) / float64(rent)
+	return math.Max(0, 1-diff/similarRentBand)
+}
+
+func bedroomsCloseness(bedrooms, other int) float64 {
+	if bedrooms == 0 || other == 0 {
+		return 0.5
+	}
+	switch diff := bedrooms - other; {
+	case diff == 0:
+		return 1
+	case diff == 1 || diff == -1:
+		return 0.5
+	}
+	return 0
+}
+
+// preferencesMatch is the share of the vegetarian, family and gender preferences the two
+// listings agree on; "Any" and unset genders count as the same
+func preferencesMatch(property, candidate *Property) float64 {
+	matched := 0.0
+	if property.IsVegetarianPreferred == candidate.IsVegetarianPreferred {
+		matched++
+	}
+	if property.IsFamilyPreferred == candidate.IsFamilyPreferred {
+		matched++
+	}
+	if genderPreference(property) == genderPreference(candidate) {
+		matched++
+	}
+	return matched / 3
+}
+
+func genderPreference(p *Property) string {
+	gender := strings.ToLower(strings.TrimSpace(p.GenderPreference))
+	if gender == "" {
+		return "any"
+	}
+	return gender
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestSimilarityRanking(t *testing.T) {
+	flat := &Property{PropertyType: "Apartment", CityID: "bangalore", LocalityID: "indiranagar",
+		Latitude: 12.9784, Longitude: 77.6408, Rent: 30000, Bedrooms: 2}
+
+	candidates := map[string]*Property{
+		// next street, a bit dearer
+		"neighbour": {PropertyType: "apartment", CityID: "bangalore", LocalityID: "indiranagar",
+			Latitude: 12.9790, Longitude: 77.6420, Rent: 32000, Bedrooms: 2},
+		// 3 km away in the next locality, same size and rent
+		"next locality": {PropertyType: "Apartment", CityID: "bangalore", LocalityID: "domlur",
+			Latitude: 12.9610, Longitude: 77.6387, Rent: 30000, Bedrooms: 2},
+		// same locality, but a villa twice the rent
+		"villa": {PropertyType: "Villa", CityID: "bangalore", LocalityID: "indiranagar", Rent: 65000, Bedrooms: 4},
+		// across town, same size and rent
+		"across town": {PropertyType: "Apartment", CityID: "bangalore", LocalityID: "whitefield",
+			Latitude: 12.9698, Longitude: 77.7500, Rent: 30000, Bedrooms: 2},
+		"pune": {CityID: "pune", Rent: 90000, Bedrooms: 5},
+	}
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return similarityScore(flat, candidates[names[i]]) > similarityScore(flat, candidates[names[j]])
+	})
+	want := []string{"neighbour", "next locality", "across town", "villa", "pune"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("ranked %v, want %v", names, want)
+		}
+	}
+
+	if score := similarityScore(flat, flat); score != 1 {
+		t.Errorf("similarityScore of a listing with itself = %v, want 1", score)
+	}
+}
+
+func TestSimilarityPreferences(t *testing.T) {
+	anyone := &Property{}
+	if score := preferencesMatch(anyone, &Property{GenderPreference: " Any "}); score != 1 {
+		t.Errorf("unset and \"Any\" gender preference match %v, want 1", score)
+	}
+	if score := preferencesMatch(anyone, &Property{GenderPreference: "Female", IsVegetarianPreferred: true}); score != 1.0/3 {
+		t.Errorf("two differing preferences match %v, want 1/3", score)
+	}
+}
+
+func TestIsOwnerDuplicate(t *testing.T) {
+	flat := &Property{OwnerID: "owner", Bedrooms: 2, LocalityID: "indiranagar", Location: "12th Main, Indiranagar"}
+	relisted := &Property{OwnerID: "owner", Bedrooms: 2, LocalityID: "indiranagar"}
+	if !isOwnerDuplicate(flat, relisted) {
+		t.Errorf("the owner's second listing of the flat is not a duplicate")
+	}
+	if isOwnerDuplicate(flat, &Property{OwnerID: "owner", Bedrooms: 3, LocalityID: "indiranagar"}) {
+		t.Errorf("the owner's bigger flat in the locality is a duplicate")
+	}
+	if isOwnerDuplicate(flat, &Property{OwnerID: "other", Bedrooms: 2, LocalityID: "indiranagar"}) {
+		t.Errorf("another owner's flat is a duplicate")
+	}
+	unplaced := &Property{OwnerID: "owner", Bedrooms: 2, Location: " 12th main, indiranagar"}
+	if !isOwnerDuplicate(&Property{OwnerID: "owner", Bedrooms: 2, Location: "12th Main, Indiranagar"}, unplaced) {
+		t.Errorf("the same address written differently is not a duplicate")
+	}
+}
@@ -0,0 +1,377 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultSocietySuggestions = 8
+	MaxSocietySuggestions     = 20
+
+	societyPageListings = 50
+	societyPageReviews  = 20
+	maxSocietyAmenities = 40
+)
+
+var ErrSocietyExists = errors.New("a society with that name already exists in this city")
+
+// Society is a building or gated community many listings share, so its address, amenities,
+// photos and reviews are entered once
+type Society struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name       string             `json:"name" bson:"name"`
+	NameKey    string             `json:"-" bson:"nameKey"` // see societyKey, unique per city
+	Aliases    []string           `json:"aliases,omitempty" bson:"aliases,omitempty"`
+	AliasKeys  []string           `json:"-" bson:"aliasKeys,omitempty"`
+	Address    string             `json:"address,omitempty" bson:"address,omitempty"`
+	Area       string             `json:"area,omitempty" bson:"area,omitempty"`
+	City       string             `json:"city,omitempty" bson:"city,omitempty"`
+	CityID     string             `json:"cityId,omitempty" bson:"cityId,omitempty"`
+	LocalityID string             `json:"localityId,omitempty" bson:"localityId,omitempty"`
+	Latitude   float64            `json:"latitude,omitempty" bson:"latitude,omitempty"`
+	Longitude  float64            `json:"longitude,omitempty" bson:"longitude,omitempty"`
+	Geo        *GeoPoint          `json:"-" bson:"geo,omitempty"`
+	Amenities  []string           `json:"amenities,omitempty" bson:"amenities,omitempty"` // shared by every flat, e.g. clubhouse, gate security
+	PhotoSet   []Photo            `json:"photoSet,omitempty" bson:"photoSet,omitempty"`
+	CreatedBy  string             `json:"-" bson:"createdBy"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// SocietyPage is a society with its live listings and reviews
+type SocietyPage struct {
+	*Society
+	Listings      []*Property `json:"listings"`
+	ListingCount  int64       `json:"listingCount"`
+	MinRent       int         `json:"minRent,omitempty"`
+	MaxRent       int         `json:"maxRent,omitempty"`
+	Reviews       []*Review   `json:"reviews"`
+	ReviewCount   int64       `json:"reviewCount"`
+	AverageRating float64     `json:"averageRating,omitempty"`
+}
+
+func GetSocietyCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("societies")
+}
+
+var societyKeyJunk = regexp.MustCompile(`[^a-z0-9]+`)
+
+// societyKey folds a society name for matching: lower case, letters and digits only
+func societyKey(name string) string {
+	return strings.TrimSpace(societyKeyJunk.ReplaceAllString(strings.ToLower(name), " "))
+}
+
+// Normalize tidies the society before it is stored: canonical city and locality, keys for
+// matching and the indexed position
+func (s *Society) Normalize() {
+	s.Name = strings.TrimSpace(s.Name)
+	s.NameKey = societyKey(s.Name)
+	s.AliasKeys = nil
+	for _, alias := range s.Aliases {
+		if key := societyKey(alias); key != "" {
+			s.AliasKeys = append(s.AliasKeys, key)
+		}
+	}
+
+	place := &Property{Area: s.Area, City: s.City, Location: s.Area}
+	place.NormalizeLocality()
+	s.Area, s.City, s.CityID, s.LocalityID = place.Area, place.City, place.CityID, place.LocalityID
+
+	s.Geo = nil
+	if s.Latitude != 0 && s.Longitude != 0 && ValidCoordinates(s.Latitude, s.Longitude) {
+		s.Geo = NewGeoPoint(s.Latitude, s.Longitude)
+	}
+}
+
+// Validate checks the fields a society needs
+func (s *Society) Validate() []string {
+	var validationErrors []string
+	if societyKey(s.Name) == "" {
+		validationErrors = append(validationErrors, "Society name cannot be empty")
+	}
+	if s.City == "" {
+		validationErrors = append(validationErrors, "City cannot be empty")
+	}
+	if (s.Latitude != 0 || s.Longitude != 0) && !ValidCoordinates(s.Latitude, s.Longitude) {
+		validationErrors = append(validationErrors, "Invalid coordinates")
+	}
+	if len(s.Amenities) > maxSocietyAmenities {
+		validationErrors = append(validationErrors, "Too many amenities")
+	}
+	return validationErrors
+}
+
+// CreateSociety stores a new society, failing with ErrSocietyExists when the city already has
+// one by that name
+func CreateSociety(society *Society) error {
+	society.ID = primitive.NewObjectID()
+	society.CreatedAt = time.Now()
+	society.UpdatedAt = society.CreatedAt
+	society.PhotoSet = nil
+	society.Normalize()
+	_, err := GetSocietyCollection().InsertOne(context.Background(), society)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSocietyExists
+	}
+	return err
+}
+
+// UpdateSociety replaces the editable fields of a society
+func UpdateSociety(id string, society *Society) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	society.Normalize()
+	set := bson.M{
+		"name": society.Name, "nameKey": society.NameKey, "aliases": society.Aliases, "aliasKeys": society.AliasKeys,
+		"address": society.Address, "area": society.Area, "city": society.City, "cityId": society.CityID, "localityId": society.LocalityID,
+		"latitude": society.Latitude, "longitude": society.Longitude, "geo": society.Geo,
+		"amenities": society.Amenities, "updatedAt": time.Now(),
+	}
+	_, err = GetSocietyCollection().UpdateOne(context.Background(), bson.M{"_id": objID}, bson.M{"$set": set})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSocietyExists
+	}
+	return err
+}
+
+func FindSocietyByID(id string) (*Society, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var society Society
+	if err := GetSocietyCollection().FindOne(context.Background(), bson.M{"_id": objID}).Decode(&society); err != nil {
+		return nil, err
+	}
+	return &society, nil
+}
+
+// AddSocietyPhoto appends a photo to the society's shared photos
+func AddSocietyPhoto(id string, photo Photo) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$push": bson.M{"photoSet": photo}, "$set": bson.M{"updatedAt": time.Now()}}
+	_, err = GetSocietyCollection().UpdateOne(context.Background(), bson.M{"_id": objID}, update)
+	return err
+}
+
+// SuggestSocieties autocompletes society names: names and aliases starting with the query
+// first, then ones containing it, optionally within a city
+func SuggestSocieties(query string, city string, limit int) ([]*Society, error) {
+	key := societyKey(query)
+	if key == "" {
+		return []*Society{}, nil
+	}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"nameKey": bson.M{"$regex": primitive.Regex{Pattern: regexp.QuoteMeta(key)}}},
+		bson.M{"aliasKeys": bson.M{"$regex": primitive.Regex{Pattern: regexp.QuoteMeta(key)}}},
+	}}
+	if city != "" {
+		if c := services.LookupCity(city); c != nil {
+			filter["cityId"] = c.ID
+		} else {
+			filter["city"] = bson.M{"$regex": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(city) + "$", Options: "i"}}
+		}
+	}
+
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.M{"name": 1}).SetLimit(int64(limit) * 5).
+		SetProjection(bson.M{"photoSet": 0})
+	cursor, err := GetSocietyCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	societies := []*Society{}
+	if err := cursor.All(ctx, &societies); err != nil {
+		return nil, err
+	}
+	prefix := func(s *Society) bool {
+		if strings.HasPrefix(s.NameKey, key) {
+			return true
+		}
+		for _, alias := range s.AliasKeys {
+			if strings.HasPrefix(alias, key) {
+				return true
+			}
+		}
+		return false
+	}
+	sort.SliceStable(societies, func(i, j int) bool { return prefix(societies[i]) && !prefix(societies[j]) })
+	if len(societies) > limit {
+		societies = societies[:limit]
+	}
+	return societies, nil
+}
+
+// matchSociety finds the society a free-text society name refers to, in the listing's city
+func matchSociety(name string, cityID string) (*Society, error) {
+	key := societyKey(name)
+	if key == "" || cityID == "" {
+		return nil, nil
+	}
+	filter := bson.M{"cityId": cityID, "$or": bson.A{bson.M{"nameKey": key}, bson.M{"aliasKeys": key}}}
+	var society Society
+	err := GetSocietyCollection().FindOne(context.Background(), filter).Decode(&society)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &society, nil
+}
+
+// linkSociety ties a listing to its society: by SocietyID when given, else by matching
+// SocietyName in cityID. A linked listing takes the society's canonical name and, unless
+// positioned, the society's coordinates. A SocietyID that is unknown or in another city is dropped.
+func (p *Property) linkSociety(cityID string, positioned bool) error {
+	var society *Society
+	var err error
+	if p.SocietyID != "" {
+		society, err = FindSocietyByID(p.SocietyID)
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+			society, err = nil, nil
+		}
+		if society != nil && (cityID == "" || society.CityID != cityID) {
+			society = nil
+		}
+		if society == nil && err == nil {
+			p.SocietyID = ""
+		}
+	}
+	if society == nil && err == nil && p.SocietyName != "" {
+		society, err = matchSociety(p.SocietyName, cityID)
+	}
+	if err != nil || society == nil {
+		return err
+	}
+
+	p.SocietyID = society.ID.Hex()
+	p.SocietyName = society.Name
+	if !positioned && !p.HasCoordinates() && society.Geo != nil {
+		p.Latitude, p.Longitude = society.Latitude, society.Longitude
+		p.syncGeoPoint()
+	}
+	return nil
+}
+
+// linkSocietyUpdate links the society named in a partial update, in the stored listing's city
+// unless the update moves it
+func linkSocietyUpdate(id primitive.ObjectID, p *Property) error {
+	var stored Property
+	opts := options.FindOne().SetProjection(bson.M{"cityId": 1, "latitude": 1, "longitude": 1})
+	if err := GetPropertyCollection().FindOne(context.Background(), bson.M{"_id": id}, opts).Decode(&stored); err != nil {
+		return err
+	}
+	cityID := p.CityID
+	if cityID == "" {
+		cityID = stored.CityID
+	}
+	return p.linkSociety(cityID, stored.HasCoordinates())
+}
+
+// OwnsPublishedListingInSociety reports whether the user has a live listing linked to the
+// society; drafts do not count, anyone can start one
+func OwnsPublishedListingInSociety(userID string, societyID string) (bool, error) {
+	filter := bson.M{"owner_id": userID, "societyId": societyID, "status": StatusPublished}
+	count, err := GetPropertyCollection().CountDocuments(context.Background(), filter, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// GetSocietyPage loads a society with its newest live listings, the rent range across all of
+// them and its reviews
+func GetSocietyPage(id string) (*SocietyPage, error) {
+	society, err := FindSocietyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	page := &SocietyPage{Society: society, Listings: []*Property{}, Reviews: []*Review{}}
+	ctx := context.Background()
+
+	// only real listings: the collection also holds reviews, which have no owner
+	listingFilter := publicListingFilter()
+	listingFilter["societyId"] = id
+	listingFilter["owner_id"] = bson.M{"$exists": true}
+	cursor, err := GetPropertyCollection().Find(ctx, listingFilter, options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(societyPageListings))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &page.Listings); err != nil {
+		return nil, err
+	}
+
+	stats, err := GetPropertyCollection().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: listingFilter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"count":   bson.M{"$sum": 1},
+			"minRent": bson.M{"$min": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$rent", 0}}, "$rent", nil}}},
+			"maxRent": bson.M{"$max": "$rent"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var listingStats []struct {
+		Count   int64 `bson:"count"`
+		MinRent int   `bson:"minRent"`
+		MaxRent int   `bson:"maxRent"`
+	}
+	if err := stats.All(ctx, &listingStats); err != nil {
+		return nil, err
+	}
+	if len(listingStats) > 0 {
+		page.ListingCount, page.MinRent, page.MaxRent = listingStats[0].Count, listingStats[0].MinRent, listingStats[0].MaxRent
+	}
+
+	page.Reviews, err = GetSocietyReviews(id, societyPageReviews)
+	if err != nil {
+		return nil, err
+	}
+	page.ReviewCount, page.AverageRating, err = societyRating(id)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// societyRating is the number of reviews of a society and their average rating
+func societyRating(societyID string) (int64, float64, error) {
+	ctx := context.Background()
+	cursor, err := GetReviewCollection().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"societyId": societyID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "count": bson.M{"$sum": 1}, "average": bson.M{"$avg": "$rating"}}}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	var results []struct {
+		Count   int64   `bson:"count"`
+		Average float64 `bson:"average"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, 0, err
+	}
+	if len(results) == 0 {
+		return 0, 0, nil
+	}
+	return results[0].Count, math.Round(results[0].Average*10) / 10, nil
+}
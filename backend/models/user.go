@@ -11,14 +11,19 @@ import (
 )
 
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty"`
-	Email        string             `bson:"email"`
-	PasswordHash string             `bson:"password_hash"`
-	Name         string             `bson:"name"`
-	Picture      string             `bson:"picture"`
-	Role         string             `bson:"role"` // e.g., "owner", "tenant", "admin"
-	CreatedAt    time.Time          `bson:"createdAt"`
-	UpdatedAt    time.Time          `bson:"updatedAt"`
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	Email         string             `bson:"email"`
+	PasswordHash  string             `bson:"password_hash"`
+	Name          string             `bson:"name"`
+	Picture       string             `bson:"picture"`
+	Role          string             `bson:"role"`            // e.g., "owner", "tenant", "admin"
+	Offices       []string           `bson:"offices"`         // Workplace IDs, used to compare commutes
+	Phone         string             `bson:"phone,omitempty"` // 10 digits, set from a phone sign-in
+	PhoneVerified bool               `bson:"phoneVerified,omitempty"`
+	EmailVerified bool               `bson:"emailVerified,omitempty"`
+	Suspended     bool               `bson:"suspended,omitempty"` // may not list, set by a moderator acting on reports
+	CreatedAt     time.Time          `bson:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt"`
 }
 
 func GetUserCollection() *mongo.Collection {
@@ -49,6 +54,45 @@ func FindUserByID(id string) (*User, error) {
 	return &user, nil
 }
 
+// IsVerified reports whether the user proved they own their phone or email
+func (u *User) IsVerified() bool {
+	return u.PhoneVerified || u.EmailVerified
+}
+
+// MarkUserVerified records what a sign-in provider vouched for: the email, and the phone when
+// phone is not empty
+func MarkUserVerified(id primitive.ObjectID, email bool, phone string) error {
+	set := bson.M{}
+	if email {
+		set["emailVerified"] = true
+	}
+	if phone != "" {
+		set["phone"] = phone
+		set["phoneVerified"] = true
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	_, err := GetUserCollection().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// SuspendUser stops a user from listing
+func SuspendUser(id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = GetUserCollection().UpdateOne(context.Background(), bson.M{"_id": objID}, bson.M{"$set": bson.M{"suspended": true, "updatedAt": time.Now()}})
+	return err
+}
+
+// IsUserSuspended reports whether a user was suspended; unknown users are not
+func IsUserSuspended(id string) bool {
+	user, err := FindUserByID(id)
+	return err == nil && user.Suspended
+}
+
 func (u *User) Save() (string, error) {
 	collection := GetUserCollection()
 	u.CreatedAt = time.Now()
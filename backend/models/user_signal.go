@@ -0,0 +1,140 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// kinds of UserSignal, from weakest to strongest interest
+const (
+	SignalSearch   = "search"
+	SignalView     = "view"
+	SignalFavorite = "favorite"
+	SignalChat     = "chat"
+)
+
+// UserSignalRetention is how long signals are kept; older ones are removed by a TTL index
+const UserSignalRetention = 90 * 24 * time.Hour
+
+// UserSignal is one thing a signed-in user did that says what they are looking for: a search,
+// or a view, favorite or chat about a listing. It keeps the parts of the listing or search the
+// feed ranks by, so the feed does not need to load the listings again.
+type UserSignal struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID       string             `json:"userId" bson:"userId"`
+	Kind         string             `json:"kind" bson:"kind"`
+	PropertyID   string             `json:"propertyId,omitempty" bson:"propertyId,omitempty"`
+	ListingType  string             `json:"listingType,omitempty" bson:"listingType,omitempty"`
+	CityID       string             `json:"cityId,omitempty" bson:"cityId,omitempty"`
+	LocalityID   string             `json:"localityId,omitempty" bson:"localityId,omitempty"`
+	PropertyType string             `json:"propertyType,omitempty" bson:"propertyType,omitempty"`
+	Bedrooms     int                `json:"bedrooms,omitempty" bson:"bedrooms,omitempty"`
+	Rent         int                `json:"rent,omitempty" bson:"rent,omitempty"`   // the listing's rent, or the middle of the searched range
+	Count        int                `json:"count,omitempty" bson:"count,omitempty"` // repeats of a listing signal
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`             // the latest repeat
+}
+
+func GetUserSignalCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("user_signals")
+}
+
+// NewSearchSignal reads a search's filters into a signal, or returns nil when they say nothing
+// the feed can use
+func NewSearchSignal(userID string, filters map[string]interface{}) *UserSignal {
+	signal := &UserSignal{UserID: userID, Kind: SignalSearch}
+	if city, ok := filters["city"].(string); ok {
+		if known := services.LookupCity(city); known != nil {
+			signal.CityID = known.ID
+		}
+	}
+	if location, ok := filters["location"].(string); ok {
+		if place := services.LookupLocality(location, signal.CityID); place != nil {
+			if place.IsCity() {
+				signal.CityID = place.ID
+			} else {
+				signal.LocalityID = place.ID
+				signal.CityID = place.CityID
+			}
+		}
+	}
+	if listingType, ok := filters["listingType"].(string); ok {
+		signal.ListingType = listingType
+	}
+	if propertyType, ok := filters["propertyType"].(string); ok {
+		signal.PropertyType = propertyType
+	}
+	if bedrooms, ok := filters["bedrooms"].(float64); ok {
+		signal.Bedrooms = int(bedrooms)
+	}
+	minRent, _ := filters["minRent"].(float64)
+	maxRent, _ := filters["maxRent"].(float64)
+	switch {
+	case minRent > 0 && maxRent > 0:
+		signal.Rent = int((minRent + maxRent) / 2)
+	case maxRent > 0:
+		signal.Rent = int(maxRent)
+	case minRent > 0:
+		signal.Rent = int(minRent)
+	}
+
+	if signal.CityID == "" && signal.LocalityID == "" && signal.PropertyType == "" && signal.Bedrooms == 0 && signal.Rent == 0 {
+		return nil
+	}
+	return signal
+}
+
+// NewPropertySignal is a view, favorite or chat about property
+func NewPropertySignal(userID string, kind string, property *Property) *UserSignal {
+	return &UserSignal{
+		UserID:       userID,
+		Kind:         kind,
+		PropertyID:   property.ID.Hex(),
+		ListingType:  property.ListingType,
+		CityID:       property.CityID,
+		LocalityID:   property.LocalityID,
+		PropertyType: property.PropertyType,
+		Bedrooms:     property.Bedrooms,
+		Rent:         property.Rent,
+	}
+}
+
+// RecordUserSignal stores a signal. Listing signals are kept once per user, kind and listing:
+// repeats bump the count and the time instead of adding a document.
+func RecordUserSignal(signal *UserSignal) error {
+	signal.CreatedAt = time.Now()
+	if signal.PropertyID == "" {
+		_, err := GetUserSignalCollection().InsertOne(context.Background(), signal)
+		return err
+	}
+
+	signal.Count = 0 // set by $inc
+	_, err := GetUserSignalCollection().UpdateOne(context.Background(),
+		bson.M{"userId": signal.UserID, "kind": signal.Kind, "propertyId": signal.PropertyID},
+		bson.M{"$set": signal, "$inc": bson.M{"count": 1}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetRecentUserSignals returns the user's latest signals, newest first
+func GetRecentUserSignals(userID string, limit int64) ([]*UserSignal, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit)
+	cursor, err := GetUserSignalCollection().Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var signals []*UserSignal
+	if err := cursor.All(ctx, &signals); err != nil {
+		return nil, err
+	}
+	return signals, nil
+}
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	VisitBooked    = "booked"
+	VisitCancelled = "cancelled"
+)
+
+var (
+	ErrVisitUnavailable   = errors.New("the requested time is not one of the listing's visiting slots")
+	ErrVisitFull          = errors.New("the requested visiting time is fully booked")
+	ErrVisitAlreadyBooked = errors.New("you already have an upcoming visit to this listing, reschedule it instead")
+	ErrVisitNotUpcoming   = errors.New("only upcoming booked visits can be changed")
+)
+
+// VisitReminder is sent to both sides once a visit is less than Before away
+type VisitReminder struct {
+	Name   string
+	Before time.Duration
+}
+
+// VisitReminders go from the latest to the earliest
+var VisitReminders = []VisitReminder{
+	{Name: "hour", Before: time.Hour},
+	{Name: "day", Before: 24 * time.Hour},
+}
+
+// Visit is a tenant's booked viewing of a listing
+type Visit struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PropertyID    string             `json:"propertyId" bson:"propertyId"`
+	OwnerID       string             `json:"ownerId" bson:"ownerId"`
+	TenantID      string             `json:"tenantId" bson:"tenantId"`
+	StartsAt      time.Time          `json:"startsAt" bson:"startsAt"`
+	EndsAt        time.Time          `json:"endsAt" bson:"endsAt"`
+	Status        string             `json:"status" bson:"status"`
+	Note          string             `json:"note,omitempty" bson:"note,omitempty"`
+	CancelledBy   string             `json:"cancelledBy,omitempty" bson:"cancelledBy,omitempty"`
+	RemindersSent []string           `json:"-" bson:"remindersSent,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt     time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// visitBooking counts the visits booked at one time of a listing. Every booking, move and
+// cancellation writes it inside its transaction, so two tenants racing for the last place
+// conflict instead of both getting it.
+type visitBooking struct {
+	ID         string    `bson:"_id"`
+	PropertyID string    `bson:"propertyId"`
+	StartsAt   time.Time `bson:"startsAt"`
+	Booked     int       `bson:"booked"`
+}
+
+func GetVisitCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("visits")
+}
+
+func getVisitBookingCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("visit_bookings")
+}
+
+// getVisitTenantCollection holds one document per tenant and listing. BookVisit increments it
+// before looking for the tenant's upcoming visit, so two bookings by the same tenant conflict
+// and the retried one sees the other's visit. A plain read would let both through.
+func getVisitTenantCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("visit_tenants")
+}
+
+func visitBookingID(propertyID string, startsAt time.Time) string {
+	return fmt.Sprintf("%s@%d", propertyID, startsAt.Unix())
+}
+
+// IsUpcoming reports whether the visit is booked and has not started
+func (v *Visit) IsUpcoming() bool {
+	return v.Status == VisitBooked && v.StartsAt.After(time.Now())
+}
+
+// withTransaction runs fn in a transaction, retrying it on write conflicts
+func withTransaction(fn func(ctx mongo.SessionContext) error) error {
+	session, err := services.GetMongoDB().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+// reserveVisitTime takes one place at a visit time, failing with ErrVisitFull when none is left
+func reserveVisitTime(ctx mongo.SessionContext, propertyID string, startsAt time.Time, capacity int) error {
+	var booking visitBooking
+	err := getVisitBookingCollection().FindOneAndUpdate(ctx,
+		bson.M{"_id": visitBookingID(propertyID, startsAt)},
+		bson.M{"$inc": bson.M{"booked": 1}, "$setOnInsert": bson.M{"propertyId": propertyID, "startsAt": startsAt}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&booking)
+	if err != nil {
+		return err
+	}
+	if booking.Booked > capacity {
+		return ErrVisitFull
+	}
+	return nil
+}
+
+func releaseVisitTime(ctx mongo.SessionContext, propertyID string, startsAt time.Time) error {
+	_, err := getVisitBookingCollection().UpdateOne(ctx,
+		bson.M{"_id": visitBookingID(propertyID, startsAt), "booked": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"booked": -1}},
+	)
+	return err
+}
+
+// bookedVisitCounts returns the visits booked per start time (unix seconds) in [from, to)
+func bookedVisitCounts(propertyID string, from time.Time, to time.Time) (map[int64]int, error) {
+	ctx := context.Background()
+	filter := bson.M{"propertyId": propertyID, "startsAt": bson.M{"$gte": from, "$lt": to}, "booked": bson.M{"$gt": 0}}
+	cursor, err := getVisitBookingCollection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[int64]int)
+	for cursor.Next(ctx) {
+		var booking visitBooking
+		if err := cursor.Decode(&booking); err != nil {
+			return nil, err
+		}
+		counts[booking.StartsAt.Unix()] = booking.Booked
+	}
+	return counts, cursor.Err()
+}
+
+// openVisitAt checks startsAt is a future visit time of the listing and returns the visit's end
+// and capacity
+func openVisitAt(propertyID string, startsAt time.Time) (time.Time, int, error) {
+	if !startsAt.After(time.Now()) {
+		return time.Time{}, 0, ErrVisitUnavailable
+	}
+	slots, err := GetVisitSlots(propertyID)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	endsAt, capacity, ok := visitOpeningAt(slots, startsAt)
+	if !ok {
+		return time.Time{}, 0, ErrVisitUnavailable
+	}
+	return endsAt, capacity, nil
+}
+
+// dueVisitReminders are the reminders whose window has already opened at booking time; the
+// booking itself tells the tenant, so they are not sent
+func dueVisitReminders(startsAt time.Time) []string {
+	due := []string{}
+	for _, reminder := range VisitReminders {
+		if time.Until(startsAt) <= reminder.Before {
+			due = append(due, reminder.Name)
+		}
+	}
+	return due
+}
+
+// BookVisit books a tenant's visit to a listing at one of its visit times
+func BookVisit(property *Property, tenantID string, startsAt time.Time, note string) (*Visit, error) {
+	propertyID := property.ID.Hex()
+	endsAt, capacity, err := openVisitAt(propertyID, startsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	visit := &Visit{
+		ID:            primitive.NewObjectID(),
+		PropertyID:    propertyID,
+		OwnerID:       property.OwnerID,
+		TenantID:      tenantID,
+		StartsAt:      startsAt,
+		EndsAt:        endsAt,
+		Status:        VisitBooked,
+		Note:          note,
+		RemindersSent: dueVisitReminders(startsAt),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+		_, err := getVisitTenantCollection().UpdateOne(ctx,
+			bson.M{"_id": propertyID + ":" + tenantID},
+			bson.M{"$inc": bson.M{"bookings": 1}, "$setOnInsert": bson.M{"propertyId": propertyID, "tenantId": tenantID}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+		upcoming, err := GetVisitCollection().CountDocuments(ctx, bson.M{
+			"propertyId": propertyID, "tenantId": tenantID, "status": VisitBooked, "startsAt": bson.M{"$gt": now},
+		})
+		if err != nil {
+			return err
+		}
+		if upcoming > 0 {
+			return ErrVisitAlreadyBooked
+		}
+		if err := reserveVisitTime(ctx, propertyID, startsAt, capacity); err != nil {
+			return err
+		}
+		_, err = GetVisitCollection().InsertOne(ctx, visit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return visit, nil
+}
+
+// RescheduleVisit moves an upcoming visit to another of the listing's visit times
+func RescheduleVisit(visit *Visit, startsAt time.Time) error {
+	if !visit.IsUpcoming() {
+		return ErrVisitNotUpcoming
+	}
+	endsAt, capacity, err := openVisitAt(visit.PropertyID, startsAt)
+	if err != nil {
+		return err
+	}
+
+	reminders := dueVisitReminders(startsAt)
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+		result, err := GetVisitCollection().UpdateOne(ctx,
+			bson.M{"_id": visit.ID, "status": VisitBooked, "startsAt": visit.StartsAt},
+			bson.M{"$set": bson.M{"startsAt": startsAt, "endsAt": endsAt, "updatedAt": time.Now(), "remindersSent": reminders}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrVisitNotUpcoming // cancelled or moved in the meantime
+		}
+		if err := releaseVisitTime(ctx, visit.PropertyID, visit.StartsAt); err != nil {
+			return err
+		}
+		return reserveVisitTime(ctx, visit.PropertyID, startsAt, capacity)
+	})
+	if err != nil {
+		return err
+	}
+	visit.StartsAt, visit.EndsAt, visit.RemindersSent = startsAt, endsAt, reminders
+	return nil
+}
+
+// CancelVisit cancels an upcoming visit on behalf of the tenant or the owner, freeing its place
+func CancelVisit(visit *Visit, userID string) error {
+	if !visit.IsUpcoming() {
+		return ErrVisitNotUpcoming
+	}
+	err := withTransaction(func(ctx mongo.SessionContext) error {
+		result, err := GetVisitCollection().UpdateOne(ctx,
+			bson.M{"_id": visit.ID, "status": VisitBooked, "startsAt": visit.StartsAt},
+			bson.M{"$set": bson.M{"status": VisitCancelled, "cancelledBy": userID, "updatedAt": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrVisitNotUpcoming
+		}
+		return releaseVisitTime(ctx, visit.PropertyID, visit.StartsAt)
+	})
+	if err != nil {
+		return err
+	}
+	visit.Status, visit.CancelledBy = VisitCancelled, userID
+	return nil
+}
+
+func GetVisitByID(id string) (*Visit, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var visit Visit
+	if err := GetVisitCollection().FindOne(context.Background(), bson.M{"_id": objID}).Decode(&visit); err != nil {
+		return nil, err
+	}
+	return &visit, nil
+}
+
+// GetVisits returns the visits matching filter, soonest first. With upcoming only booked visits
+// that have not started are returned.
+func GetVisits(filter bson.M, upcoming bool) ([]*Visit, error) {
+	if upcoming {
+		filter["status"] = VisitBooked
+		filter["startsAt"] = bson.M{"$gt": time.Now()}
+	}
+	ctx := context.Background()
+	cursor, err := GetVisitCollection().Find(ctx, filter, options.Find().SetSort(bson.M{"startsAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	visits := []*Visit{}
+	if err := cursor.All(ctx, &visits); err != nil {
+		return nil, err
+	}
+	return visits, nil
+}
+
+// StreamVisitsDueForReminder opens a cursor over the booked visits starting within the reminder's
+// window that have not had it yet. The caller must close it.
+func StreamVisitsDueForReminder(ctx context.Context, reminder VisitReminder) (*mongo.Cursor, error) {
+	now := time.Now()
+	return GetVisitCollection().Find(ctx, bson.M{
+		"status":        VisitBooked,
+		"startsAt":      bson.M{"$gt": now, "$lte": now.Add(reminder.Before)},
+		"remindersSent": bson.M{"$ne": reminder.Name},
+	})
+}
+
+// ClaimVisitReminder marks a reminder, and every earlier one it makes redundant, as sent. It
+// reports false when another worker sent it first.
+func ClaimVisitReminder(visitID primitive.ObjectID, reminder VisitReminder) (bool, error) {
+	var covered []string
+	for _, r := range VisitReminders {
+		if r.Before >= reminder.Before {
+			covered = append(covered, r.Name)
+		}
+	}
+	result, err := GetVisitCollection().UpdateOne(context.Background(),
+		bson.M{"_id": visitID, "status": VisitBooked, "remindersSent": bson.M{"$ne": reminder.Name}},
+		bson.M{"$addToSet": bson.M{"remindersSent": bson.M{"$each": covered}}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
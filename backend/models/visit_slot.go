@@ -0,0 +1,230 @@
+package models
+
+import (
+	"backend/services"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	VisitSlotWeekly = "weekly"
+	VisitSlotOnce   = "once"
+
+	defaultVisitMinutes = 30
+	MaxVisitOpeningDays = 30 // how far ahead openings are listed
+	visitClockLayout    = "15:04"
+)
+
+// VisitTimeZone is the zone visiting slot dates and times are written in
+var VisitTimeZone = time.FixedZone("IST", 5*60*60+30*60)
+
+// VisitSlot is a window in which an owner shows a listing, split into visits of VisitMinutes.
+// Weekly slots repeat on Weekdays (0 is Sunday), one-off slots happen on Date.
+type VisitSlot struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PropertyID   string             `json:"propertyId" bson:"propertyId"`
+	Repeat       string             `json:"repeat" bson:"repeat"` // "weekly" or "once"
+	Weekdays     []int              `json:"weekdays,omitempty" bson:"weekdays,omitempty"`
+	Date         string             `json:"date,omitempty" bson:"date,omitempty"` // YYYY-MM-DD, see DateLayout
+	StartTime    string             `json:"startTime" bson:"startTime"`           // HH:MM in VisitTimeZone
+	EndTime      string             `json:"endTime" bson:"endTime"`
+	VisitMinutes int                `json:"visitMinutes" bson:"visitMinutes"`
+	Capacity     int                `json:"capacity" bson:"capacity"` // visits booked at the same time, e.g. for open houses
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// VisitOpening is a bookable visit time
+type VisitOpening struct {
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	Remaining int       `json:"remaining"`
+}
+
+func GetVisitSlotCollection() *mongo.Collection {
+	return services.GetMongoDB().Collection("visit_slots")
+}
+
+// Validate fills in the defaults and returns one message per broken rule
+func (s *VisitSlot) Validate() []string {
+	var validationErrors []string
+	if s.VisitMinutes == 0 {
+		s.VisitMinutes = defaultVisitMinutes
+	}
+	if s.Capacity == 0 {
+		s.Capacity = 1
+	}
+
+	switch s.Repeat {
+	case VisitSlotWeekly:
+		if len(s.Weekdays) == 0 {
+			validationErrors = append(validationErrors, "Weekly slots need at least one weekday")
+		}
+		for _, day := range s.Weekdays {
+			if day < 0 || day > 6 {
+				validationErrors = append(validationErrors, "Weekdays go from 0 (Sunday) to 6 (Saturday)")
+				break
+			}
+		}
+		s.Date = ""
+	case VisitSlotOnce:
+		if !ValidDate(s.Date) {
+			validationErrors = append(validationErrors, "One-off slots need a date like 2006-01-02")
+		}
+		s.Weekdays = nil
+	default:
+		validationErrors = append(validationErrors, "Repeat must be weekly or once")
+	}
+
+	start, errStart := time.Parse(visitClockLayout, s.StartTime)
+	end, errEnd := time.Parse(visitClockLayout, s.EndTime)
+	if errStart != nil || errEnd != nil {
+		validationErrors = append(validationErrors, "Start and end times must look like 18:30")
+	} else if end.Sub(start) < time.Duration(s.VisitMinutes)*time.Minute {
+		validationErrors = append(validationErrors, "A slot must fit at least one visit")
+	}
+	if s.VisitMinutes < 0 || s.Capacity < 0 {
+		validationErrors = append(validationErrors, "Visit length and capacity cannot be negative")
+	}
+	return validationErrors
+}
+
+// startTimes lists the visit start times the slot offers on a day (midnight in VisitTimeZone)
+func (s *VisitSlot) startTimes(day time.Time) []time.Time {
+	switch s.Repeat {
+	case VisitSlotWeekly:
+		weekday := int(day.Weekday())
+		found := false
+		for _, d := range s.Weekdays {
+			found = found || d == weekday
+		}
+		if !found {
+			return nil
+		}
+	case VisitSlotOnce:
+		if s.Date != day.Format(DateLayout) {
+			return nil
+		}
+	}
+
+	start, errStart := time.Parse(visitClockLayout, s.StartTime)
+	end, errEnd := time.Parse(visitClockLayout, s.EndTime)
+	if errStart != nil || errEnd != nil || s.VisitMinutes <= 0 {
+		return nil
+	}
+	length := time.Duration(s.VisitMinutes) * time.Minute
+	first := day.Add(time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute)
+	last := day.Add(time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute)
+
+	var times []time.Time
+	for t := first; !t.Add(length).After(last); t = t.Add(length) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// visitDay is midnight in VisitTimeZone of the day t falls on
+func visitDay(t time.Time) time.Time {
+	local := t.In(VisitTimeZone)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, VisitTimeZone)
+}
+
+func AddVisitSlot(slot *VisitSlot) error {
+	slot.ID = primitive.NewObjectID()
+	slot.CreatedAt = time.Now()
+	_, err := GetVisitSlotCollection().InsertOne(context.Background(), slot)
+	return err
+}
+
+// GetVisitSlots returns a listing's visiting slots, oldest first
+func GetVisitSlots(propertyID string) ([]*VisitSlot, error) {
+	ctx := context.Background()
+	cursor, err := GetVisitSlotCollection().Find(ctx, bson.M{"propertyId": propertyID}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	slots := []*VisitSlot{}
+	if err := cursor.All(ctx, &slots); err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// DeleteVisitSlot reports whether the slot existed. Visits already booked in it stay booked.
+func DeleteVisitSlot(propertyID string, slotID string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(slotID)
+	if err != nil {
+		return false, err
+	}
+	result, err := GetVisitSlotCollection().DeleteOne(context.Background(), bson.M{"_id": objID, "propertyId": propertyID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// visitOpeningAt finds the slot offering a visit at startsAt and returns the visit's end and how
+// many visits may be booked at that time. Overlapping slots offer the larger capacity.
+func visitOpeningAt(slots []*VisitSlot, startsAt time.Time) (time.Time, int, bool) {
+	day := visitDay(startsAt)
+	var endsAt time.Time
+	capacity := 0
+	for _, slot := range slots {
+		for _, t := range slot.startTimes(day) {
+			if t.Equal(startsAt) && slot.Capacity > capacity {
+				endsAt, capacity = t.Add(time.Duration(slot.VisitMinutes)*time.Minute), slot.Capacity
+			}
+		}
+	}
+	return endsAt, capacity, capacity > 0
+}
+
+// GetVisitOpenings lists the bookable visit times of a listing over the next days, from the
+// given time on, with the visits still free at each
+func GetVisitOpenings(propertyID string, from time.Time, days int) ([]VisitOpening, error) {
+	if days <= 0 || days > MaxVisitOpeningDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxVisitOpeningDays)
+	}
+	slots, err := GetVisitSlots(propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	openings := make(map[int64]*VisitOpening)
+	first := visitDay(from)
+	for i := 0; i < days; i++ {
+		day := first.AddDate(0, 0, i)
+		for _, slot := range slots {
+			for _, t := range slot.startTimes(day) {
+				if !t.After(from) {
+					continue
+				}
+				if existing, ok := openings[t.Unix()]; !ok || existing.Remaining < slot.Capacity {
+					openings[t.Unix()] = &VisitOpening{StartsAt: t, EndsAt: t.Add(time.Duration(slot.VisitMinutes) * time.Minute), Remaining: slot.Capacity}
+				}
+			}
+		}
+	}
+
+	booked, err := bookedVisitCounts(propertyID, from, first.AddDate(0, 0, days))
+	if err != nil {
+		return nil, err
+	}
+	result := []VisitOpening{}
+	for key, opening := range openings {
+		opening.Remaining -= booked[key]
+		if opening.Remaining > 0 {
+			result = append(result, *opening)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].StartsAt.Before(result[j].StartsAt) })
+	return result, nil
+}
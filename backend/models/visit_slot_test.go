@@ -0,0 +1,94 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// monday is 19 October 2026 in VisitTimeZone
+var monday = time.Date(2026, 10, 19, 0, 0, 0, 0, VisitTimeZone)
+
+func at(day time.Time, clock string) time.Time {
+	t, err := time.Parse(visitClockLayout, clock)
+	if err != nil {
+		panic(err)
+	}
+	return day.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+}
+
+func TestVisitSlotWeek(t *testing.T) {
+	evenings := &VisitSlot{Repeat: VisitSlotWeekly, Weekdays: []int{1, 3}, StartTime: "18:00", EndTime: "19:30"}
+	openHouse := &VisitSlot{Repeat: VisitSlotOnce, Date: "2026-10-24", StartTime: "10:00", EndTime: "11:50", VisitMinutes: 20, Capacity: 10}
+	for _, slot := range []*VisitSlot{evenings, openHouse} {
+		if errs := slot.Validate(); len(errs) > 0 {
+			t.Fatalf("Validate() = %q", errs)
+		}
+	}
+	if evenings.VisitMinutes != defaultVisitMinutes || evenings.Capacity != 1 {
+		t.Errorf("Validate() left %d minutes, capacity %d", evenings.VisitMinutes, evenings.Capacity)
+	}
+
+	visits := make(map[time.Weekday][]time.Time)
+	for i := 0; i < 7; i++ {
+		day := monday.AddDate(0, 0, i)
+		for _, slot := range []*VisitSlot{evenings, openHouse} {
+			visits[day.Weekday()] = append(visits[day.Weekday()], slot.startTimes(day)...)
+		}
+	}
+	wednesday := monday.AddDate(0, 0, 2)
+	saturday := monday.AddDate(0, 0, 5)
+	want := map[time.Weekday][]time.Time{
+		time.Monday:    {at(monday, "18:00"), at(monday, "18:30"), at(monday, "19:00")},
+		time.Wednesday: {at(wednesday, "18:00"), at(wednesday, "18:30"), at(wednesday, "19:00")},
+		// the last 20 minute visit has to end by 11:50
+		time.Saturday: {at(saturday, "10:00"), at(saturday, "10:20"), at(saturday, "10:40"), at(saturday, "11:00"), at(saturday, "11:20")},
+	}
+	for weekday, times := range visits {
+		if !reflect.DeepEqual(times, want[weekday]) {
+			t.Errorf("%s visits = %v, want %v", weekday, times, want[weekday])
+		}
+	}
+}
+
+func TestVisitSlotValidate(t *testing.T) {
+	slots := map[string]*VisitSlot{
+		"no weekdays":     {Repeat: VisitSlotWeekly, StartTime: "18:00", EndTime: "19:00"},
+		"weekday 7":       {Repeat: VisitSlotWeekly, Weekdays: []int{7}, StartTime: "18:00", EndTime: "19:00"},
+		"date in words":   {Repeat: VisitSlotOnce, Date: "next Sunday", StartTime: "18:00", EndTime: "19:00"},
+		"12-hour clock":   {Repeat: VisitSlotWeekly, Weekdays: []int{1}, StartTime: "6pm", EndTime: "7pm"},
+		"shorter visit":   {Repeat: VisitSlotWeekly, Weekdays: []int{1}, StartTime: "18:00", EndTime: "18:20"},
+		"monthly repeats": {Repeat: "monthly", StartTime: "18:00", EndTime: "19:00"},
+	}
+	for name, slot := range slots {
+		if errs := slot.Validate(); len(errs) != 1 {
+			t.Errorf("Validate(%s) = %q, want one error", name, errs)
+		}
+	}
+}
+
+func TestVisitOpeningAt(t *testing.T) {
+	slots := []*VisitSlot{
+		{Repeat: VisitSlotWeekly, Weekdays: []int{1}, StartTime: "18:00", EndTime: "20:00", VisitMinutes: 30, Capacity: 1},
+		{Repeat: VisitSlotOnce, Date: "2026-10-19", StartTime: "19:00", EndTime: "20:00", VisitMinutes: 60, Capacity: 5},
+	}
+
+	// a tenant booking from a phone set to UTC asks for 12:30Z, which is 18:00 IST
+	endsAt, capacity, ok := visitOpeningAt(slots, time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC))
+	if !ok || capacity != 1 || !endsAt.Equal(at(monday, "18:30")) {
+		t.Errorf("visitOpeningAt(18:00 IST) = %v, %d, %v", endsAt, capacity, ok)
+	}
+	// the open house overlaps the weekly slot at 19:00 and offers more places
+	endsAt, capacity, ok = visitOpeningAt(slots, at(monday, "19:00"))
+	if !ok || capacity != 5 || !endsAt.Equal(at(monday, "20:00")) {
+		t.Errorf("visitOpeningAt(19:00) = %v, %d, %v, want the open house", endsAt, capacity, ok)
+	}
+	for _, clock := range []string{"18:10", "09:00", "20:00"} {
+		if _, _, ok := visitOpeningAt(slots, at(monday, clock)); ok {
+			t.Errorf("visitOpeningAt(%s) offered a visit", clock)
+		}
+	}
+	if _, _, ok := visitOpeningAt(slots, at(monday.AddDate(0, 0, 7), "19:30")); !ok {
+		t.Errorf("visitOpeningAt(next Monday 19:30) offered no visit")
+	}
+}
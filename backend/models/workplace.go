@@ -0,0 +1,189 @@
+package models
+
+import (
+	"backend/services"
+	"backend/utils"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// WorkplacesVersion changes whenever the dataset below does, so stored distances get refreshed
+const WorkplacesVersion = 1
+
+// officeDistanceRadiusKm limits precomputed distances to offices a tenant could commute to
+const officeDistanceRadiusKm = 40
+
+// Workplace is an office or tech park tenants search by. Coordinates are the campus centre.
+type Workplace struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases,omitempty"`
+	City      string   `json:"city"`
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Popular   bool     `json:"popular,omitempty"` // every listing carries its distance to popular workplaces
+}
+
+// Workplaces is the curated list of offices and tech parks
+var Workplaces = []Workplace{
+	// Bengaluru
+	{ID: "embassy-tech-village", Name: "Embassy Tech Village", Aliases: []string{"ETV", "Embassy Tech Village ORR", "Devarabisanahalli", "Flipkart"}, City: "Bengaluru", Latitude: 12.9345, Longitude: 77.6905, Popular: true},
+	{ID: "prestige-tech-park", Name: "Prestige Tech Park", Aliases: []string{"PTP", "Kadubeesanahalli", "Kadubisanahalli"}, City: "Bengaluru", Latitude: 12.9395, Longitude: 77.6950, Popular: true},
+	{ID: "rmz-ecospace", Name: "RMZ Ecospace", Aliases: []string{"Ecospace", "Eco Space", "Bellandur Ecospace"}, City: "Bengaluru", Latitude: 12.9262, Longitude: 77.6805, Popular: true},
+	{ID: "rmz-ecoworld", Name: "RMZ Ecoworld", Aliases: []string{"Ecoworld", "Eco World"}, City: "Bengaluru", Latitude: 12.9237, Longitude: 77.6850, Popular: true},
+	{ID: "bagmane-tech-park", Name: "Bagmane Tech Park", Aliases: []string{"Bagmane", "CV Raman Nagar Tech Park", "Google Bagmane"}, City: "Bengaluru", Latitude: 12.9818, Longitude: 77.6620, Popular: true},
+	{ID: "manyata-tech-park", Name: "Manyata Tech Park", Aliases: []string{"Manyata", "Manyata Embassy Business Park", "MTP", "Nagawara"}, City: "Bengaluru", Latitude: 13.0450, Longitude: 77.6210, Popular: true},
+	{ID: "itpl", Name: "International Tech Park Bangalore", Aliases: []string{"ITPL", "ITPB", "Whitefield ITPL"}, City: "Bengaluru", Latitude: 12.9866, Longitude: 77.7370, Popular: true},
+	{ID: "epip-whitefield", Name: "EPIP Zone Whitefield", Aliases: []string{"EPIP", "EPIP Zone"}, City: "Bengaluru", Latitude: 12.9790, Longitude: 77.7285},
+	{ID: "electronic-city", Name: "Electronic City", Aliases: []string{"E City", "Ecity", "Electronic City Phase 1", "Infosys Electronic City"}, City: "Bengaluru", Latitude: 12.8450, Longitude: 77.6600, Popular: true},
+	{ID: "embassy-golf-links", Name: "Embassy GolfLinks Business Park", Aliases: []string{"EGL", "Embassy Golf Links", "Golf Links"}, City: "Bengaluru", Latitude: 12.9545, Longitude: 77.6440, Popular: true},
+	{ID: "global-village", Name: "Global Village Tech Park", Aliases: []string{"Global Village", "GVTP"}, City: "Bengaluru", Latitude: 12.9195, Longitude: 77.4995},
+	{ID: "wtc-bangalore", Name: "World Trade Center Bangalore", Aliases: []string{"WTC Bangalore", "Brigade Gateway", "Amazon WTC"}, City: "Bengaluru", Latitude: 13.0125, Longitude: 77.5550},
+	{ID: "cessna-business-park", Name: "Cessna Business Park", Aliases: []string{"Cessna", "Kadubeesanahalli Cessna"}, City: "Bengaluru", Latitude: 12.9365, Longitude: 77.6960},
+	{ID: "rmz-infinity", Name: "RMZ Infinity", Aliases: []string{"Infinity Old Madras Road"}, City: "Bengaluru", Latitude: 12.9935, Longitude: 77.6600},
+
+	// Hyderabad
+	{ID: "hitec-city", Name: "HITEC City", Aliases: []string{"Hitech City", "Cyber Towers", "Madhapur"}, City: "Hyderabad", Latitude: 17.4500, Longitude: 78.3810, Popular: true},
+	{ID: "mindspace-hyderabad", Name: "Mindspace Madhapur", Aliases: []string{"Mindspace Hyderabad", "Raheja Mindspace"}, City: "Hyderabad", Latitude: 17.4415, Longitude: 78.3800, Popular: true},
+	{ID: "financial-district", Name: "Financial District", Aliases: []string{"Nanakramguda", "Gachibowli Financial District"}, City: "Hyderabad", Latitude: 17.4155, Longitude: 78.3425, Popular: true},
+
+	// Pune
+	{ID: "hinjewadi", Name: "Rajiv Gandhi Infotech Park", Aliases: []string{"Hinjewadi", "Hinjawadi", "Hinjewadi Phase 1"}, City: "Pune", Latitude: 18.5910, Longitude: 73.7390, Popular: true},
+	{ID: "eon-it-park", Name: "EON IT Park", Aliases: []string{"EON Kharadi", "Kharadi"}, City: "Pune", Latitude: 18.5515, Longitude: 73.9470, Popular: true},
+
+	// Chennai
+	{ID: "tidel-park", Name: "Tidel Park", Aliases: []string{"Tidel Park Chennai", "Taramani"}, City: "Chennai", Latitude: 12.9895, Longitude: 80.2485, Popular: true},
+	{ID: "dlf-it-park-chennai", Name: "DLF IT Park Chennai", Aliases: []string{"DLF Manapakkam", "Ramapuram DLF"}, City: "Chennai", Latitude: 13.0220, Longitude: 80.1770},
+
+	// NCR
+	{ID: "cyber-city", Name: "DLF Cyber City", Aliases: []string{"Cyber City", "Cyber Hub", "Gurgaon Cyber City"}, City: "Gurugram", Latitude: 28.4950, Longitude: 77.0890, Popular: true},
+	{ID: "noida-sector-62", Name: "Noida Sector 62", Aliases: []string{"Sector 62", "Noida 62"}, City: "Noida", Latitude: 28.6210, Longitude: 77.3640, Popular: true},
+}
+
+var workplacesByKey = func() map[string]*Workplace {
+	byKey := make(map[string]*Workplace)
+	for i := range Workplaces {
+		w := &Workplaces[i]
+		byKey[w.ID] = w
+		byKey[utils.NormalizeText(w.Name)] = w
+		for _, alias := range w.Aliases {
+			byKey[utils.NormalizeText(alias)] = w
+		}
+	}
+	return byKey
+}()
+
+// FindWorkplace resolves an ID, name or alias, ignoring case and punctuation
+func FindWorkplace(query string) *Workplace {
+	if w, ok := workplacesByKey[strings.TrimSpace(query)]; ok {
+		return w
+	}
+	return workplacesByKey[utils.NormalizeText(query)]
+}
+
+// SearchWorkplaces returns the workplaces whose name or alias contains query, popular ones first
+func SearchWorkplaces(query string, city string) []Workplace {
+	query = utils.NormalizeText(query)
+	city = canonicalCity(city)
+
+	var matches []Workplace
+	for _, w := range Workplaces {
+		if city != "" && canonicalCity(w.City) != city {
+			continue
+		}
+		if query == "" || strings.Contains(utils.NormalizeText(w.Name), query) || aliasContains(w.Aliases, query) {
+			matches = append(matches, w)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Popular && !matches[j].Popular })
+	return matches
+}
+
+// canonicalCity normalizes a city name so "Bangalore" and "Bengaluru" compare equal
+func canonicalCity(city string) string {
+	if known := services.LookupCity(city); known != nil {
+		return known.ID
+	}
+	return utils.NormalizeText(city)
+}
+
+func aliasContains(aliases []string, query string) bool {
+	for _, alias := range aliases {
+		if strings.Contains(utils.NormalizeText(alias), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkplaceLandmarks lets the geocoder resolve "near Embassy Tech Village" style locations
+func WorkplaceLandmarks() []services.GazetteerPlace {
+	landmarks := make([]services.GazetteerPlace, 0, len(Workplaces))
+	for _, w := range Workplaces {
+		landmarks = append(landmarks, services.GazetteerPlace{
+			Name:      w.Name,
+			Aliases:   w.Aliases,
+			Kind:      "landmark",
+			City:      w.City,
+			Latitude:  w.Latitude,
+			Longitude: w.Longitude,
+		})
+	}
+	return landmarks
+}
+
+// DistanceKm is the straight-line distance from the workplace to a point
+func (w *Workplace) DistanceKm(latitude, longitude float64) float64 {
+	return utils.RoundKm(utils.HaversineKm(w.Latitude, w.Longitude, latitude, longitude))
+}
+
+// popularOfficeDistances are the distances stored on a listing, keyed by workplace ID. Offices
+// in other cities are left out.
+func popularOfficeDistances(latitude, longitude float64) map[string]float64 {
+	distances := make(map[string]float64)
+	for i := range Workplaces {
+		w := &Workplaces[i]
+		if !w.Popular {
+			continue
+		}
+		if km := w.DistanceKm(latitude, longitude); km <= officeDistanceRadiusKm {
+			distances[w.ID] = km
+		}
+	}
+	return distances
+}
+
+// RefreshOfficeDistances recomputes DistancesFromOffices on listings stored before the current
+// WorkplacesVersion and returns how many were updated
+func RefreshOfficeDistances() (int, error) {
+	ctx := context.Background()
+	collection := GetPropertyCollection()
+	filter := bson.M{"geo": bson.M{"$exists": true}, "officeDistancesVersion": bson.M{"$ne": WorkplacesVersion}}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var property Property
+		if err := cursor.Decode(&property); err != nil {
+			return updated, err
+		}
+		update := bson.M{"$set": bson.M{
+			"distancesFromOffices":   popularOfficeDistances(property.Latitude, property.Longitude),
+			"officeDistancesVersion": WorkplacesVersion,
+			"updatedAt":              time.Now(),
+		}}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": property.ID}, update); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"backend/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterAdminRoutes(r *mux.Router) {
+	moderationRouter := r.PathPrefix("/moderation").Subrouter()
+	moderationRouter.HandleFunc("/properties", controllers.GetHeldProperties).Methods("GET")
+	moderationRouter.HandleFunc("/properties/{id}/approve", controllers.ApproveProperty).Methods("POST")
+	moderationRouter.HandleFunc("/properties/{id}/reject", controllers.RejectProperty).Methods("POST")
+	moderationRouter.HandleFunc("/reports", controllers.GetReportQueue).Methods("GET")
+	moderationRouter.HandleFunc("/reports/{id}", controllers.ResolveReports).Methods("POST")
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"backend/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterExportRoutes registers the listing export on the authenticated API router
+func RegisterExportRoutes(r *mux.Router) {
+	exportRouter := r.PathPrefix("/export").Subrouter()
+	exportRouter.HandleFunc("/properties", controllers.ExportProperties).Methods("GET", "POST")
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterMediaRoutes serves uploads from the local and memory storage drivers, S3 serves its own
+func RegisterMediaRoutes(r *mux.Router) {
+	if !utils.StoreServesMedia() {
+		return
+	}
+	r.HandleFunc("/media/{key:.+}", controllers.ServeMedia).Methods("GET")
+	r.HandleFunc("/media/{key:.+}", controllers.ReceiveMedia).Methods("PUT")
+}
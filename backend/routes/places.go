@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middlewares"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// geocodeRequestsPerMinute is what one client IP may spend on the geocoding lookups, which may
+// reach a rate-limited external provider
+const geocodeRequestsPerMinute = 30
+
+// RegisterPlaceRoutes registers the public workplace and geocoding lookups
+func RegisterPlaceRoutes(r *mux.Router) {
+	r.HandleFunc("/api/workplaces", controllers.GetWorkplaces).Methods("GET")
+
+	geocodeLimit := middlewares.RateLimitMiddleware(geocodeRequestsPerMinute, time.Minute)
+	r.Handle("/api/geocode", geocodeLimit(http.HandlerFunc(controllers.Geocode))).Methods("GET")
+	r.Handle("/api/geocode/reverse", geocodeLimit(http.HandlerFunc(controllers.ReverseGeocode))).Methods("GET")
+}
@@ -19,6 +19,16 @@ func RegisterPropertyRoutes(r *mux.Router) {
 	protectedPropertyRouter.Use(middlewares.AuthMiddleware)              // AuthMiddleware to this subrouter only
 
 	protectedPropertyRouter.HandleFunc("/", controllers.AddProperty).Methods("POST")
+	protectedPropertyRouter.HandleFunc("/import", controllers.ImportProperties).Methods("POST")
+	protectedPropertyRouter.HandleFunc("/import/{jobId}", controllers.GetImportJob).Methods("GET")
+	protectedPropertyRouter.HandleFunc("/import/{jobId}/resume", controllers.ResumeImportJob).Methods("POST")
+	protectedPropertyRouter.HandleFunc("/{id}/processing", controllers.GetPropertyProcessing).Methods("GET")
+	protectedPropertyRouter.HandleFunc("/{id}/publish", controllers.PublishProperty).Methods("POST")
+	protectedPropertyRouter.HandleFunc("/{id}/quality", controllers.GetPropertyQuality).Methods("GET")
+	protectedPropertyRouter.HandleFunc("/{id}/contact", controllers.RevealContact).Methods("POST")
+	protectedPropertyRouter.HandleFunc("/{id}/report", controllers.ReportProperty).Methods("POST")
+	protectedPropertyRouter.HandleFunc("/{id}/price-alerts", controllers.SubscribePriceAlert).Methods("POST")
+	protectedPropertyRouter.HandleFunc("/{id}/price-alerts", controllers.UnsubscribePriceAlert).Methods("DELETE")
 	protectedPropertyRouter.HandleFunc("/{id}", controllers.UpdateProperty).Methods("PUT")
 	protectedPropertyRouter.HandleFunc("/{id}", controllers.DeleteProperty).Methods("DELETE")
 	protectedPropertyRouter.HandleFunc("/uploadfile", controllers.UploadFile).Methods("POST")
@@ -3,6 +3,7 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middlewares"
+	"net/http"
 
 	"github.com/gorilla/mux"
 )
@@ -12,13 +13,19 @@ func RegisterRoutes(r *mux.Router) {
 	RegisterSearchRoutes(r)
 	RegisterViewsRoutes(r)
 	RegisterAIRoutes(r)
+	RegisterMediaRoutes(r)
+	RegisterPlaceRoutes(r)
+	RegisterSocietyRoutes(r)
 
 	// Public Property Routes
 	r.HandleFunc("/api/properties", controllers.GetProperties).Methods("GET")
 	r.HandleFunc("/api/properties/top", controllers.GetTopProperties).Methods("GET")
-	r.HandleFunc("/api/properties/{id}", controllers.GetPropertyByID).Methods("GET")
+	// needs a user for their offices, and must come before /api/properties/{id}
+	r.Handle("/api/properties/compare", middlewares.AuthMiddleware(http.HandlerFunc(controllers.CompareProperties))).Methods("GET")
+	r.Handle("/api/properties/{id}", middlewares.OptionalAuthMiddleware(http.HandlerFunc(controllers.GetPropertyByID))).Methods("GET")
+	r.HandleFunc("/api/properties/{id}/similar", controllers.GetSimilarProperties).Methods("GET")
 	r.HandleFunc("/api/reviews", controllers.GetReviews).Methods("GET")
-	r.HandleFunc("/api/properties/{id}/view", controllers.UpdatePropertyViews).Methods("POST")
+	r.Handle("/api/properties/{id}/view", middlewares.OptionalAuthMiddleware(http.HandlerFunc(controllers.UpdatePropertyViews))).Methods("POST")
 
 	api := r.PathPrefix("/api").Subrouter()
 	api.Use(middlewares.AuthMiddleware)
@@ -27,8 +34,13 @@ func RegisterRoutes(r *mux.Router) {
 	RegisterPropertyRoutes(api)
 	RegisterChatRoutes(api)
 	RegisterUserRoutes(api)
+	RegisterUploadRoutes(api)
+	RegisterExportRoutes(api)
+	RegisterVisitRoutes(api)
+	RegisterShortlistRoutes(api)
+	api.HandleFunc("/feed", controllers.GetFeed).Methods("GET")
 
-	// adminAPI := api.PathPrefix("/admin").Subrouter()
-	// adminAPI.Use(middlewares.RoleMiddleware([]string{"admin"}))
-	// RegisterAdminRoutes(adminAPI)
+	adminAPI := api.PathPrefix("/admin").Subrouter()
+	adminAPI.Use(middlewares.RoleMiddleware([]string{"admin"}))
+	RegisterAdminRoutes(adminAPI)
 }
@@ -2,13 +2,15 @@ package routes
 
 import (
 	"backend/controllers"
+	"backend/middlewares"
 
 	"github.com/gorilla/mux"
 )
 
 func RegisterSearchRoutes(r *mux.Router) {
 	searchRouter := r.PathPrefix("/search").Subrouter()
-	// searchRouter.Use(middlewares.AuthMiddleware)
+	// signed-in searches are recorded for the home feed
+	searchRouter.Use(middlewares.OptionalAuthMiddleware)
 	searchRouter.HandleFunc("", controllers.SearchProperties).Methods("POST", "GET")
 	searchRouter.HandleFunc("/popular-places", controllers.GetPopularPlaces).Methods("GET")
 }
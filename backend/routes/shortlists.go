@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"backend/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterShortlistRoutes(r *mux.Router) {
+	shortlistRouter := r.PathPrefix("/shortlists").Subrouter()
+	shortlistRouter.HandleFunc("", controllers.GetShortlists).Methods("GET")
+	shortlistRouter.HandleFunc("", controllers.CreateShortlist).Methods("POST")
+	shortlistRouter.HandleFunc("/{shortlistId}", controllers.GetShortlist).Methods("GET")
+	shortlistRouter.HandleFunc("/{shortlistId}", controllers.RenameShortlist).Methods("PUT")
+	shortlistRouter.HandleFunc("/{shortlistId}", controllers.DeleteShortlist).Methods("DELETE")
+	shortlistRouter.HandleFunc("/{shortlistId}/properties/{propertyId}", controllers.SaveShortlistItem).Methods("PUT")
+	shortlistRouter.HandleFunc("/{shortlistId}/properties/{propertyId}", controllers.RemoveShortlistItem).Methods("DELETE")
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middlewares"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterSocietyRoutes(r *mux.Router) {
+	// society pages and autocomplete are public
+	r.HandleFunc("/api/societies", controllers.SuggestSocieties).Methods("GET")
+	r.HandleFunc("/api/societies/{id}", controllers.GetSociety).Methods("GET")
+
+	societyRouter := r.PathPrefix("/api/societies").Subrouter()
+	societyRouter.Use(middlewares.AuthMiddleware)
+	societyRouter.HandleFunc("", controllers.CreateSociety).Methods("POST")
+	societyRouter.HandleFunc("/{id}", controllers.UpdateSociety).Methods("PUT")
+	societyRouter.HandleFunc("/{id}/reviews", controllers.ReviewSociety).Methods("POST")
+}
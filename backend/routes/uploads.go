@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"backend/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterUploadRoutes(r *mux.Router) {
+	uploadRouter := r.PathPrefix("/uploads").Subrouter()
+	uploadRouter.HandleFunc("/presign", controllers.PresignUpload).Methods("POST")
+	uploadRouter.HandleFunc("/complete", controllers.CompleteUpload).Methods("POST")
+}
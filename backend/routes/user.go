@@ -9,4 +9,7 @@ func RegisterUserRoutes(r *mux.Router) {
 	userRouter := r.PathPrefix("/profile").Subrouter()
 	userRouter.HandleFunc("", controllers.GetUserProfile).Methods("GET")
 	userRouter.HandleFunc("/update", controllers.UpdateUserProfile).Methods("POST")
+	userRouter.HandleFunc("/price-alerts", controllers.GetPriceAlerts).Methods("GET")
+	userRouter.HandleFunc("/visits", controllers.GetUserVisits).Methods("GET")
+	userRouter.HandleFunc("/leads", controllers.GetLeads).Methods("GET")
 }
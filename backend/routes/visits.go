@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"backend/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterVisitRoutes(r *mux.Router) {
+	r.HandleFunc("/properties/{id}/visit-slots", controllers.GetVisitSlots).Methods("GET")
+	r.HandleFunc("/properties/{id}/visit-slots", controllers.AddVisitSlot).Methods("POST")
+	r.HandleFunc("/properties/{id}/visit-slots/{slotId}", controllers.DeleteVisitSlot).Methods("DELETE")
+	r.HandleFunc("/properties/{id}/visits", controllers.GetPropertyVisits).Methods("GET")
+	r.HandleFunc("/properties/{id}/visits", controllers.BookVisit).Methods("POST")
+	r.HandleFunc("/properties/{id}/visits/{visitId}", controllers.RescheduleVisit).Methods("PUT")
+	r.HandleFunc("/properties/{id}/visits/{visitId}/cancel", controllers.CancelVisit).Methods("POST")
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// GeoResult is one resolved place
+type GeoResult struct {
+	Name       string  `json:"name"`           // the place that matched, e.g. "HSR Layout"
+	Kind       string  `json:"kind,omitempty"` // "locality", "city" or "landmark"
+	Area       string  `json:"area,omitempty"`
+	City       string  `json:"city,omitempty"`
+	State      string  `json:"state,omitempty"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Confidence float64 `json:"confidence"` // 0-1, 1 when the whole query named the place
+	Source     string  `json:"source"`
+}
+
+// Geocoder turns free-text places into coordinates and back
+type Geocoder interface {
+	Geocode(ctx context.Context, query string) (*GeoResult, error)
+	ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeoResult, error)
+}
+
+var (
+	ErrNoGeocodeMatch = errors.New("no matching place found")
+	ErrGeocoderBusy   = errors.New("geocoder is busy, try again shortly")
+)
+
+// DefaultGeocoder is the bundled gazetteer, followed by the provider in GEOCODER_PROVIDER if set
+var DefaultGeocoder Geocoder
+
+// InitGeocoder loads the gazetteer plus any extra landmarks (e.g. the workplaces dataset) and
+// plugs in the optional external provider: GEOCODER_PROVIDER="" (offline only) or "nominatim".
+// External lookups are cached; the gazetteer is in memory and needs no cache.
+func InitGeocoder(landmarks []GazetteerPlace) error {
+	gazetteer, err := newGazetteer(landmarks)
+	if err != nil {
+		return err
+	}
+
+	provider := os.Getenv("GEOCODER_PROVIDER")
+	switch provider {
+	case "", "offline":
+		DefaultGeocoder = gazetteer
+	case "nominatim":
+		DefaultGeocoder = chainGeocoder{gazetteer, newCachedGeocoder(newNominatimGeocoder(), "nominatim")}
+	default:
+		return fmt.Errorf("unknown GEOCODER_PROVIDER %q, expected nominatim or nothing", provider)
+	}
+	utils.Logger.Printf("Geocoder: gazetteer with %d places, external provider %q", gazetteer.size(), provider)
+	return nil
+}
+
+// chainGeocoder asks each geocoder in turn until one is confident, keeping the best answer, so a
+// query the gazetteer only knows the city of still reaches the external provider
+type chainGeocoder []Geocoder
+
+const confidentMatch = 0.8
+
+func (c chainGeocoder) Geocode(ctx context.Context, query string) (*GeoResult, error) {
+	return c.best(func(g Geocoder) (*GeoResult, error) { return g.Geocode(ctx, query) })
+}
+
+func (c chainGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeoResult, error) {
+	return c.best(func(g Geocoder) (*GeoResult, error) { return g.ReverseGeocode(ctx, latitude, longitude) })
+}
+
+func (c chainGeocoder) best(lookup func(Geocoder) (*GeoResult, error)) (*GeoResult, error) {
+	var best *GeoResult
+	var lastErr error = ErrNoGeocodeMatch
+	for _, g := range c {
+		result, err := lookup(g)
+		if err != nil {
+			if !errors.Is(err, ErrNoGeocodeMatch) {
+				utils.Logger.Printf("Geocoder %T failed: %v", g, err)
+				lastErr = err
+			}
+			continue
+		}
+		if best == nil || result.Confidence > best.Confidence {
+			best = result
+		}
+		if best.Confidence >= confidentMatch {
+			break
+		}
+	}
+	if best != nil {
+		return best, nil
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"backend/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	geocodeCachePrefix = "geocode:"
+	geocodeHitTTL      = 30 * 24 * time.Hour
+	geocodeMissTTL     = 24 * time.Hour // places get added to providers, so retry misses daily
+)
+
+// cachedGeocoder remembers a slow or rate-limited geocoder's answers, misses included. It uses
+// Redis when it is enabled and the geocode_cache collection otherwise.
+type cachedGeocoder struct {
+	next      Geocoder
+	namespace string
+}
+
+func newCachedGeocoder(next Geocoder, namespace string) *cachedGeocoder {
+	return &cachedGeocoder{next: next, namespace: namespace}
+}
+
+// geocodeCacheEntry is a cached answer; a nil Result is a cached miss
+type geocodeCacheEntry struct {
+	Key       string     `bson:"_id" json:"-"`
+	Result    *GeoResult `bson:"result,omitempty" json:"result,omitempty"`
+	ExpiresAt time.Time  `bson:"expiresAt" json:"-"`
+}
+
+func (c *cachedGeocoder) Geocode(ctx context.Context, query string) (*GeoResult, error) {
+	key := fmt.Sprintf("%s%s:fwd:%s", geocodeCachePrefix, c.namespace, utils.NormalizeText(query))
+	return c.cached(ctx, key, func() (*GeoResult, error) { return c.next.Geocode(ctx, query) })
+}
+
+func (c *cachedGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeoResult, error) {
+	// ~10 m cells, nearby points share an answer
+	key := fmt.Sprintf("%s%s:rev:%.4f,%.4f", geocodeCachePrefix, c.namespace, latitude, longitude)
+	return c.cached(ctx, key, func() (*GeoResult, error) { return c.next.ReverseGeocode(ctx, latitude, longitude) })
+}
+
+func (c *cachedGeocoder) cached(ctx context.Context, key string, lookup func() (*GeoResult, error)) (*GeoResult, error) {
+	if entry, ok := readGeocodeCache(ctx, key); ok {
+		if entry.Result == nil {
+			return nil, ErrNoGeocodeMatch
+		}
+		return entry.Result, nil
+	}
+
+	result, err := lookup()
+	if err != nil && !errors.Is(err, ErrNoGeocodeMatch) {
+		return nil, err // provider errors are not cached
+	}
+	ttl := geocodeHitTTL
+	if result == nil {
+		ttl = geocodeMissTTL
+	}
+	if cacheErr := writeGeocodeCache(ctx, geocodeCacheEntry{Key: key, Result: result, ExpiresAt: time.Now().Add(ttl)}, ttl); cacheErr != nil {
+		utils.Logger.Printf("Failed to cache geocode result for %s: %v", key, cacheErr)
+	}
+	return result, err
+}
+
+var geocodeCacheIndexOnce sync.Once
+
+func geocodeCacheCollection() *mongo.Collection {
+	collection := GetMongoDB().Collection("geocode_cache")
+	geocodeCacheIndexOnce.Do(func() {
+		index := mongo.IndexModel{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)}
+		if _, err := collection.Indexes().CreateOne(context.Background(), index); err != nil {
+			utils.Logger.Printf("Failed to create geocode cache TTL index: %v", err)
+		}
+	})
+	return collection
+}
+
+func readGeocodeCache(ctx context.Context, key string) (*geocodeCacheEntry, bool) {
+	var entry geocodeCacheEntry
+	if IsRedisEnabled() {
+		val, err := GetFromRedis(ctx, key)
+		if err != nil || json.Unmarshal([]byte(val), &entry) != nil {
+			return nil, false
+		}
+		return &entry, true
+	}
+
+	err := geocodeCacheCollection().FindOne(ctx, bson.M{"_id": key, "expiresAt": bson.M{"$gt": time.Now()}}).Decode(&entry)
+	if err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func writeGeocodeCache(ctx context.Context, entry geocodeCacheEntry, ttl time.Duration) error {
+	if IsRedisEnabled() {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return SetToRedis(ctx, entry.Key, string(data), ttl)
+	}
+
+	_, err := geocodeCacheCollection().ReplaceOne(ctx, bson.M{"_id": entry.Key}, entry, options.Replace().SetUpsert(true))
+	return err
+}
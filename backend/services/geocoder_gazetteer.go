@@ -0,0 +1,211 @@
+package services
+
+import (
+	"backend/utils"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:embed data/gazetteer.csv
+var gazetteerCSV []byte
+
+const (
+	maxPlaceWords      = 5  // longest place name, in words, matched inside a query
+	reverseLocalityKm  = 5  // a point further than this from every locality resolves to its city
+	reverseCityKm      = 40 // and further than this from every city resolves to nothing
+	gazetteerSource    = "gazetteer"
+	placeKindCity      = "city"
+	placeKindLocality  = "locality"
+	placeKindLandmark  = "landmark"
+	placeMatchWordRank = 10
+)
+
+// GazetteerPlace is one entry of the offline gazetteer
+type GazetteerPlace struct {
+	Name      string
+	Aliases   []string
+	Kind      string // "locality", "city" or "landmark"
+	City      string
+	State     string
+	Latitude  float64
+	Longitude float64
+}
+
+// gazetteer resolves places from the bundled list of Indian cities and localities without network
+type gazetteer struct {
+	places []GazetteerPlace
+	byKey  map[string][]*GazetteerPlace // normalized name or alias -> places
+	cities map[string]*GazetteerPlace   // normalized city -> its city entry
+}
+
+// parseGazetteer reads the bundled gazetteer CSV
+func parseGazetteer() ([]GazetteerPlace, error) {
+	records, err := csv.NewReader(bytes.NewReader(gazetteerCSV)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid gazetteer: %w", err)
+	}
+
+	var places []GazetteerPlace
+	for i, record := range records[1:] {
+		if len(record) != 7 {
+			return nil, fmt.Errorf("invalid gazetteer line %d", i+2)
+		}
+		lat, errLat := strconv.ParseFloat(record[5], 64)
+		lng, errLng := strconv.ParseFloat(record[6], 64)
+		if errLat != nil || errLng != nil {
+			return nil, fmt.Errorf("invalid coordinates on gazetteer line %d", i+2)
+		}
+		var aliases []string
+		if record[1] != "" {
+			aliases = strings.Split(record[1], "|")
+		}
+		places = append(places, GazetteerPlace{
+			Name: record[0], Aliases: aliases, Kind: record[2], City: record[3], State: record[4], Latitude: lat, Longitude: lng,
+		})
+	}
+	return places, nil
+}
+
+func newGazetteer(landmarks []GazetteerPlace) (*gazetteer, error) {
+	places, err := parseGazetteer()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &gazetteer{places: append(places, landmarks...), byKey: make(map[string][]*GazetteerPlace), cities: make(map[string]*GazetteerPlace)}
+	for i := range g.places {
+		place := &g.places[i]
+		for _, name := range append([]string{place.Name}, place.Aliases...) {
+			key := utils.NormalizeText(name)
+			g.byKey[key] = append(g.byKey[key], place)
+		}
+		if place.Kind == placeKindCity {
+			g.cities[utils.NormalizeText(place.City)] = place
+		}
+	}
+	// landmarks only know their city, borrow the state from it
+	for i := range g.places {
+		if place := &g.places[i]; place.State == "" {
+			if city := g.cities[utils.NormalizeText(place.City)]; city != nil {
+				place.State = city.State
+			}
+		}
+	}
+	return g, nil
+}
+
+func (g *gazetteer) size() int {
+	return len(g.places)
+}
+
+// Geocode finds the most specific known place named anywhere in the query, so "near Embassy Tech
+// Village" and "HSR Layout Sector 2, Bangalore" both resolve. A city named in the query breaks
+// ties between places of the same name.
+func (g *gazetteer) Geocode(ctx context.Context, query string) (*GeoResult, error) {
+	words := strings.Fields(utils.NormalizeText(query))
+	if len(words) == 0 {
+		return nil, ErrNoGeocodeMatch
+	}
+
+	type match struct {
+		place *GazetteerPlace
+		words int
+	}
+	var matches []match
+	cityNamed := make(map[string]bool)
+	for start := range words {
+		for n := 1; n <= maxPlaceWords && start+n <= len(words); n++ {
+			for _, place := range g.byKey[strings.Join(words[start:start+n], " ")] {
+				matches = append(matches, match{place, n})
+				if place.Kind == placeKindCity {
+					cityNamed[place.City] = true
+				}
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return nil, ErrNoGeocodeMatch
+	}
+
+	var best *GazetteerPlace
+	bestWords, bestRank := 0, -1
+	for _, m := range matches {
+		rank := m.words * placeMatchWordRank
+		switch m.place.Kind {
+		case placeKindLandmark:
+			rank += 3
+		case placeKindLocality:
+			rank += 2
+		}
+		if cityNamed[m.place.City] {
+			rank += 5
+		}
+		if rank > bestRank {
+			best, bestWords, bestRank = m.place, m.words, rank
+		}
+	}
+
+	confidence := 0.8
+	if bestWords == len(words) {
+		confidence = 1
+	} else if best.Kind == placeKindCity {
+		confidence = 0.5 // only the city was recognised
+	}
+	return g.result(best, confidence), nil
+}
+
+// ReverseGeocode returns the nearest locality, or the nearest city when no locality is close
+func (g *gazetteer) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeoResult, error) {
+	var nearestLocality, nearestCity *GazetteerPlace
+	localityKm, cityKm := float64(reverseLocalityKm), float64(reverseCityKm)
+	for i := range g.places {
+		place := &g.places[i]
+		km := utils.HaversineKm(latitude, longitude, place.Latitude, place.Longitude)
+		switch place.Kind {
+		case placeKindLocality:
+			if km < localityKm {
+				nearestLocality, localityKm = place, km
+			}
+		case placeKindCity:
+			if km < cityKm {
+				nearestCity, cityKm = place, km
+			}
+		}
+	}
+
+	switch {
+	case nearestLocality != nil:
+		return g.result(nearestLocality, 1-localityKm/reverseLocalityKm*0.5), nil
+	case nearestCity != nil:
+		return g.result(nearestCity, 0.5), nil
+	}
+	return nil, ErrNoGeocodeMatch
+}
+
+func (g *gazetteer) result(place *GazetteerPlace, confidence float64) *GeoResult {
+	result := &GeoResult{
+		Name:       place.Name,
+		Kind:       place.Kind,
+		City:       place.City,
+		State:      place.State,
+		Latitude:   place.Latitude,
+		Longitude:  place.Longitude,
+		Confidence: confidence,
+		Source:     gazetteerSource,
+	}
+	switch place.Kind {
+	case placeKindLocality:
+		result.Area = place.Name
+	case placeKindLandmark:
+		// a landmark's area is the locality it sits in
+		if locality, err := g.ReverseGeocode(context.Background(), place.Latitude, place.Longitude); err == nil && locality.Kind == placeKindLocality {
+			result.Area = locality.Area
+		}
+	}
+	return result
+}
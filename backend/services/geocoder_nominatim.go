@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNominatimURL = "https://nominatim.openstreetmap.org"
+	nominatimTimeout    = 5 * time.Second
+	nominatimInterval   = time.Second // the public server allows one request per second
+	nominatimMaxQueue   = 5           // lookups that may wait for a slot, later ones get ErrGeocoderBusy
+)
+
+// nominatimGeocoder looks places up in OpenStreetMap through a Nominatim server, GEOCODER_URL
+// (the public server by default). Results are restricted to India.
+type nominatimGeocoder struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+
+	mu       sync.Mutex
+	nextSlot time.Time // when the next request may be sent
+}
+
+type nominatimPlace struct {
+	Lat         string  `json:"lat"`
+	Lon         string  `json:"lon"`
+	Name        string  `json:"name"`
+	DisplayName string  `json:"display_name"`
+	Importance  float64 `json:"importance"`
+	Address     struct {
+		Suburb        string `json:"suburb"`
+		Neighbourhood string `json:"neighbourhood"`
+		CityDistrict  string `json:"city_district"`
+		City          string `json:"city"`
+		Town          string `json:"town"`
+		State         string `json:"state"`
+	} `json:"address"`
+}
+
+func newNominatimGeocoder() *nominatimGeocoder {
+	baseURL := os.Getenv("GEOCODER_URL")
+	if baseURL == "" {
+		baseURL = defaultNominatimURL
+	}
+	userAgent := os.Getenv("GEOCODER_USER_AGENT")
+	if userAgent == "" {
+		userAgent = "livelywalls-backend"
+	}
+	return &nominatimGeocoder{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		userAgent: userAgent,
+		client:    &http.Client{Timeout: nominatimTimeout},
+	}
+}
+
+func (n *nominatimGeocoder) Geocode(ctx context.Context, query string) (*GeoResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "jsonv2")
+	params.Set("addressdetails", "1")
+	params.Set("countrycodes", "in")
+	params.Set("limit", "1")
+
+	var places []nominatimPlace
+	if err := n.get(ctx, "/search", params, &places); err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, ErrNoGeocodeMatch
+	}
+	return places[0].result(places[0].Importance)
+}
+
+func (n *nominatimGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeoResult, error) {
+	params := url.Values{}
+	params.Set("lat", strconv.FormatFloat(latitude, 'f', 6, 64))
+	params.Set("lon", strconv.FormatFloat(longitude, 'f', 6, 64))
+	params.Set("format", "jsonv2")
+	params.Set("addressdetails", "1")
+	params.Set("zoom", "16") // suburb level
+
+	var place nominatimPlace
+	if err := n.get(ctx, "/reverse", params, &place); err != nil {
+		return nil, err
+	}
+	if place.Lat == "" {
+		return nil, ErrNoGeocodeMatch
+	}
+	return place.result(1)
+}
+
+func (n *nominatimGeocoder) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	if err := n.wait(ctx); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", n.userAgent)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// wait spaces requests out to respect the server's rate limit. It books the next free slot and
+// sleeps outside the lock; when nominatimMaxQueue lookups are already waiting it gives up at once.
+func (n *nominatimGeocoder) wait(ctx context.Context) error {
+	n.mu.Lock()
+	now := time.Now()
+	slot := n.nextSlot
+	if slot.Before(now) {
+		slot = now
+	}
+	if slot.Sub(now) >= nominatimMaxQueue*nominatimInterval {
+		n.mu.Unlock()
+		return ErrGeocoderBusy
+	}
+	n.nextSlot = slot.Add(nominatimInterval)
+	n.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p nominatimPlace) result(confidence float64) (*GeoResult, error) {
+	lat, errLat := strconv.ParseFloat(p.Lat, 64)
+	lng, errLng := strconv.ParseFloat(p.Lon, 64)
+	if errLat != nil || errLng != nil {
+		return nil, fmt.Errorf("nominatim returned invalid coordinates %q, %q", p.Lat, p.Lon)
+	}
+
+	area := firstNonEmpty(p.Address.Suburb, p.Address.Neighbourhood, p.Address.CityDistrict)
+	name := firstNonEmpty(p.Name, area, p.DisplayName)
+	return &GeoResult{
+		Name:       name,
+		Area:       area,
+		City:       firstNonEmpty(p.Address.City, p.Address.Town),
+		State:      p.Address.State,
+		Latitude:   lat,
+		Longitude:  lng,
+		Confidence: confidence,
+		Source:     "nominatim",
+	}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
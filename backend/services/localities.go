@@ -0,0 +1,182 @@
+package services
+
+import (
+	"backend/utils"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Locality is a canonical city or locality. IDs are stable slugs: "bengaluru" for a city and
+// "bengaluru/hsr-layout" for a locality in it.
+type Locality struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"` // canonical spelling, e.g. "Bengaluru" for "Bangalore" or "BLR"
+	Kind    string   `json:"kind"` // "city" or "locality"
+	CityID  string   `json:"cityId,omitempty"`
+	City    string   `json:"city"`
+	State   string   `json:"state"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// localityRegistry indexes the gazetteer's cities and localities by every spelling
+type localityRegistry struct {
+	byID  map[string]*Locality
+	byKey map[string][]*Locality // normalized name or alias -> localities
+}
+
+var (
+	localities     *localityRegistry
+	localitiesOnce sync.Once
+)
+
+// registry builds the locality registry from the gazetteer on first use
+func registry() *localityRegistry {
+	localitiesOnce.Do(func() {
+		places, err := parseGazetteer()
+		if err != nil {
+			utils.Logger.Printf("Failed to load locality registry: %v", err)
+		}
+		localities = newLocalityRegistry(places)
+	})
+	return localities
+}
+
+func newLocalityRegistry(places []GazetteerPlace) *localityRegistry {
+	r := &localityRegistry{byID: make(map[string]*Locality), byKey: make(map[string][]*Locality)}
+	for _, place := range places {
+		if place.Kind != placeKindCity && place.Kind != placeKindLocality {
+			continue
+		}
+		cityID := slug(place.City)
+		locality := &Locality{
+			ID:      cityID,
+			Name:    place.Name,
+			Kind:    place.Kind,
+			City:    place.City,
+			State:   place.State,
+			Aliases: place.Aliases,
+		}
+		if place.Kind == placeKindLocality {
+			locality.ID = cityID + "/" + slug(place.Name)
+			locality.CityID = cityID
+		}
+		r.byID[locality.ID] = locality
+		for _, name := range locality.Names() {
+			key := utils.NormalizeText(name)
+			r.byKey[key] = append(r.byKey[key], locality)
+		}
+	}
+	return r
+}
+
+func slug(s string) string {
+	return strings.ReplaceAll(utils.NormalizeText(s), " ", "-")
+}
+
+// Names is the canonical name followed by every alias
+func (l *Locality) Names() []string {
+	return append([]string{l.Name}, l.Aliases...)
+}
+
+// IsCity reports whether l is a city rather than a locality in one
+func (l *Locality) IsCity() bool {
+	return l.Kind == placeKindCity
+}
+
+// NamesPattern is a regex group matching any spelling of the locality, e.g. "(HSR Layout|HSR)"
+func (l *Locality) NamesPattern() string {
+	names := l.Names()
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	return "(" + strings.Join(quoted, "|") + ")"
+}
+
+// LookupLocality resolves text that is exactly a city or locality name or alias, ignoring case and
+// punctuation. A locality wins over a city of the same name; cityID, if set, picks between
+// localities that share a name.
+func LookupLocality(text string, cityID string) *Locality {
+	candidates := registry().byKey[utils.NormalizeText(text)]
+	var best *Locality
+	for _, l := range candidates {
+		switch {
+		case best == nil:
+			best = l
+		case cityID != "" && l.CityID == cityID && best.CityID != cityID:
+			best = l
+		case l.Kind == placeKindLocality && best.Kind == placeKindCity:
+			best = l
+		}
+	}
+	return best
+}
+
+// LookupCity resolves text that is exactly a city name or alias
+func LookupCity(text string) *Locality {
+	for _, l := range registry().byKey[utils.NormalizeText(text)] {
+		if l.Kind == placeKindCity {
+			return l
+		}
+	}
+	return nil
+}
+
+// FindLocalityIn returns the most specific locality mentioned anywhere in free text such as
+// "2BHK near HSR Layout Sector 2", preferring those in cityID when it is set
+func FindLocalityIn(text string, cityID string) *Locality {
+	words := strings.Fields(utils.NormalizeText(text))
+	var best *Locality
+	bestWords := 0
+	for start := range words {
+		for n := 1; n <= maxPlaceWords && start+n <= len(words); n++ {
+			for _, l := range registry().byKey[strings.Join(words[start:start+n], " ")] {
+				if l.Kind != placeKindLocality || (cityID != "" && l.CityID != cityID) {
+					continue
+				}
+				if n > bestWords {
+					best, bestWords = l, n
+				}
+			}
+		}
+	}
+	return best
+}
+
+// GetLocalityByID returns a registered locality or city
+func GetLocalityByID(id string) *Locality {
+	return registry().byID[id]
+}
+
+// PlaceID is the canonical ID of a searched place. Places the registry does not know are counted
+// under their normalized text, so "Green Glen Layout" and "green glen layout " still add up.
+func PlaceID(text string) string {
+	if l := LookupLocality(text, ""); l != nil {
+		return l.ID
+	}
+	if l := FindLocalityIn(text, ""); l != nil {
+		return l.ID
+	}
+	if normalized := utils.NormalizeText(text); normalized != "" {
+		return unlistedPlacePrefix + slug(normalized)
+	}
+	return ""
+}
+
+const unlistedPlacePrefix = "unlisted:"
+
+// PlaceName is the display name for a PlaceID
+func PlaceName(id string) string {
+	if l := GetLocalityByID(id); l != nil {
+		return l.Name
+	}
+	words := strings.Fields(strings.ReplaceAll(strings.TrimPrefix(id, unlistedPlacePrefix), "-", " "))
+	for i, word := range words {
+		first, size := utf8.DecodeRuneInString(word) // slugs may keep non-ASCII letters
+		words[i] = string(unicode.ToUpper(first)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
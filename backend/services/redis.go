@@ -10,6 +10,10 @@ import (
 )
 
 const MostSearchedPlacesKey = "most_searched_places"
+
+// MostSearchedLocalitiesKey counts searches by canonical place ID, so "Bangalore" and "Bengaluru"
+// share a score. It replaces MostSearchedPlacesKey, which counted raw search text.
+const MostSearchedLocalitiesKey = "most_searched_localities"
 const redisViewKeyPrefix = "property_views:"
 
 var RedisClient *redis.Client
@@ -69,28 +73,78 @@ func SetToRedis(ctx context.Context, key string, value string, expiration time.D
 	return err
 }
 
+func DeleteFromRedis(ctx context.Context, keys ...string) error {
+	if !RedisEnabled {
+		return nil
+	}
+	return RedisClient.Del(ctx, keys...).Err()
+}
+
+// IncrementInRedis increments the counter at key, starting it at 0 if it does not exist
+func IncrementInRedis(ctx context.Context, key string) error {
+	if !RedisEnabled {
+		return nil
+	}
+	return RedisClient.Incr(ctx, key).Err()
+}
+
 // IncrementSearchedPlaceCount increments the search count for a place in Redis Sorted Set.
-// For simplicity, let's use 'city' as the 'place' for now.
-func IncrementSearchedPlaceCount(ctx context.Context, city string) error {
+// Places are counted by their canonical ID, see PlaceID.
+func IncrementSearchedPlaceCount(ctx context.Context, place string) error {
 	if !RedisEnabled { // Check if Redis is enabled before using
 		return nil // No error, just don't increment if Redis is disabled
 	}
-	return RedisClient.ZIncrBy(ctx, MostSearchedPlacesKey, 1, city).Err()
+	id := PlaceID(place)
+	if id == "" {
+		return nil
+	}
+	return RedisClient.ZIncrBy(ctx, MostSearchedLocalitiesKey, 1, id).Err()
+}
+
+// PopularPlace is a searched place with its canonical ID and search count
+type PopularPlace struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind,omitempty"` // "city" or "locality", empty for places the registry does not know
+	City     string `json:"city,omitempty"`
+	Searches int64  `json:"searches"`
 }
 
+// GetTopSearchedPlaces returns the canonical names of the most searched places
 func GetTopSearchedPlaces(ctx context.Context, limit int) ([]string, error) {
+	places, err := GetTopSearchedPlacesDetailed(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(places))
+	for _, place := range places {
+		names = append(names, place.Name)
+	}
+	return names, nil
+}
+
+func GetTopSearchedPlacesDetailed(ctx context.Context, limit int) ([]PopularPlace, error) {
 	if !RedisEnabled {
-		return []string{}, nil
+		return []PopularPlace{}, nil
 	}
-	// ZRevRange returns elements in reverse order (highest score first).
+	// ZRevRangeWithScores returns elements in reverse order (highest score first).
 	// 0 to limit-1 gets the top 'limit' elements.
-	results, err := RedisClient.ZRevRange(ctx, MostSearchedPlacesKey, 0, int64(limit-1)).Result()
+	results, err := RedisClient.ZRevRangeWithScores(ctx, MostSearchedLocalitiesKey, 0, int64(limit-1)).Result()
 	if err != nil {
 		utils.Logger.Errorf("Error retrieving top searched places from Redis: %v", err)
 		return nil, err
 	}
-	utils.Logger.Printf("Top searched places: %v", results)
-	return results, nil
+
+	places := make([]PopularPlace, 0, len(results))
+	for _, z := range results {
+		id, _ := z.Member.(string)
+		place := PopularPlace{ID: id, Name: PlaceName(id), Searches: int64(z.Score)}
+		if l := GetLocalityByID(id); l != nil {
+			place.Kind, place.City = l.Kind, l.City
+		}
+		places = append(places, place)
+	}
+	return places, nil
 }
 
 // GetSearchedPlaceRank retrieves the rank of a place in the sorted set.
@@ -22,6 +22,10 @@ type Message struct {
 	Receiver  string `json:"receiver"`
 	Message   string `json:"message"`
 	Timestamp string `json:"timestamp"`
+
+	// Type is empty for chat messages and names the event for system notifications
+	Type       string `json:"type,omitempty"`
+	PropertyID string `json:"propertyId,omitempty"`
 }
 
 func HandleConnections(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// phonePattern matches Indian mobile numbers, with or without +91 or 0 in front and with spaces
+// or dashes between the digits
+var phonePattern = regexp.MustCompile(`(?:\+?91[\s-]*|\b0)?\b[6-9](?:[\s-]?\d){9}\b`)
+
+// FindPhoneNumbers returns the distinct mobile numbers in text as their 10 digits
+func FindPhoneNumbers(text string) []string {
+	var numbers []string
+	seen := make(map[string]bool)
+	for _, match := range phonePattern.FindAllString(text, -1) {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, match)
+		if len(digits) < 10 {
+			continue
+		}
+		number := digits[len(digits)-10:]
+		if !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+var emailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+
+// ContactMask replaces the phone numbers and email addresses MaskContacts hides
+const ContactMask = "[contact hidden]"
+
+// FindEmails returns the distinct email addresses in text, lower-cased
+func FindEmails(text string) []string {
+	var emails []string
+	seen := make(map[string]bool)
+	for _, match := range emailPattern.FindAllString(text, -1) {
+		email := strings.ToLower(match)
+		if !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// MaskContacts replaces the mobile numbers and email addresses in text with ContactMask
+func MaskContacts(text string) string {
+	text = phonePattern.ReplaceAllString(text, ContactMask)
+	return emailPattern.ReplaceAllString(text, ContactMask)
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NormalizeText lowercases s and reduces it to single-space separated letters and digits
+func NormalizeText(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// TextSketch is a bottom-k MinHash sketch: the k smallest hashes of the text's 3-word shingles.
+// Two sketches estimate the Jaccard similarity of the texts without storing the texts.
+func TextSketch(text string, k int) []int64 {
+	words := strings.Fields(NormalizeText(text))
+	if len(words) == 0 {
+		return nil
+	}
+
+	seen := make(map[int64]bool)
+	for i := 0; i < len(words); i++ {
+		end := i + 3
+		if end > len(words) {
+			end = len(words)
+		}
+		if end-i < 3 && i > 0 {
+			break // short texts still get one shingle
+		}
+		h := fnv.New32a()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		seen[int64(h.Sum32())] = true
+	}
+
+	sketch := make([]int64, 0, len(seen))
+	for hash := range seen {
+		sketch = append(sketch, hash)
+	}
+	sort.Slice(sketch, func(i, j int) bool { return sketch[i] < sketch[j] })
+	if len(sketch) > k {
+		sketch = sketch[:k]
+	}
+	return sketch
+}
+
+// SketchSimilarity is the Jaccard similarity (0-1) of two sorted TextSketch results. It is exact
+// for texts short enough to fit in the sketch and a close estimate for longer ones.
+func SketchSimilarity(a, b []int64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	i, j, union, shared := 0, 0, 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case j >= len(b) || (i < len(a) && a[i] < b[j]):
+			i++
+		default:
+			j++
+		}
+		union++
+	}
+	return float64(shared) / float64(union)
+}
+
+// HashDistance is the number of differing bits between two hex encoded 64-bit perceptual hashes,
+// or 64 if either is not a valid hash
+func HashDistance(a, b string) int {
+	x, errA := strconv.ParseUint(a, 16, 64)
+	y, errB := strconv.ParseUint(b, 16, 64)
+	if errA != nil || errB != nil {
+		return 64
+	}
+	return bits.OnesCount64(x ^ y)
+}
+
+// SamePhotoMaxDistance is the HashDistance below which two photos are the same picture;
+// re-encodes, resizes and light crops stay below it
+const SamePhotoMaxDistance = 6
+
+// SamePhoto reports whether two perceptual hashes are of the same picture
+func SamePhoto(a, b string) bool {
+	return HashDistance(a, b) <= SamePhotoMaxDistance
+}
+
+// PhotoHashKeys splits a perceptual hash into its 8 bytes and returns a key for every pair of
+// them. Hashes at most SamePhotoMaxDistance bits apart leave at least two bytes untouched, so
+// they share a key: an index on the keys finds near-identical photos without a scan.
+func PhotoHashKeys(hash string) []string {
+	x, err := strconv.ParseUint(hash, 16, 64)
+	if err != nil {
+		return nil
+	}
+	var b [8]byte
+	for i := range b {
+		b[i] = byte(x >> (8 * (7 - i)))
+	}
+	keys := make([]string, 0, 28)
+	for i := 0; i < len(b); i++ {
+		for j := i + 1; j < len(b); j++ {
+			keys = append(keys, fmt.Sprintf("%d%d%02x%02x", i, j, b[i], b[j]))
+		}
+	}
+	return keys
+}
+
+// PhotoSetKeys is PhotoHashKeys for every hash, without repeats
+func PhotoSetKeys(hashes []string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, hash := range hashes {
+		for _, key := range PhotoHashKeys(hash) {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestHashDistance(t *testing.T) {
+	if d := HashDistance("f0f0f0f0f0f0f0f0", "f0f0f0f0f0f0f0f1"); d != 1 {
+		t.Errorf("HashDistance one bit apart = %d, want 1", d)
+	}
+	if d := HashDistance("0000000000000000", "ffffffffffffffff"); d != 64 {
+		t.Errorf("HashDistance of inverted hashes = %d, want 64", d)
+	}
+	if d := HashDistance("not a hash", "0000000000000000"); d != 64 {
+		t.Errorf("HashDistance of an invalid hash = %d, want 64", d)
+	}
+}
+
+// Photos within SamePhotoMaxDistance must always share a key, however the differing bits are
+// spread over the bytes, or the photoKeys index misses them
+func TestPhotoHashKeysFindNearIdenticalPhotos(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		x := random.Uint64()
+		y := x
+		for _, bit := range random.Perm(64)[:1+random.Intn(SamePhotoMaxDistance)] {
+			y ^= 1 << uint(bit)
+		}
+		a, b := fmt.Sprintf("%016x", x), fmt.Sprintf("%016x", y)
+		if !SamePhoto(a, b) {
+			t.Fatalf("SamePhoto(%s, %s) = false at distance %d", a, b, HashDistance(a, b))
+		}
+		if !sharesKey(PhotoHashKeys(a), PhotoHashKeys(b)) {
+			t.Fatalf("PhotoHashKeys(%s) and PhotoHashKeys(%s) share no key at distance %d", a, b, HashDistance(a, b))
+		}
+	}
+}
+
+func TestPhotoHashKeys(t *testing.T) {
+	keys := PhotoHashKeys("0123456789abcdef")
+	if len(keys) != 28 {
+		t.Fatalf("PhotoHashKeys gave %d keys, want one per pair of bytes", len(keys))
+	}
+	// one differing bit in each of seven bytes leaves no pair untouched
+	if sharesKey(keys, PhotoHashKeys("0022446688aaccef")) {
+		t.Errorf("hashes differing in seven bytes share a key")
+	}
+	if keys := PhotoHashKeys("not a hash"); keys != nil {
+		t.Errorf("PhotoHashKeys of an invalid hash = %v, want nil", keys)
+	}
+	// the last hash differs from the first only in its last byte, so adds its 7 pairs
+	set := PhotoSetKeys([]string{"0123456789abcdef", "0123456789abcdef", "", "0123456789abcde0"})
+	if len(set) != 28+7 {
+		t.Errorf("PhotoSetKeys gave %d keys, want %d", len(set), 28+7)
+	}
+}
+
+func sharesKey(a, b []string) bool {
+	seen := make(map[string]bool, len(a))
+	for _, key := range a {
+		seen[key] = true
+	}
+	for _, key := range b {
+		if seen[key] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "math"
+
+// EarthRadiusKm is the mean earth radius every distance in the app is measured with
+const EarthRadiusKm = 6371.0
+
+// HaversineKm is the great-circle distance in km between two latitude/longitude points
+func HaversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// RoundKm rounds a distance to 100 m, which is all the precision a straight-line commute has
+func RoundKm(km float64) float64 {
+	return math.Round(km*10) / 10
+}
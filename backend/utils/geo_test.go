@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversineKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{"same point", 12.9716, 77.5946, 12.9716, 77.5946, 0},
+		{"one degree of latitude", 12, 77, 13, 77, 111.19},
+		{"one degree of longitude at the equator", 0, 77, 0, 78, 111.19},
+		{"MG Road to Koramangala", 12.9756, 77.6066, 12.9352, 77.6245, 4.89},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HaversineKm(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("HaversineKm() = %.3f, want %.2f", got, tt.want)
+			}
+			if back := HaversineKm(tt.lat2, tt.lng2, tt.lat1, tt.lng1); back != got {
+				t.Errorf("distance back = %.3f, want %.3f", back, got)
+			}
+		})
+	}
+}
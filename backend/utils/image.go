@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"mime/multipart"
+	"sync"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ImageRendition describes one stored size/format variant of an uploaded photo
+type ImageRendition struct {
+	Size   string `json:"size" bson:"size"`     // "thumbnail", "card", "full"
+	Format string `json:"format" bson:"format"` // "webp", "jpeg"
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+	URL    string `json:"url" bson:"url"`
+}
+
+type renditionSpec struct {
+	Size    string
+	MaxEdge int
+}
+
+// Longest edge in pixels for every rendition we store. Images are never upscaled.
+var renditionSpecs = []renditionSpec{
+	{Size: "thumbnail", MaxEdge: 320},
+	{Size: "card", MaxEdge: 800},
+	{Size: "full", MaxEdge: 1600},
+}
+
+const jpegQuality = 82
+
+// StoredImage is the result of processing and storing one uploaded photo
+type StoredImage struct {
+	Renditions     []ImageRendition
+	PerceptualHash string // 64-bit dHash in hex, see perceptualHash
+}
+
+type processedImage struct {
+	ImageRendition
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+// processImage decodes an uploaded photo, applies its EXIF orientation and re-encodes
+// it into every rendition. Re-encoding from decoded pixels drops all EXIF/XMP metadata,
+// including GPS coordinates.
+func processImage(data []byte, policy UploadPolicy) ([]processedImage, string, error) {
+	// never decode a canvas larger than the policy allows, whatever the caller checked
+	width, height, err := imageDimensions(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image header: %w", err)
+	}
+	if err := policy.checkDimensions(width, height); err != nil {
+		return nil, "", err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	src = applyOrientation(src, readExifOrientation(data))
+
+	var renditions []processedImage
+	for _, spec := range renditionSpecs {
+		resized := resizeToFit(src, spec.MaxEdge)
+		bounds := resized.Bounds()
+
+		var jpegBuf bytes.Buffer
+		if err := jpeg.Encode(&jpegBuf, resized, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", fmt.Errorf("failed to encode %s jpeg: %w", spec.Size, err)
+		}
+		renditions = append(renditions, processedImage{
+			ImageRendition: ImageRendition{Size: spec.Size, Format: "jpeg", Width: bounds.Dx(), Height: bounds.Dy()},
+			ContentType:    "image/jpeg",
+			Extension:      ".jpg",
+			Data:           jpegBuf.Bytes(),
+		})
+
+		var webpBuf bytes.Buffer
+		if err := nativewebp.Encode(&webpBuf, resized, nil); err != nil {
+			return nil, "", fmt.Errorf("failed to encode %s webp: %w", spec.Size, err)
+		}
+		renditions = append(renditions, processedImage{
+			ImageRendition: ImageRendition{Size: spec.Size, Format: "webp", Width: bounds.Dx(), Height: bounds.Dy()},
+			ContentType:    "image/webp",
+			Extension:      ".webp",
+			Data:           webpBuf.Bytes(),
+		})
+	}
+	return renditions, fmt.Sprintf("%016x", perceptualHash(src)), nil
+}
+
+// UploadImageRenditions processes an uploaded photo within the dimension limits of policy and
+// stores every rendition under basePath (without extension), e.g.
+// "/properties/user_1/20250101_uuid" becomes "/properties/user_1/20250101_uuid_card.webp".
+func UploadImageRenditions(file multipart.File, basePath string, policy UploadPolicy) (*StoredImage, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		Logger.Printf("Failed to read file: %v", err)
+		return nil, err
+	}
+	return StoreImageRenditions(data, basePath, policy)
+}
+
+// StoreImageRenditions is UploadImageRenditions for a photo that is already in memory
+func StoreImageRenditions(data []byte, basePath string, policy UploadPolicy) (*StoredImage, error) {
+	processed, hash, err := processImage(data, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	renditions := make([]ImageRendition, len(processed))
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(processed))
+	for i, p := range processed {
+		wg.Add(1)
+		go func(i int, p processedImage) {
+			defer wg.Done()
+			fileName := fmt.Sprintf("%s_%s%s", basePath, p.Size, p.Extension)
+			url, err := UploadBytes(p.Data, fileName, p.ContentType)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			p.ImageRendition.URL = url
+			renditions[i] = p.ImageRendition
+		}(i, p)
+	}
+	wg.Wait()
+	close(errChan)
+
+	if err, ok := <-errChan; ok {
+		return nil, err
+	}
+	return &StoredImage{Renditions: renditions, PerceptualHash: hash}, nil
+}
+
+// PickRendition returns the URL of the requested size/format, or "" if it is missing
+func PickRendition(renditions []ImageRendition, size string, format string) string {
+	for _, r := range renditions {
+		if r.Size == size && r.Format == format {
+			return r.URL
+		}
+	}
+	return ""
+}
+
+// perceptualHash is a difference hash: the image is shrunk to 9x8 grey pixels and each bit
+// records whether a pixel is brighter than its right neighbour. Re-encoded, resized or slightly
+// recompressed copies of a photo end up within a few bits of each other.
+func perceptualHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// resizeToFit scales img down so its longest edge is at most maxEdge
+func resizeToFit(img image.Image, maxEdge int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return img
+	}
+
+	if w >= h {
+		h = h * maxEdge / w
+		w = maxEdge
+	} else {
+		w = w * maxEdge / h
+		h = maxEdge
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// readExifOrientation returns the EXIF orientation tag (1-8) of a JPEG, or 1 if absent
+func readExifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image
+			return 1
+		}
+		segLen := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		segStart := pos + 4
+		segEnd := pos + 2 + segLen
+		if segLen < 2 || segEnd > len(data) {
+			return 1
+		}
+		if marker == 0xE1 && segEnd-segStart > 6 && string(data[segStart:segStart+6]) == "Exif\x00\x00" {
+			return parseTiffOrientation(data[segStart+6 : segEnd])
+		}
+		pos = segEnd
+	}
+	return 1
+}
+
+func parseTiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates/flips img so it displays upright for the given EXIF orientation
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	var dst *image.RGBA
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirror horizontal
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
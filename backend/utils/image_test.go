@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestProcessImageUsesCallerPolicy(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1200, 900))); err != nil {
+		t.Fatal(err)
+	}
+
+	avatar := UploadPolicy{MaxWidth: 1000, MaxHeight: 1000, MaxPixels: 1_000_000}
+	if _, _, err := processImage(buf.Bytes(), avatar); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("processImage under a 1000px policy = %v, want ErrImageTooLarge", err)
+	}
+
+	renditions, hash, err := processImage(buf.Bytes(), PhotoUploadPolicy)
+	if err != nil {
+		t.Fatalf("processImage under the photo policy = %v", err)
+	}
+	if len(renditions) != 2*len(renditionSpecs) || hash == "" {
+		t.Errorf("processImage gave %d renditions and hash %q", len(renditions), hash)
+	}
+	for _, r := range renditions {
+		if r.Size == "thumbnail" && (r.Width != 320 || r.Height != 240) {
+			t.Errorf("thumbnail is %dx%d, want 320x240", r.Width, r.Height)
+		}
+	}
+}
@@ -1,79 +1,178 @@
 package utils
 
 import (
-	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"io"
-	"mime/multipart"
-	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var (
-	s3Client   *s3.S3
-	bucketName string
-)
+// BlobStore is where uploaded files live. Keys are slash separated paths without a leading slash,
+// e.g. "properties/user_1/20250101_uuid_card.webp".
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// PutPrivate stores an object that is never publicly readable, see IsPrivateKey
+	PutPrivate(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	PublicURL(key string) string
+	// Presign returns a URL the client can PUT exactly size bytes of contentType to until expiry
+	Presign(ctx context.Context, key string, contentType string, size int64, expiry time.Duration) (string, error)
+}
+
+var ErrBlobNotFound = errors.New("object not found")
 
-// InitS3 initializes the S3 client
-func InitS3() error {
-	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	bucketName = os.Getenv("S3_BUCKET_NAME")
-	endpoint := os.Getenv("S3_ENDPOINT")
+// Store is the BlobStore selected by STORAGE_DRIVER
+var Store BlobStore
 
-	// Ensure bucketName is set
-	if bucketName == "" {
-		return fmt.Errorf("S3_BUCKET_NAME environment variable is not set")
+// InitStorage picks the storage driver from STORAGE_DRIVER: "s3" (default), "local" or "memory"
+func InitStorage() error {
+	driver := os.Getenv("STORAGE_DRIVER")
+	switch driver {
+	case "", "s3":
+		store, err := newS3Store()
+		if err != nil {
+			return err
+		}
+		Store = store
+	case "local":
+		store, err := newLocalStore(os.Getenv("LOCAL_STORAGE_DIR"), mediaBaseURL())
+		if err != nil {
+			return err
+		}
+		Store = store
+	case "memory":
+		Store = NewMemoryStore(mediaBaseURL())
+	default:
+		return fmt.Errorf("unknown STORAGE_DRIVER %q, expected s3, local or memory", driver)
 	}
+	Logger.Printf("Storage driver: %T", Store)
+	return nil
+}
 
-	// Initialize S3 session
-	sess, err := session.NewSession(&aws.Config{
-		Credentials: credentials.NewStaticCredentials(accessKey, secretKey, ""),
-		Endpoint:    aws.String(endpoint),
-		Region:      aws.String("ap-south-1"),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to initialize S3 session: %v", err)
+// StoreServesMedia reports whether the /media route has to serve and receive files for the driver
+func StoreServesMedia() bool {
+	_, isS3 := Store.(*s3Store)
+	return Store != nil && !isS3
+}
+
+// mediaBaseURL is where the /media route is reachable for the local and memory drivers
+func mediaBaseURL() string {
+	if base := os.Getenv("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/") + "/media"
 	}
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	return "http://localhost:" + port + "/media"
+}
 
-	s3Client = s3.New(sess)
-	return nil
+// normalizeKey strips the leading slash our upload paths are built with
+func normalizeKey(key string) string {
+	return strings.TrimPrefix(key, "/")
 }
 
-// UploadFileToS3 uploads a file to S3 and returns its public URL
-func UploadFileToS3(file multipart.File, fileHeader *multipart.FileHeader, fileName string, userID string) (string, error) {
-	// Unique filename
-	//fileName := fmt.Sprintf("/properties/user_%s/%s%s", userID, time.Now().Format("20060102150405"), filepath.Ext(fileHeader.Filename))
-	Logger.Printf("Uploading file: %s", fileName)
+// privateKeyPrefixes hold files nobody may download through a public URL: raw uploads still
+// carrying EXIF, and import sources with owners' names and phone numbers
+var privateKeyPrefixes = []string{"uploads/", "imports/"}
 
-	// Read file into memory
-	buffer, err := io.ReadAll(file)
-	if err != nil {
-		Logger.Printf("Failed to read file: %v", err)
+// IsPrivateKey reports whether an object must never be served publicly
+func IsPrivateKey(key string) bool {
+	key = normalizeKey(key)
+	for _, prefix := range privateKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadBytes stores an in-memory object and returns its public URL
+func UploadBytes(buffer []byte, fileName string, contentType string) (string, error) {
+	key := normalizeKey(fileName)
+	if err := Store.Put(context.Background(), key, buffer, contentType); err != nil {
+		Logger.Printf("Failed to upload file to storage: %v", err)
 		return "", err
 	}
+	return Store.PublicURL(key), nil
+}
 
-	// Upload to S3
-	_, err = s3Client.PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String("/" + bucketName),
-		Key:           aws.String(fileName),
-		Body:          bytes.NewReader(buffer),
-		ContentLength: aws.Int64(int64(len(buffer))), // Correct size calculation
-		ContentType:   aws.String(http.DetectContentType(buffer)),
-		ACL:           aws.String("public-read"),
-	})
+// UploadPrivateBytes stores an in-memory object that has no public URL
+func UploadPrivateBytes(buffer []byte, fileName string, contentType string) error {
+	if err := Store.PutPrivate(context.Background(), normalizeKey(fileName), buffer, contentType); err != nil {
+		Logger.Printf("Failed to upload private file to storage: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetObjectBytes downloads an object, refusing anything larger than maxBytes
+func GetObjectBytes(fileName string, maxBytes int64) ([]byte, error) {
+	body, err := Store.Get(context.Background(), normalizeKey(fileName))
 	if err != nil {
-		Logger.Printf("Failed to upload file to S3: %v", err)
-		return "", err
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err != nil {
+		return nil, err
 	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrFileTooLarge
+	}
+	return data, nil
+}
+
+// PresignPutURL returns a URL the client can upload one object to directly
+func PresignPutURL(fileName string, contentType string, size int64, expiry time.Duration) (string, error) {
+	return Store.Presign(context.Background(), normalizeKey(fileName), contentType, size, expiry)
+}
 
-	// Generate public URL
-	supabaseProjectURL := os.Getenv("SUPABASE_PROJECT_URL")
-	url := fmt.Sprintf("%s/storage/v1/object/public/%s/%s", supabaseProjectURL, bucketName, fileName)
+// DeleteObject removes an object from storage
+func DeleteObject(fileName string) error {
+	return Store.Delete(context.Background(), normalizeKey(fileName))
+}
+
+// mediaUploadSignature signs a presigned PUT to the /media route of the local and memory drivers
+func mediaUploadSignature(key string, contentType string, size int64, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%d", key, contentType, size, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func presignMediaURL(baseURL string, key string, contentType string, size int64, expiry time.Duration) string {
+	expires := time.Now().Add(expiry).Unix()
+	query := url.Values{}
+	query.Set("contentType", contentType)
+	query.Set("size", strconv.FormatInt(size, 10))
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", mediaUploadSignature(key, contentType, size, expires))
+	return fmt.Sprintf("%s/%s?%s", baseURL, key, query.Encode())
+}
 
-	return url, nil
+// VerifyMediaUpload checks a PUT to /media against the query string issued by Presign
+func VerifyMediaUpload(key string, query url.Values, contentType string, size int64) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return errors.New("upload URL has expired")
+	}
+	signedSize, err := strconv.ParseInt(query.Get("size"), 10, 64)
+	if err != nil || signedSize != size || query.Get("contentType") != contentType {
+		return errors.New("upload does not match the signed content type and size")
+	}
+	expected := mediaUploadSignature(key, contentType, size, expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return errors.New("invalid upload signature")
+	}
+	return nil
 }
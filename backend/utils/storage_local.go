@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localStore keeps files on disk and relies on the /media route to serve and receive them
+type localStore struct {
+	root    string
+	baseURL string
+}
+
+func newLocalStore(root string, baseURL string) (*localStore, error) {
+	if root == "" {
+		root = "./media"
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create local storage dir %s: %v", root, err)
+	}
+	return &localStore{root: root, baseURL: baseURL}, nil
+}
+
+// path resolves a key inside root, rejecting keys that try to escape it
+func (s *localStore) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if strings.Contains(key, "..") || cleaned == "/" {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+func (s *localStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// PutPrivate is Put; the /media route refuses private keys, see IsPrivateKey
+func (s *localStore) PutPrivate(ctx context.Context, key string, data []byte, contentType string) error {
+	return s.Put(ctx, key, data, contentType)
+}
+
+func (s *localStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return file, err
+}
+
+func (s *localStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *localStore) PublicURL(key string) string {
+	return fmt.Sprintf("%s/%s", s.baseURL, key)
+}
+
+func (s *localStore) Presign(ctx context.Context, key string, contentType string, size int64, expiry time.Duration) (string, error) {
+	return presignMediaURL(s.baseURL, key, contentType, size, expiry), nil
+}
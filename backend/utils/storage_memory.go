@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps objects in a map. It is meant for tests and throwaway local runs.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+	baseURL string
+}
+
+func NewMemoryStore(baseURL string) *MemoryStore {
+	return &MemoryStore{
+		objects: make(map[string][]byte),
+		baseURL: baseURL,
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+// PutPrivate is Put; the /media route refuses private keys, see IsPrivateKey
+func (s *MemoryStore) PutPrivate(ctx context.Context, key string, data []byte, contentType string) error {
+	return s.Put(ctx, key, data, contentType)
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *MemoryStore) PublicURL(key string) string {
+	return fmt.Sprintf("%s/%s", s.baseURL, key)
+}
+
+func (s *MemoryStore) Presign(ctx context.Context, key string, contentType string, size int64, expiry time.Duration) (string, error) {
+	return presignMediaURL(s.baseURL, key, contentType, size, expiry), nil
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// s3Store talks to any S3-compatible service (AWS, Supabase storage, MinIO)
+type s3Store struct {
+	client    *s3.S3
+	bucket    string
+	publicURL string
+}
+
+func newS3Store() (*s3Store, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	bucketName := os.Getenv("S3_BUCKET_NAME")
+	endpoint := os.Getenv("S3_ENDPOINT")
+	region := os.Getenv("S3_REGION")
+	if region == "" {
+		region = "ap-south-1"
+	}
+
+	// Ensure bucketName is set
+	if bucketName == "" {
+		return nil, fmt.Errorf("S3_BUCKET_NAME environment variable is not set")
+	}
+
+	// Initialize S3 session
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(accessKey, secretKey, ""),
+		Endpoint:         aws.String(endpoint),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(endpoint != ""),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize S3 session: %v", err)
+	}
+
+	// STORAGE_PUBLIC_URL is the bucket's public root; default to the Supabase layout we started with
+	publicURL := strings.TrimSuffix(os.Getenv("STORAGE_PUBLIC_URL"), "/")
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("%s/storage/v1/object/public/%s", os.Getenv("SUPABASE_PROJECT_URL"), bucketName)
+	}
+
+	return &s3Store{client: s3.New(sess), bucket: bucketName, publicURL: publicURL}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	return s.put(ctx, key, data, contentType, aws.String("public-read"))
+}
+
+// PutPrivate leaves out the ACL, so the object stays as private as the bucket
+func (s *s3Store) PutPrivate(ctx context.Context, key string, data []byte, contentType string) error {
+	return s.put(ctx, key, data, contentType, nil)
+}
+
+func (s *s3Store) put(ctx context.Context, key string, data []byte, contentType string, acl *string) error {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String(contentType),
+		ACL:           acl,
+	})
+	return err
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound") {
+			return nil, ErrBlobNotFound
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *s3Store) PublicURL(key string) string {
+	return fmt.Sprintf("%s/%s", s.publicURL, key)
+}
+
+// Presign signs the content type and length, so the upload fails unless the client sends exactly those
+func (s *s3Store) Presign(ctx context.Context, key string, contentType string, size int64, expiry time.Duration) (string, error) {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	})
+	req.SetContext(ctx)
+	return req.Presign(expiry)
+}
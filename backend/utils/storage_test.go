@@ -0,0 +1,16 @@
+package utils
+
+import "testing"
+
+func TestIsPrivateKey(t *testing.T) {
+	for _, key := range []string{"/imports/user_1/20261019_uuid.csv", "imports/user_1/20261019_uuid.xlsx", "uploads/user_1/property/uuid.jpg"} {
+		if !IsPrivateKey(key) {
+			t.Errorf("IsPrivateKey(%q) = false", key)
+		}
+	}
+	for _, key := range []string{"properties/user_1/20261019_uuid_card.webp", "societies/1/importsx.jpg", "profile_picture/user_1/uploads.jpg"} {
+		if IsPrivateKey(key) {
+			t.Errorf("IsPrivateKey(%q) = true", key)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// UploadPolicy describes what an upload endpoint accepts. Types are checked against the
+// file's magic bytes, never against the client supplied Content-Type or file extension.
+type UploadPolicy struct {
+	AllowedTypes    []string // e.g. "image/jpeg"
+	MaxFiles        int
+	MaxFileBytes    int64
+	MaxRequestBytes int64 // whole multipart body, enforced with http.MaxBytesReader
+	MaxWidth        int
+	MaxHeight       int
+	MaxPixels       int // guards against decompression bombs (tiny file, huge canvas)
+}
+
+// FileError reports why a single uploaded file was rejected
+type FileError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+var (
+	ErrUnsupportedFileType = errors.New("unsupported file type, only JPEG, PNG and WebP images are allowed")
+	ErrFileTooLarge        = errors.New("file is too large")
+	ErrImageTooLarge       = errors.New("image dimensions are too large")
+)
+
+var PhotoUploadPolicy = UploadPolicy{
+	AllowedTypes:    []string{"image/jpeg", "image/png", "image/webp"},
+	MaxFiles:        8,
+	MaxFileBytes:    10 << 20,
+	MaxRequestBytes: 50 << 20,
+	MaxWidth:        8000,
+	MaxHeight:       8000,
+	MaxPixels:       40_000_000,
+}
+
+var ProfilePictureUploadPolicy = UploadPolicy{
+	AllowedTypes:    []string{"image/jpeg", "image/png", "image/webp"},
+	MaxFiles:        1,
+	MaxFileBytes:    5 << 20,
+	MaxRequestBytes: 6 << 20,
+	MaxWidth:        6000,
+	MaxHeight:       6000,
+	MaxPixels:       24_000_000,
+}
+
+// LimitRequestBody caps the total size of the request body before it is parsed
+func (p UploadPolicy) LimitRequestBody(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, p.MaxRequestBytes)
+}
+
+// CheckFiles validates every file and returns one FileError per rejected file
+func (p UploadPolicy) CheckFiles(files []*multipart.FileHeader) []FileError {
+	var fileErrors []FileError
+	if len(files) > p.MaxFiles {
+		return []FileError{{Error: fmt.Sprintf("too many files, max %d allowed", p.MaxFiles)}}
+	}
+	for _, fileHeader := range files {
+		if err := p.CheckFile(fileHeader); err != nil {
+			fileErrors = append(fileErrors, FileError{File: fileHeader.Filename, Error: err.Error()})
+		}
+	}
+	return fileErrors
+}
+
+// CheckFile sniffs the file type and reads only the image header to check its dimensions
+func (p UploadPolicy) CheckFile(fileHeader *multipart.FileHeader) error {
+	if fileHeader.Size > p.MaxFileBytes {
+		return fmt.Errorf("%w (max %d MB)", ErrFileTooLarge, p.MaxFileBytes>>20)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, p.MaxFileBytes+1))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	return p.CheckBytes(data)
+}
+
+// CheckBytes applies the policy to an in-memory file
+func (p UploadPolicy) CheckBytes(data []byte) error {
+	if int64(len(data)) > p.MaxFileBytes {
+		return fmt.Errorf("%w (max %d MB)", ErrFileTooLarge, p.MaxFileBytes>>20)
+	}
+
+	if !p.allows(SniffImageType(data)) {
+		return ErrUnsupportedFileType
+	}
+
+	width, height, err := imageDimensions(data)
+	if err != nil {
+		return fmt.Errorf("failed to read image header: %w", err)
+	}
+	return p.checkDimensions(width, height)
+}
+
+func (p UploadPolicy) checkDimensions(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid image dimensions %dx%d", width, height)
+	}
+	if width > p.MaxWidth || height > p.MaxHeight || width*height > p.MaxPixels {
+		return fmt.Errorf("%w: %dx%d (max %dx%d)", ErrImageTooLarge, width, height, p.MaxWidth, p.MaxHeight)
+	}
+	return nil
+}
+
+func (p UploadPolicy) allows(contentType string) bool {
+	for _, t := range p.AllowedTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// SniffImageType identifies an image by its magic bytes, returning "" for anything else
+func SniffImageType(data []byte) string {
+	switch {
+	case len(data) >= 3 && bytes.Equal(data[:3], []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case len(data) >= 8 && bytes.Equal(data[:8], []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "image/webp"
+	}
+	return ""
+}
+
+// imageDimensions reads the width and height from the image header without decoding pixels
+func imageDimensions(data []byte) (int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngBomb is a few dozen bytes of PNG whose header claims a width x height canvas
+func pngBomb(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12], ihdr[13] = 8, 0 // 8-bit greyscale
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(13))
+	buf.Write(ihdr)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(ihdr))
+	return buf.Bytes()
+}
+
+func TestCheckBytesRejectsDecompressionBombs(t *testing.T) {
+	bomb := pngBomb(60000, 60000)
+	if len(bomb) > 100 {
+		t.Fatalf("bomb is %d bytes", len(bomb))
+	}
+	if err := PhotoUploadPolicy.CheckBytes(bomb); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("CheckBytes(60000x60000 PNG) = %v, want ErrImageTooLarge", err)
+	}
+	// within the width and height limits, but too many pixels in all
+	if err := PhotoUploadPolicy.CheckBytes(pngBomb(7000, 7000)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("CheckBytes(7000x7000 PNG) = %v, want ErrImageTooLarge", err)
+	}
+	if err := PhotoUploadPolicy.CheckBytes(pngBomb(4000, 3000)); err != nil {
+		t.Errorf("CheckBytes(4000x3000 PNG) = %v, want nil", err)
+	}
+	// the profile picture policy is stricter
+	if err := ProfilePictureUploadPolicy.CheckBytes(pngBomb(7000, 1000)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("profile CheckBytes(7000x1000 PNG) = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestCheckBytesSniffsTheRealType(t *testing.T) {
+	var jpegPhoto bytes.Buffer
+	if err := jpeg.Encode(&jpegPhoto, image.NewGray(image.Rect(0, 0, 640, 480)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := PhotoUploadPolicy.CheckBytes(jpegPhoto.Bytes()); err != nil {
+		t.Errorf("CheckBytes(JPEG) = %v", err)
+	}
+	if got := SniffImageType([]byte("RIFF\x00\x00\x00\x00WEBPVP8 ")); got != "image/webp" {
+		t.Errorf("SniffImageType(WebP) = %q", got)
+	}
+
+	rejected := map[string][]byte{
+		"script named photo.jpg": []byte("<?php system($_GET['c']); ?>"),
+		"GIF":                    []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00"),
+		"SVG":                    []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`),
+		"HEIC from a phone":      []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"),
+		"empty":                  nil,
+	}
+	for name, data := range rejected {
+		if err := PhotoUploadPolicy.CheckBytes(data); !errors.Is(err, ErrUnsupportedFileType) {
+			t.Errorf("CheckBytes(%s) = %v, want ErrUnsupportedFileType", name, err)
+		}
+	}
+
+	// a real PNG signature in front of garbage still has to parse
+	if err := PhotoUploadPolicy.CheckBytes(encodePNG(t, 4, 4)[:12]); err == nil {
+		t.Errorf("CheckBytes(truncated PNG) = nil, want an error")
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	policy := PhotoUploadPolicy
+	policy.MaxFiles = 2
+	policy.MaxFileBytes = 1 << 20
+	names := []string{"kitchen.png", "notes.png", "padded.png"}
+	contents := [][]byte{encodePNG(t, 10, 10), []byte("not really a png"), append(encodePNG(t, 1, 1), make([]byte, 1<<20)...)}
+
+	fileErrors := policy.CheckFiles(multipartFiles(t, names, contents...))
+	if len(fileErrors) != 1 || fileErrors[0].File != "" {
+		t.Fatalf("CheckFiles(3 files, max 2) = %v, want one error for the whole upload", fileErrors)
+	}
+
+	policy.MaxFiles = 3
+	fileErrors = policy.CheckFiles(multipartFiles(t, names, contents...))
+	if len(fileErrors) != 2 || fileErrors[0].File != "notes.png" || fileErrors[1].File != "padded.png" {
+		t.Fatalf("CheckFiles() = %v, want notes.png and padded.png rejected", fileErrors)
+	}
+	if fileErrors[1].Error != "file is too large (max 1 MB)" {
+		t.Errorf("padded.png error = %q", fileErrors[1].Error)
+	}
+}
+
+// multipartFiles uploads the files as one form and returns their parsed headers
+func multipartFiles(t *testing.T, names []string, contents ...[]byte) []*multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, name := range names {
+		part, err := writer.CreateFormFile("photoFiles", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(contents[i])
+	}
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(10 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["photoFiles"]
+}
@@ -11,6 +11,7 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
 }
 
 func WriteSuccessResponse(w http.ResponseWriter, data interface{}, statusCode int) {
@@ -27,6 +28,14 @@ func WriteErrorResponse(w http.ResponseWriter, errorMessage string, statusCode i
 	json.NewEncoder(w).Encode(response)
 }
 
+// WriteErrorResponseWithDetails is WriteErrorResponse plus structured details, e.g. per-file upload errors
+func WriteErrorResponseWithDetails(w http.ResponseWriter, errorMessage string, details interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	response := Response{Success: false, Error: errorMessage, Details: details}
+	json.NewEncoder(w).Encode(response)
+}
+
 func GenerateRandomPassword() string {
 	letters := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	digits := "0123456789"